@@ -1,20 +1,25 @@
 package controllers
 
 import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/services"
+	"errors"
 	"net/http"
-	"os"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-type AdminAuthController struct{}
+type AdminAuthController struct {
+	UserService services.UserService
+}
 
-func NewAdminAuthController() *AdminAuthController {
-	return &AdminAuthController{}
+func NewAdminAuthController(us services.UserService) *AdminAuthController {
+	return &AdminAuthController{UserService: us}
 }
 
+// POST /admin/login
+// Staff accounts live in the users collection; create the first one with
+// tools/create_admin
 func (a *AdminAuthController) AdminLogin(c *gin.Context) {
 	var req struct {
 		Email    string `json:"email"`
@@ -26,31 +31,31 @@ func (a *AdminAuthController) AdminLogin(c *gin.Context) {
 		return
 	}
 
-	adminEmail := os.Getenv("ADMIN_EMAIL")
-	adminPassword := os.Getenv("ADMIN_PASSWORD")
-	jwtSecret := os.Getenv("JWT_SECRET")
-
-	if req.Email != adminEmail || req.Password != adminPassword {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
-		return
-	}
-
-	claims := jwt.MapClaims{
-		"user_id": "admin",
-		"email":   adminEmail,
-		"role":    "admin",
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	tokens, user, err := a.UserService.StaffLogin(req.Email, req.Password, sessionClient(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+		status := http.StatusUnauthorized
+		if !errors.Is(err, services.ErrInvalidCredentials) {
+			status = http.StatusInternalServerError
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
+	role := models.ParseRole(user.Role)
 	c.JSON(http.StatusOK, gin.H{
-		"token": tokenString,
-		"role":  "admin",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"role":          role,
+		"permissions":   role.Permissions(),
 	})
 }
+
+// GET /admin/roles
+func (a *AdminAuthController) ListRoles(c *gin.Context) {
+	roles := []gin.H{}
+	for _, role := range models.StaffRoles() {
+		roles = append(roles, gin.H{"role": role, "permissions": role.Permissions()})
+	}
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
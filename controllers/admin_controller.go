@@ -1,8 +1,10 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"beauty-ecommerce-backend/models"
 	"beauty-ecommerce-backend/services"
@@ -55,6 +57,16 @@ func (ac *AdminController) CreateProduct(c *gin.Context) {
 		return
 	}
 
+	// Optional, used for weight-based shipping rates
+	weightGrams := 0
+	if weightStr := c.PostForm("weight_grams"); weightStr != "" {
+		weightGrams, err = strconv.Atoi(weightStr)
+		if err != nil || weightGrams < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid weight_grams"})
+			return
+		}
+	}
+
 	imageURL := ""
 	imageID := ""
 
@@ -73,8 +85,9 @@ func (ac *AdminController) CreateProduct(c *gin.Context) {
 	product := models.Product{
 		Name:        name,
 		Description: description,
-		Price:       price,
+		Price:       models.MoneyFromFloat(price, models.DefaultCurrency),
 		Stock:       stock,
+		WeightGrams: weightGrams,
 		Category:    category,
 		ImageURL:    imageURL,
 		ImageID:     imageID,
@@ -114,6 +127,70 @@ func (ac *AdminController) DeleteProduct(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Product deleted"})
 }
 
+// VARIANT METHODS
+type variantRequest struct {
+	SKU        string            `json:"sku"`
+	Attributes map[string]string `json:"attributes"`
+	Price      *float64          `json:"price"` // major units, e.g. 12.99
+	Stock      int               `json:"stock"`
+	ImageURL   string            `json:"image_url"`
+}
+
+func (r variantRequest) toModel() models.ProductVariant {
+	variant := models.ProductVariant{
+		SKU:        r.SKU,
+		Attributes: r.Attributes,
+		Stock:      r.Stock,
+		ImageURL:   r.ImageURL,
+	}
+	if r.Price != nil {
+		price := models.MoneyFromFloat(*r.Price, models.DefaultCurrency)
+		variant.Price = &price
+	}
+	return variant
+}
+
+func (ac *AdminController) CreateVariant(c *gin.Context) {
+	var req variantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := ac.ProductService.AddVariant(c.Param("id"), req.toModel())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"product": product})
+}
+
+func (ac *AdminController) UpdateVariant(c *gin.Context) {
+	var req variantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := ac.ProductService.UpdateVariant(c.Param("id"), c.Param("variantId"), req.toModel())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"product": product})
+}
+
+func (ac *AdminController) DeleteVariant(c *gin.Context) {
+	if err := ac.ProductService.DeleteVariant(c.Param("id"), c.Param("variantId")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Variant deleted"})
+}
+
 //===== ORDER METHODS =====//
 
 func (ac *AdminController) ListOrders(c *gin.Context) {
@@ -126,6 +203,26 @@ func (ac *AdminController) ListOrders(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"orders": orders})
 }
 
+// GET /admin/orders/:id/payments
+func (ac *AdminController) ListPaymentAttempts(c *gin.Context) {
+	orderID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID"})
+		return
+	}
+
+	attempts, err := ac.OrderService.GetPaymentAttempts(orderID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrOrderNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"payments": attempts})
+}
+
 func (ac *AdminController) UpdateOrderStatus(c *gin.Context) {
 	// Get order ID from URL
 	idStr := c.Param("id")
@@ -137,20 +234,32 @@ func (ac *AdminController) UpdateOrderStatus(c *gin.Context) {
 
 	// Bind JSON payload
 	var payload struct {
-		Status string `json:"status"`
+		Status string `json:"status" binding:"required"`
+		Reason string `json:"reason"`
 	}
 	if err := c.ShouldBindJSON(&payload); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Call service with primitive.ObjectID
-	if err := ac.OrderService.UpdateOrderStatus(orderID, payload.Status); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	adminID, _ := utils.ExtractUserIDAndRole(c)
+	status := models.OrderStatus(strings.ToLower(strings.TrimSpace(payload.Status)))
+
+	order, err := ac.OrderService.UpdateOrderStatus(orderID, status, adminID, payload.Reason)
+	if err != nil {
+		var transitionErr *models.StatusTransitionError
+		switch {
+		case errors.As(err, &transitionErr):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "allowed": transitionErr.From.Next()})
+		case errors.Is(err, services.ErrOrderNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Order status updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Order status updated successfully", "order": order})
 }
 
 //////////////////////////////
@@ -190,7 +299,14 @@ func (ac *AdminController) UpdateUser(c *gin.Context) {
 
 	// Call service
 	if err := ac.UserService.UpdateUser(id, update); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, services.ErrInvalidUserID), errors.Is(err, services.ErrInvalidRole):
+			status = http.StatusBadRequest
+		case errors.Is(err, services.ErrUserNotFound):
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -2,10 +2,13 @@ package controllers
 
 import (
 	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/repositories"
 	"beauty-ecommerce-backend/services"
+	"beauty-ecommerce-backend/utils"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -19,6 +22,21 @@ func InitCartController(cs services.CartService) {
 }
 
 // -------------------- Helper --------------------
+// cartErrorStatus maps catalog validation errors to a client error
+func cartErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrProductNotFound), errors.Is(err, models.ErrVariantNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrVariantRequired):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrInvalidQuantity), errors.Is(err, services.ErrOutOfStock),
+		errors.Is(err, services.ErrCartOwnerRequired):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func getUserIDFromContext(c *gin.Context) (primitive.ObjectID, error) {
 	userClaims, exists := c.Get("user")
 	if !exists {
@@ -43,10 +61,65 @@ func getUserIDFromContext(c *gin.Context) (primitive.ObjectID, error) {
 	return userID, nil
 }
 
+// CartTokenHeader carries the opaque token that identifies a guest cart
+const CartTokenHeader = "X-Cart-Token"
+
+// resolveCartOwner returns the signed-in user, or the guest cart named by the
+// X-Cart-Token header. When issue is true and the guest has no token yet, a new
+// one is generated and returned in the response header.
+func resolveCartOwner(c *gin.Context, issue bool) (models.CartOwner, error) {
+	if _, signedIn := c.Get("user"); signedIn {
+		userID, err := getUserIDFromContext(c)
+		if err != nil {
+			return models.CartOwner{}, err
+		}
+		return models.CartOwner{UserID: userID}, nil
+	}
+
+	token := c.GetHeader(CartTokenHeader)
+	if token == "" && issue {
+		token = utils.GenerateRandomToken(32)
+	}
+	if token != "" && !isValidCartToken(token) {
+		return models.CartOwner{}, errors.New("invalid cart token")
+	}
+	if token != "" {
+		c.Header(CartTokenHeader, token)
+	}
+	return models.CartOwner{Token: token}, nil
+}
+
+func isValidCartToken(token string) bool {
+	if len(token) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(token)
+	return err == nil
+}
+
+// mergeGuestCart folds the caller's guest cart into the user's cart after
+// login or registration. A failed merge never blocks sign-in.
+func mergeGuestCart(c *gin.Context, email string) {
+	token := c.GetHeader(CartTokenHeader)
+	if token == "" || cartService == nil || !isValidCartToken(token) {
+		return
+	}
+
+	user, err := userService.GetUserByEmail(email)
+	if err != nil {
+		fmt.Println("⚠️ Guest cart merge skipped:", err)
+		return
+	}
+	if err := cartService.MergeGuestCart(token, user.ID); err != nil {
+		fmt.Println("⚠️ Guest cart merge failed:", err)
+	}
+}
+
 // -------------------- CREATE CART ITEM --------------------
 func CreateCart(c *gin.Context) {
 	var body struct {
 		ProductID string `json:"product_id"`
+		VariantID string `json:"variant_id"`
 		Quantity  int    `json:"quantity"`
 	}
 
@@ -55,9 +128,9 @@ func CreateCart(c *gin.Context) {
 		return
 	}
 
-	userID, err := getUserIDFromContext(c)
+	owner, err := resolveCartOwner(c, true)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -67,43 +140,77 @@ func CreateCart(c *gin.Context) {
 		return
 	}
 
+	variantID := primitive.NilObjectID
+	if body.VariantID != "" {
+		variantID, err = primitive.ObjectIDFromHex(body.VariantID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+			return
+		}
+	}
+
 	cartItem := models.CartItem{
-		ID:        primitive.NewObjectID(),
 		ProductID: productID,
-		UserID:    userID,
+		VariantID: variantID,
+		UserID:    owner.UserID,
+		CartToken: owner.Token,
 		Quantity:  body.Quantity,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
 	}
 
 	createdCartItem, err := cartService.CreateCartItem(cartItem)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"cart_item": createdCartItem})
+	response := gin.H{"cart_item": createdCartItem}
+	if owner.IsGuest() {
+		response["cart_token"] = owner.Token
+	}
+	c.JSON(http.StatusCreated, response)
 }
 
 // -------------------- GET CART ITEMS --------------------
 func GetCart(c *gin.Context) {
-	userID, err := getUserIDFromContext(c)
+	owner, err := resolveCartOwner(c, false)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currency, rate, err := requestCurrency(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	cartItems, err := cartService.GetCartByUser(userID)
+	// A guest who has not added anything yet has no cart token
+	if owner.IsGuest() && owner.Token == "" {
+		c.JSON(http.StatusOK, gin.H{"cart": models.PricedCart{
+			Items:    []models.PricedCartItem{},
+			Subtotal: models.Money{Currency: currency},
+		}})
+		return
+	}
+
+	cart, err := cartService.GetPricedCart(owner)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	cart.Convert(currency, rate)
 
-	c.JSON(http.StatusOK, gin.H{"cart_items": cartItems})
+	c.JSON(http.StatusOK, gin.H{"cart": cart})
 }
 
 // -------------------- UPDATE CART ITEM --------------------
 func UpdateCartItem(c *gin.Context) {
+	owner, err := resolveCartOwner(c, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	cartItemID, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cart item ID"})
@@ -119,17 +226,16 @@ func UpdateCartItem(c *gin.Context) {
 	}
 
 	existing, err := cartService.GetCartItemByID(cartItemID)
-	if err != nil {
+	if err != nil || !owner.Owns(existing) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Cart item not found"})
 		return
 	}
 
 	existing.Quantity = body.Quantity
-	existing.UpdatedAt = time.Now()
 
 	updated, err := cartService.UpdateCartItem(*existing)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -138,12 +244,24 @@ func UpdateCartItem(c *gin.Context) {
 
 // -------------------- DELETE CART ITEM --------------------
 func DeleteCartItem(c *gin.Context) {
+	owner, err := resolveCartOwner(c, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	cartItemID, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cart item ID"})
 		return
 	}
 
+	existing, err := cartService.GetCartItemByID(cartItemID)
+	if err != nil || !owner.Owns(existing) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cart item not found"})
+		return
+	}
+
 	if err := cartService.DeleteCartItem(cartItemID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -154,24 +272,68 @@ func DeleteCartItem(c *gin.Context) {
 
 // -------------------- CLEAR CART --------------------
 func ClearCart(c *gin.Context) {
+	owner, err := resolveCartOwner(c, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !owner.IsGuest() || owner.Token != "" {
+		if err := cartService.ClearCart(owner); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cart cleared successfully"})
+}
+
+// -------------------- CHECKOUT CART --------------------
+func CheckoutCart(c *gin.Context) {
+	var body struct {
+		CustomerName    string         `json:"customer_name"`
+		CustomerEmail   string         `json:"customer_email"`
+		CustomerPhone   string         `json:"customer_phone"`
+		ShippingAddress models.Address `json:"shipping_address"`
+		DeliveryType    string         `json:"delivery_type"`
+		CouponCode      string         `json:"coupon_code"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
-	cartItems, err := cartService.GetCartByUser(userID)
+	currency, _, err := requestCurrency(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	for _, item := range cartItems {
-		if err := cartService.DeleteCartItem(item.ID); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+	details := models.Order{
+		Currency:        currency,
+		CustomerName:    body.CustomerName,
+		CustomerEmail:   body.CustomerEmail,
+		CustomerPhone:   body.CustomerPhone,
+		ShippingAddress: body.ShippingAddress,
+		DeliveryType:    body.DeliveryType,
+		CouponCode:      body.CouponCode,
+	}
+
+	order, changes, err := orderService.CheckoutCart(userID, details)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, repositories.ErrInsufficientStock) {
+			status = http.StatusConflict
 		}
+		c.JSON(status, gin.H{"error": err.Error(), "changes": changes})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Cart cleared successfully"})
+	c.JSON(http.StatusCreated, gin.H{"order": order, "changes": changes})
 }
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CouponController struct {
+	service services.CouponService
+}
+
+func NewCouponController(service services.CouponService) *CouponController {
+	return &CouponController{service}
+}
+
+// POST /admin/coupons
+func (cc *CouponController) CreateCoupon(c *gin.Context) {
+	var coupon models.Coupon
+	if err := c.ShouldBindJSON(&coupon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	created, err := cc.service.CreateCoupon(coupon)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"coupon": created})
+}
+
+// GET /admin/coupons
+func (cc *CouponController) ListCoupons(c *gin.Context) {
+	coupons, err := cc.service.GetCoupons()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"coupons": coupons})
+}
+
+// GET /admin/coupons/:id
+func (cc *CouponController) GetCoupon(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid coupon ID"})
+		return
+	}
+
+	coupon, err := cc.service.GetCoupon(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"coupon": coupon})
+}
+
+// PUT /admin/coupons/:id
+func (cc *CouponController) UpdateCoupon(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid coupon ID"})
+		return
+	}
+
+	var coupon models.Coupon
+	if err := c.ShouldBindJSON(&coupon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	updated, err := cc.service.UpdateCoupon(id, coupon)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrCouponNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"coupon": updated})
+}
+
+// DELETE /admin/coupons/:id
+func (cc *CouponController) DeleteCoupon(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid coupon ID"})
+		return
+	}
+
+	if err := cc.service.DeleteCoupon(id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrCouponNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "coupon deleted"})
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/services"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var currencyService services.CurrencyService
+
+func InitCurrencyController(cs services.CurrencyService) {
+	currencyService = cs
+}
+
+// CurrencyHeader picks the currency prices are shown and charged in. The
+// ?currency= query parameter does the same; without either, a signed-in
+// user's preferred currency is used, then DefaultCurrency.
+const CurrencyHeader = "X-Currency"
+
+// requestCurrency returns the currency for this request and its rate from
+// DefaultCurrency
+func requestCurrency(c *gin.Context) (string, float64, error) {
+	currency := c.GetHeader(CurrencyHeader)
+	if currency == "" {
+		currency = c.Query("currency")
+	}
+	explicit := currency != ""
+
+	if !explicit {
+		if userID, err := getUserIDFromContext(c); err == nil && userService != nil {
+			if user, err := userService.GetProfile(userID); err == nil {
+				currency = user.Currency
+			}
+		}
+	}
+
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" || currency == models.DefaultCurrency {
+		return models.DefaultCurrency, 1, nil
+	}
+
+	rate, err := currencyService.Rate(currency)
+	if err != nil {
+		if !explicit {
+			// A preference for a currency that has since been withdrawn
+			return models.DefaultCurrency, 1, nil
+		}
+		return "", 0, err
+	}
+	return currency, rate, nil
+}
+
+// GET /currencies
+func ListCurrencies(c *gin.Context) {
+	currencies, err := currencyService.Currencies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"currencies": currencies, "default": models.DefaultCurrency})
+}
+
+// GET /admin/currencies/rates
+func ListExchangeRates(c *gin.Context) {
+	rates, err := currencyService.GetRates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"base": models.DefaultCurrency, "rates": rates})
+}
+
+// PUT /admin/currencies/rates/:currency
+func SetExchangeRate(c *gin.Context) {
+	var body struct {
+		Rate   float64 `json:"rate"`
+		Active *bool   `json:"active"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	rate := models.ExchangeRate{Currency: c.Param("currency"), Rate: body.Rate, Active: true}
+	if body.Active != nil {
+		rate.Active = *body.Active
+	}
+
+	saved, err := currencyService.SetRate(rate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rate": saved})
+}
+
+// DELETE /admin/currencies/rates/:currency
+func DeleteExchangeRate(c *gin.Context) {
+	if err := currencyService.DeleteRate(c.Param("currency")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrExchangeRateNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "exchange rate deleted"})
+}
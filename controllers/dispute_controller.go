@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DisputeController struct {
+	service services.DisputeService
+}
+
+func NewDisputeController(service services.DisputeService) *DisputeController {
+	return &DisputeController{service}
+}
+
+// GET /admin/disputes?status=needs_response
+// GET /admin/disputes?open=true for everything still awaiting an outcome
+func (dc *DisputeController) ListDisputes(c *gin.Context) {
+	status := models.DisputeStatus(strings.ToLower(c.Query("status")))
+	disputes, err := dc.service.ListDisputes(status, c.Query("open") == "true")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"disputes": disputes})
+}
+
+// GET /admin/disputes/:id
+func (dc *DisputeController) GetDispute(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dispute ID"})
+		return
+	}
+
+	dispute, err := dc.service.GetDispute(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"dispute": dispute})
+}
@@ -2,7 +2,9 @@ package controllers
 
 import (
 	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/repositories"
 	"beauty-ecommerce-backend/services"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -40,8 +42,19 @@ func CreateOrder(c *gin.Context) {
 	// ✅ Controller responsibility: attach authenticated user
 	order.UserID = userID
 
+	currency, _, err := requestCurrency(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	order.Currency = currency
+
 	createdOrder, err := orderService.CreateOrder(order)
 	if err != nil {
+		if errors.Is(err, repositories.ErrInsufficientStock) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
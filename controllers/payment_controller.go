@@ -1,202 +1,233 @@
 package controllers
 
 import (
+	"beauty-ecommerce-backend/repositories"
 	"beauty-ecommerce-backend/services"
 	"beauty-ecommerce-backend/utils"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"os"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/stripe/stripe-go/v74"
-	"github.com/stripe/stripe-go/v74/webhook"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 var (
-	PaymentOrderService services.OrderService
-	PaymentUserService  services.UserService
+	PaymentOrderService   services.OrderService
+	PaymentUserService    services.UserService
+	PaymentWebhookService services.WebhookService
+	PaymentProvider       services.PaymentProvider
 )
 
-// InitPaymentController initializes services and Stripe key
-func InitPaymentController(orderService services.OrderService, userService services.UserService) {
+// InitPaymentController initializes services and the payment provider
+func InitPaymentController(orderService services.OrderService, userService services.UserService, webhookService services.WebhookService, provider services.PaymentProvider) {
 	PaymentOrderService = orderService
 	PaymentUserService = userService
-	stripe.Key = os.Getenv("STRIPE_SECRET_KEY")
+	PaymentWebhookService = webhookService
+	PaymentProvider = provider
+}
+
+// paymentErrorStatus maps payment initialisation errors to HTTP statuses
+func paymentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrPaymentMethodNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrUserNotFound), errors.Is(err, services.ErrPaymentNotSaved),
+		errors.Is(err, services.ErrCheckoutNotSaved):
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
 }
 
 // POST /orders/:id/pay
+// POST /orders/:id/pay?mode=checkout for the provider's hosted checkout page
+//
+// The optional body picks a saved payment method, {"payment_method_id": "..."},
+// or asks to save the one entered, {"save_payment_method": true}
 func InitializePayment(c *gin.Context) {
-	orderIDHex := c.Param("id")
-	orderID, err := primitive.ObjectIDFromHex(orderIDHex)
+	orderID, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
 		return
 	}
-
-	order, err := PaymentOrderService.GetOrderByID(orderID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "order not found"})
+	userID, _ := utils.ExtractUserIDAndRole(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	if order.TotalPrice <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "order total invalid"})
+	switch c.DefaultQuery("mode", "intent") {
+	case "intent":
+	case "checkout":
+		initializeCheckout(c, orderID, userID)
+		return
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be intent or checkout"})
 		return
 	}
 
-	user, err := PaymentUserService.GetUserByID(order.UserID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+	var options services.PaymentOptions
+	if err := c.ShouldBindJSON(&options); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request format"})
 		return
 	}
 
-	pi, err := utils.CreateStripePaymentIntentWithMetadata(
-		order.TotalPrice,
-		orderIDHex,
-		user.Email,
-		user.Name,
-		order.DeliveryType,
-		order.Subtotal,
-		order.ShippingFee,
-		order.TotalPrice,
-	)
+	order, intent, err := PaymentOrderService.InitializePayment(orderID, userID, options)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(paymentErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := PaymentOrderService.SaveOrderReference(orderIDHex, pi.ID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save payment reference"})
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "payment initialized",
+		"order_id":      order.ID.Hex(),
+		"amount":        order.TotalPrice,
+		"currency":      order.TotalPrice.Currency,
+		"provider":      PaymentProvider.Name(),
+		"payment_id":    intent.ID,
+		"client_secret": intent.ClientSecret,
+		"status":        intent.Status,
+	})
+}
+
+func initializeCheckout(c *gin.Context, orderID, userID primitive.ObjectID) {
+	order, session, err := PaymentOrderService.InitializeCheckout(orderID, userID)
+	if err != nil {
+		c.JSON(paymentErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":       "payment initialized",
-		"order_id":      orderIDHex,
-		"amount":        order.TotalPrice,
-		"payment_id":    pi.ID,
-		"client_secret": pi.ClientSecret,
+		"message":      "checkout initialized",
+		"order_id":     order.ID.Hex(),
+		"amount":       order.TotalPrice,
+		"currency":     order.TotalPrice.Currency,
+		"provider":     PaymentProvider.Name(),
+		"session_id":   session.ID,
+		"checkout_url": session.URL,
 	})
 }
 
-func StripeWebhook(c *gin.Context) {
-	webhookSecret := os.Getenv("STRIPE_WEBHOOK_SECRET")
-	if webhookSecret == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Webhook secret not configured"})
+// GET /users/me/payment-methods
+func ListPaymentMethods(c *gin.Context) {
+	userID, _ := utils.ExtractUserIDAndRole(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	payload, err := c.GetRawData()
+	methods, err := PaymentUserService.ListPaymentMethods(userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"payment_methods": methods})
+}
 
-	sigHeader := c.GetHeader("Stripe-Signature")
-	var event stripe.Event
-
-	if sigHeader == "" {
-		// Local test mode
-		fmt.Println("⚡ Local test mode: skipping signature verification")
-		if err := json.Unmarshal(payload, &event); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
-			return
-		}
-	} else {
-		event, err = webhook.ConstructEventWithOptions(
-			payload, sigHeader, webhookSecret,
-			webhook.ConstructEventOptions{IgnoreAPIVersionMismatch: true},
-		)
-		if err != nil {
-			fmt.Println("❌ Signature Verification Failed:", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid signature"})
-			return
-		}
+// POST /users/me/payment-methods
+// Returns a setup intent; the frontend collects the card with its client secret
+func SetupPaymentMethod(c *gin.Context) {
+	userID, _ := utils.ExtractUserIDAndRole(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
 	}
 
-	fmt.Println("🔥 Stripe event received:", event.Type)
-
-	switch event.Type {
-	case "payment_intent.succeeded":
-		var pi stripe.PaymentIntent
-		if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
-			fmt.Println("❌ Failed to parse PaymentIntent:", err)
-			break
-		}
+	setup, err := PaymentUserService.SetupPaymentMethod(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "payment method setup started",
+		"provider":      PaymentProvider.Name(),
+		"setup_id":      setup.ID,
+		"client_secret": setup.ClientSecret,
+	})
+}
 
-		fmt.Println("✅ Payment succeeded:", pi.ID)
-		if err := PaymentOrderService.MarkOrderAsPaid(pi.ID); err != nil {
-			fmt.Println("❌ Failed to mark order as paid:", err)
-		}
+// DELETE /users/me/payment-methods/:methodId
+func RemovePaymentMethod(c *gin.Context) {
+	userID, _ := utils.ExtractUserIDAndRole(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
 
-		go func() {
-			// Extract metadata
-			userEmail := pi.Metadata["user_email"]
-			userName := pi.Metadata["user_name"]
-			orderID := pi.Metadata["order_id"]
-			deliveryType := pi.Metadata["delivery_type"]
-			subtotal, _ := strconv.ParseFloat(pi.Metadata["subtotal"], 64)
-			shipping, _ := strconv.ParseFloat(pi.Metadata["shipping_fee"], 64)
-			total, _ := strconv.ParseFloat(pi.Metadata["total_price"], 64)
-
-			if userEmail == "" {
-				userEmail = "okakafavour81@gmail.com"
-			}
-
-			// Send emails using helpers
-			utils.SendCustomerPaymentSuccess(userEmail, userName, orderID, deliveryType, subtotal, shipping, total)
-			utils.SendAdminNotification(orderID, userName, userEmail, deliveryType, subtotal, shipping, total)
-		}()
-
-	case "payment_intent.payment_failed":
-		var pi stripe.PaymentIntent
-		if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
-			fmt.Println("❌ Failed to parse failed PaymentIntent:", err)
-			break
+	if err := PaymentUserService.RemovePaymentMethod(userID, c.Param("methodId")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrPaymentMethodNotFound) {
+			status = http.StatusNotFound
 		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "payment method removed"})
+}
 
-		fmt.Println("❌ Payment FAILED:", pi.ID)
-		if err := PaymentOrderService.MarkOrderAsFailed(pi.ID); err != nil {
-			fmt.Println("❌ Failed to mark order as FAILED:", err)
-		}
+// POST /payment/webhook
+func PaymentWebhook(c *gin.Context) {
+	payload, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body"})
+		return
+	}
 
-		go func() {
-			userEmail := pi.Metadata["user_email"]
-			userName := pi.Metadata["user_name"]
-			orderID := pi.Metadata["order_id"]
-
-			if userEmail == "" {
-				userEmail = "okakafavour81@gmail.com"
-			}
-
-			utils.SendFailedPaymentEmail(userEmail, userName, orderID)
-			utils.SendAdminNotification(orderID, userName, userEmail, "N/A", 0, 0, 0)
-		}()
-
-	case "charge.refunded":
-		var charge stripe.Charge
-		if err := json.Unmarshal(event.Data.Raw, &charge); err == nil && charge.PaymentIntent != nil {
-			fmt.Println("💸 Payment refunded:", charge.ID)
-			if err := PaymentOrderService.MarkOrderAsRefunded(charge.PaymentIntent.ID); err != nil {
-				fmt.Println("❌ Failed to mark order as refunded:", err)
-			}
-		}
+	// Every event must be signed by the provider
+	event, err := PaymentProvider.VerifyWebhook(payload, c.Request.Header)
+	if err != nil {
+		fmt.Println("❌ Signature Verification Failed:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid signature"})
+		return
+	}
 
-	case "charge.dispute.created":
-		var dispute stripe.Dispute
-		if err := json.Unmarshal(event.Data.Raw, &dispute); err == nil && dispute.Charge != nil {
-			fmt.Println("⚠️ Payment disputed, Charge ID:", dispute.Charge.ID)
-			if err := PaymentOrderService.MarkOrderAsDisputed(dispute.Charge.ID); err != nil {
-				fmt.Println("❌ Failed to mark order as disputed:", err)
-			}
-		}
+	fmt.Println("🔥 Payment event received:", event.Type)
+	if event.ID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event has no ID"})
+		return
+	}
 
+	// Providers retry anything that does not get a 2xx, so failures must not return 200
+	err = PaymentWebhookService.HandleEvent(event.ID, event.Type, event.Data)
+	switch {
+	case errors.Is(err, repositories.ErrEventDone):
+		c.JSON(http.StatusOK, gin.H{"status": "duplicate"})
+	case errors.Is(err, repositories.ErrEventInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrEventNotApplied):
+		// Recorded as failed for an admin; retrying would fail the same way
+		c.JSON(http.StatusOK, gin.H{"status": "failed"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "event processing failed"})
 	default:
-		fmt.Println("⚠️ Unhandled Stripe event type:", event.Type)
+		c.JSON(http.StatusOK, gin.H{"status": "success"})
+	}
+}
+
+// GET /admin/webhooks/events?status=failed
+func ListWebhookEvents(c *gin.Context) {
+	events, err := PaymentWebhookService.ListEvents(c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
 
-	c.JSON(http.StatusOK, gin.H{"status": "success"})
+// POST /admin/webhooks/events/:id/replay
+func ReplayWebhookEvent(c *gin.Context) {
+	event, err := PaymentWebhookService.ReplayEvent(c.Param("id"))
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, services.ErrWebhookEventNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, repositories.ErrEventInProgress), errors.Is(err, repositories.ErrEventDone):
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "event replayed", "event": event})
 }
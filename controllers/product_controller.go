@@ -2,10 +2,14 @@ package controllers
 
 import (
 	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/repositories"
 	"beauty-ecommerce-backend/services"
 	"beauty-ecommerce-backend/utils"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -110,7 +114,7 @@ func (pc *ProductController) CreateProduct(c *gin.Context) {
 	product := models.Product{
 		Name:        name,
 		Description: description,
-		Price:       price,
+		Price:       models.MoneyFromFloat(price, models.DefaultCurrency),
 		Stock:       stock,
 		Category:    category,
 		ImageURL:    imageURL,
@@ -152,7 +156,7 @@ func (pc *ProductController) UpdateProduct(c *gin.Context) {
 		update.Description = *input.Description
 	}
 	if input.Price != nil {
-		update.Price = *input.Price
+		update.Price = models.MoneyFromFloat(*input.Price, models.DefaultCurrency)
 	}
 	if input.Stock != nil {
 		update.Stock = *input.Stock
@@ -188,25 +192,87 @@ func (pc *ProductController) UpdateProduct(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"product": product})
 }
 
+// GET /products?q=&category=&min_price=&max_price=&in_stock=&sort=&page=&limit=&cursor=
 func (pc *ProductController) GetAllProducts(c *gin.Context) {
-	products, err := pc.productService.GetAllProducts()
+	query := models.ProductQuery{
+		Search:   strings.TrimSpace(c.Query("q")),
+		Category: c.Query("category"),
+		Sort:     c.Query("sort"),
+		Cursor:   c.Query("cursor"),
+	}
+	query.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	query.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
+	query.InStock, _ = strconv.ParseBool(c.DefaultQuery("in_stock", "false"))
+
+	currency, rate, err := requestCurrency(c)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Price filters are given in the display currency; the catalog is stored in DefaultCurrency
+	if raw := c.Query("min_price"); raw != "" {
+		minPrice, err := strconv.ParseFloat(raw, 64)
+		if err != nil || minPrice < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid min_price"})
+			return
+		}
+		minMoney := models.MoneyFromFloat(minPrice, currency).Convert(models.DefaultCurrency, 1/rate)
+		query.MinPrice = &minMoney
+	}
+	if raw := c.Query("max_price"); raw != "" {
+		maxPrice, err := strconv.ParseFloat(raw, 64)
+		if err != nil || maxPrice < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid max_price"})
+			return
+		}
+		maxMoney := models.MoneyFromFloat(maxPrice, currency).Convert(models.DefaultCurrency, 1/rate)
+		query.MaxPrice = &maxMoney
+	}
+	if query.MinPrice != nil && query.MaxPrice != nil && query.MaxPrice.LessThan(*query.MinPrice) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_price cannot be greater than max_price"})
+		return
+	}
+
+	switch query.Sort {
+	case "", models.ProductSortNewest, models.ProductSortPriceAsc, models.ProductSortPriceDesc, models.ProductSortRating:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort, use one of: newest, price_asc, price_desc, rating"})
+		return
+	}
+
+	page, err := pc.productService.SearchProducts(query)
+	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"products": products})
+	for i := range page.Products {
+		page.Products[i].Convert(currency, rate)
+	}
+	c.JSON(http.StatusOK, page)
 }
 
 func (pc *ProductController) GetProductByID(c *gin.Context) {
 	id := c.Param("id")
 
+	currency, rate, err := requestCurrency(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	product, err := pc.productService.GetProductByID(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
 		return
 	}
 
+	product.Convert(currency, rate)
 	c.JSON(http.StatusOK, gin.H{"product": product})
 }
 
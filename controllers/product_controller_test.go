@@ -60,7 +60,7 @@ func TestCreateProduct(t *testing.T) {
 	product := models.Product{
 		Name:        "Test Product",
 		Description: "This is a test product",
-		Price:       10.5,
+		Price:       models.GBP(1050),
 		Stock:       5,
 		Category:    "Test Category",
 		ImageURL:    "http://example.com/image.jpg",
@@ -96,7 +96,7 @@ func TestDeleteProduct(t *testing.T) {
 	product := models.Product{
 		Name:        "Test Product",
 		Description: "Test",
-		Price:       10,
+		Price:       models.GBP(1000),
 		Stock:       2,
 		Category:    "Test",
 		ImageURL:    "http://img.com",
@@ -141,7 +141,7 @@ func TestUpdateProduct(t *testing.T) {
 	product := models.Product{
 		Name:        "Original Product",
 		Description: "Original description",
-		Price:       10.0,
+		Price:       models.GBP(1000),
 		Stock:       5,
 		Category:    "Original Category",
 		ImageURL:    "http://example.com/original.jpg",
@@ -166,7 +166,7 @@ func TestUpdateProduct(t *testing.T) {
 	update := models.Product{
 		Name:        "Updated Product",
 		Description: "Updated description",
-		Price:       20.0,
+		Price:       models.GBP(2000),
 		Stock:       10,
 		Category:    "Updated Category",
 		ImageURL:    "http://example.com/updated.jpg",
@@ -191,5 +191,5 @@ func TestUpdateProduct(t *testing.T) {
 	prod := updated["product"].(map[string]interface{})
 	assert.Equal(t, "Updated Product", prod["name"])
 	assert.Equal(t, "Updated description", prod["description"])
-	assert.Equal(t, float64(20.0), prod["price"])
+	assert.Equal(t, float64(2000), prod["price"].(map[string]interface{})["amount"])
 }
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/services"
+	"beauty-ecommerce-backend/utils"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RefundController struct {
+	service services.RefundService
+}
+
+func NewRefundController(service services.RefundService) *RefundController {
+	return &RefundController{service}
+}
+
+// POST /admin/orders/:id/refunds
+func (rc *RefundController) RefundOrder(c *gin.Context) {
+	orderID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID"})
+		return
+	}
+
+	// An empty body refunds the whole order
+	var req models.RefundRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+
+	adminID, _ := utils.ExtractUserIDAndRole(c)
+	order, refund, err := rc.service.RefundOrder(orderID, req, adminID)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, services.ErrOrderNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, services.ErrRefundNotRecorded):
+			status = http.StatusInternalServerError
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"refund": refund, "order": order})
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/services"
+	"beauty-ecommerce-backend/utils"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReturnController struct {
+	service services.ReturnService
+}
+
+func NewReturnController(service services.ReturnService) *ReturnController {
+	return &ReturnController{service}
+}
+
+// returnErrorStatus maps return service errors to HTTP statuses
+func returnErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrReturnNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrRefundNotRecorded):
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
+
+// -------------------- CUSTOMER --------------------
+
+// POST /orders/:id/returns
+func (rc *ReturnController) RequestReturn(c *gin.Context) {
+	orderID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+	userID, _ := utils.ExtractUserIDAndRole(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.ReturnRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "items and reason are required"})
+		return
+	}
+
+	ret, err := rc.service.RequestReturn(orderID, userID, req)
+	if err != nil {
+		c.JSON(returnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Return requested", "return": ret})
+}
+
+// GET /orders/:id/returns
+func (rc *ReturnController) GetOrderReturns(c *gin.Context) {
+	orderID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+	userID, _ := utils.ExtractUserIDAndRole(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	returns, err := rc.service.GetOrderReturns(orderID, userID)
+	if err != nil {
+		c.JSON(returnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"returns": returns})
+}
+
+// -------------------- ADMIN --------------------
+
+// GET /admin/returns?status=requested
+func (rc *ReturnController) ListReturns(c *gin.Context) {
+	status := models.ReturnStatus(strings.ToLower(c.Query("status")))
+	returns, err := rc.service.ListReturns(status)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"returns": returns})
+}
+
+// GET /admin/returns/:id
+func (rc *ReturnController) GetReturn(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid return ID"})
+		return
+	}
+
+	ret, err := rc.service.GetReturn(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"return": ret})
+}
+
+// POST /admin/returns/:id/approve
+func (rc *ReturnController) ApproveReturn(c *gin.Context) {
+	rc.review(c, rc.service.ApproveReturn)
+}
+
+// POST /admin/returns/:id/reject
+func (rc *ReturnController) RejectReturn(c *gin.Context) {
+	rc.review(c, rc.service.RejectReturn)
+}
+
+// POST /admin/returns/:id/receive
+func (rc *ReturnController) MarkReturnReceived(c *gin.Context) {
+	rc.review(c, rc.service.MarkReturnReceived)
+}
+
+// review runs one of the admin status changes that take an optional note
+func (rc *ReturnController) review(c *gin.Context, action func(id, adminID primitive.ObjectID, note string) (*models.Return, error)) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid return ID"})
+		return
+	}
+
+	var payload struct {
+		Note string `json:"note"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+
+	adminID, _ := utils.ExtractUserIDAndRole(c)
+	ret, err := action(id, adminID, payload.Note)
+	if err != nil {
+		c.JSON(returnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"return": ret})
+}
+
+// POST /admin/returns/:id/refund
+func (rc *ReturnController) RefundReturn(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid return ID"})
+		return
+	}
+
+	// Returned goods go back into stock unless the admin says otherwise,
+	// e.g. when they arrived damaged
+	payload := struct {
+		Restock *bool `json:"restock"`
+	}{}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+	restock := payload.Restock == nil || *payload.Restock
+
+	adminID, _ := utils.ExtractUserIDAndRole(c)
+	ret, order, err := rc.service.RefundReturn(id, adminID, restock)
+	if err != nil {
+		c.JSON(returnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"return": ret, "order": order})
+}
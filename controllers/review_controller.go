@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"beauty-ecommerce-backend/models"
 	"beauty-ecommerce-backend/services"
 	"beauty-ecommerce-backend/utils"
 	"net/http"
@@ -68,7 +69,7 @@ func (rc *ReviewController) GetProductReviews(c *gin.Context) {
 // ---------------- Update Review ----------------
 func (rc *ReviewController) UpdateReview(c *gin.Context) {
 	userID, role := utils.ExtractUserIDAndRole(c)
-	isAdmin := role == "ADMIN"
+	isAdmin := models.ParseRole(role).Can(models.PermReviewsModerate)
 
 	reviewID, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
@@ -98,7 +99,7 @@ func (rc *ReviewController) UpdateReview(c *gin.Context) {
 // ---------------- Delete Review ----------------
 func (rc *ReviewController) DeleteReview(c *gin.Context) {
 	userID, role := utils.ExtractUserIDAndRole(c)
-	isAdmin := role == "ADMIN"
+	isAdmin := models.ParseRole(role).Can(models.PermReviewsModerate)
 
 	reviewID, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/services"
+	"beauty-ecommerce-backend/utils"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ShipmentController struct {
+	service services.ShipmentService
+}
+
+func NewShipmentController(service services.ShipmentService) *ShipmentController {
+	return &ShipmentController{service}
+}
+
+// shipmentErrorStatus maps shipment service errors to HTTP statuses
+func shipmentErrorStatus(err error) int {
+	var transitionErr *models.StatusTransitionError
+	switch {
+	case errors.As(err, &transitionErr):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrShipmentNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// GET /admin/carriers
+func (sc *ShipmentController) ListCarriers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"carriers": sc.service.Carriers()})
+}
+
+// POST /admin/orders/:id/shipments
+func (sc *ShipmentController) CreateShipment(c *gin.Context) {
+	orderID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID"})
+		return
+	}
+
+	var req models.ShipmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	adminID, _ := utils.ExtractUserIDAndRole(c)
+	order, shipment, err := sc.service.CreateShipment(orderID, req, adminID)
+	if err != nil {
+		c.JSON(shipmentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"shipment":  shipment,
+		"label_url": shipment.LabelURL,
+		"order":     order,
+	})
+}
+
+// PATCH /admin/orders/:id/shipments/:shipmentId
+func (sc *ShipmentController) UpdateShipmentStatus(c *gin.Context) {
+	orderID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID"})
+		return
+	}
+	shipmentID, err := primitive.ObjectIDFromHex(c.Param("shipmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid shipment ID"})
+		return
+	}
+
+	var payload struct {
+		Status      string `json:"status" binding:"required"`
+		Description string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminID, _ := utils.ExtractUserIDAndRole(c)
+	status := models.ShipmentStatus(strings.ToLower(strings.TrimSpace(payload.Status)))
+
+	order, err := sc.service.UpdateShipmentStatus(orderID, shipmentID, status, payload.Description, adminID)
+	if err != nil {
+		c.JSON(shipmentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Shipment updated", "order": order})
+}
+
+// POST /admin/shipments/refresh
+func (sc *ShipmentController) RefreshTracking(c *gin.Context) {
+	count, err := sc.service.RefreshTracking()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": count})
+}
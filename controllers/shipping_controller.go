@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ShippingController struct {
+	service services.ShippingService
+	carts   services.CartService
+}
+
+func NewShippingController(service services.ShippingService, carts services.CartService) *ShippingController {
+	return &ShippingController{service: service, carts: carts}
+}
+
+// GET /shipping/quote?country=GB&postal_code=SW1A1AA
+// Prices the caller's cart (signed-in or guest) for the given address.
+func (sc *ShippingController) Quote(c *gin.Context) {
+	address := models.Address{
+		Country:    c.Query("country"),
+		PostalCode: c.Query("postal_code"),
+	}
+	if address.Country == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "country is required"})
+		return
+	}
+
+	owner, err := resolveCartOwner(c, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if owner.IsGuest() && owner.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "your cart is empty"})
+		return
+	}
+
+	currency, rate, err := requestCurrency(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := sc.carts.GetPricedCart(owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if cart.ItemCount == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "your cart is empty"})
+		return
+	}
+
+	quotes, err := sc.service.QuoteShipping(address, models.ShippingParcel{
+		Subtotal:    cart.Subtotal,
+		WeightGrams: cart.WeightGrams,
+	})
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Zones are priced in DefaultCurrency; show the quotes in the shopper's currency
+	for i := range quotes {
+		quotes[i].Price = quotes[i].Price.Convert(currency, rate)
+	}
+	cart.Convert(currency, rate)
+
+	c.JSON(http.StatusOK, gin.H{
+		"methods":      quotes,
+		"subtotal":     cart.Subtotal,
+		"weight_grams": cart.WeightGrams,
+	})
+}
+
+// POST /admin/shipping/zones
+func (sc *ShippingController) CreateZone(c *gin.Context) {
+	var zone models.ShippingZone
+	if err := c.ShouldBindJSON(&zone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	created, err := sc.service.CreateZone(zone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"zone": created})
+}
+
+// GET /admin/shipping/zones
+func (sc *ShippingController) ListZones(c *gin.Context) {
+	zones, err := sc.service.GetZones()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"zones": zones})
+}
+
+// GET /admin/shipping/zones/:id
+func (sc *ShippingController) GetZone(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid zone ID"})
+		return
+	}
+
+	zone, err := sc.service.GetZone(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"zone": zone})
+}
+
+// PUT /admin/shipping/zones/:id
+func (sc *ShippingController) UpdateZone(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid zone ID"})
+		return
+	}
+
+	var zone models.ShippingZone
+	if err := c.ShouldBindJSON(&zone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	updated, err := sc.service.UpdateZone(id, zone)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrShippingZoneNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"zone": updated})
+}
+
+// DELETE /admin/shipping/zones/:id
+func (sc *ShippingController) DeleteZone(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid zone ID"})
+		return
+	}
+
+	if err := sc.service.DeleteZone(id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrShippingZoneNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "shipping zone deleted"})
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TaxController struct {
+	service services.TaxService
+}
+
+func NewTaxController(service services.TaxService) *TaxController {
+	return &TaxController{service}
+}
+
+// GET /admin/tax/rates
+func (tc *TaxController) ListRates(c *gin.Context) {
+	rates, err := tc.service.GetRates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rates": rates})
+}
+
+// POST /admin/tax/rates
+func (tc *TaxController) CreateRate(c *gin.Context) {
+	var rate models.TaxRate
+	if err := c.ShouldBindJSON(&rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	created, err := tc.service.CreateRate(rate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"rate": created})
+}
+
+// PUT /admin/tax/rates/:id
+func (tc *TaxController) UpdateRate(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tax rate ID"})
+		return
+	}
+
+	var rate models.TaxRate
+	if err := c.ShouldBindJSON(&rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	updated, err := tc.service.UpdateRate(id, rate)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrTaxRateNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rate": updated})
+}
+
+// DELETE /admin/tax/rates/:id
+func (tc *TaxController) DeleteRate(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tax rate ID"})
+		return
+	}
+
+	if err := tc.service.DeleteRate(id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrTaxRateNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "tax rate deleted"})
+}
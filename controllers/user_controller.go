@@ -2,24 +2,33 @@ package controllers
 
 import (
 	"beauty-ecommerce-backend/models"
-	"beauty-ecommerce-backend/repositories"
 	"beauty-ecommerce-backend/services"
-	servicesimpl "beauty-ecommerce-backend/services_impl"
 	"beauty-ecommerce-backend/utils"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-var userService services.UserService
+var (
+	userService    services.UserService
+	sessionService services.SessionService
+)
+
+func InitUserController(service services.UserService, sessions services.SessionService) {
+	userService = service
+	sessionService = sessions
+}
 
-func InitUserController(userRepo *repositories.UserRepository) {
-	userService = servicesimpl.NewUserService(userRepo)
+// sessionClient describes the device a sign-in comes from
+func sessionClient(c *gin.Context) services.SessionClient {
+	return services.SessionClient{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
 }
 
 func Register(c *gin.Context) {
@@ -50,6 +59,8 @@ func Register(c *gin.Context) {
 `, user.Name)
 	utils.QueueEmail(user.Email, user.Name, subject, html)
 
+	mergeGuestCart(c, user.Email)
+
 	c.JSON(http.StatusOK, gin.H{"message": "User registered successfully"})
 }
 
@@ -60,13 +71,79 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	token, err := userService.Login(input.Email, input.Password)
+	tokens, err := userService.Login(input.Email, input.Password, sessionClient(c))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Login successful", "token": token})
+	mergeGuestCart(c, input.Email)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Login successful",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+// POST /auth/refresh
+// Trades a refresh token for a new access token and refresh token; the old
+// refresh token stops working
+func RefreshSession(c *gin.Context) {
+	var body struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
+
+	tokens, err := sessionService.Refresh(body.RefreshToken, sessionClient(c))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			status = http.StatusUnauthorized
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// POST /auth/logout
+// Ends the session holding the refresh token. Its access token lapses within
+// utils.AccessTokenTTL.
+func Logout(c *gin.Context) {
+	var body struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
+
+	if err := sessionService.Logout(body.RefreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// POST /auth/logout-all
+// Signs the user out on every device, this one included
+func LogoutAll(c *gin.Context) {
+	userID, _ := utils.ExtractUserIDAndRole(c)
+	if userID == primitive.NilObjectID {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := sessionService.RevokeAll(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "logged out of all devices"})
 }
 
 func GetProfile(c *gin.Context) {
@@ -87,6 +164,35 @@ func GetProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"user": user})
 }
 
+// PUT /users/me/currency
+func UpdatePreferredCurrency(c *gin.Context) {
+	userID, _ := utils.ExtractUserIDAndRole(c)
+	if userID == primitive.NilObjectID {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var body struct {
+		Currency string `json:"currency" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "currency is required"})
+		return
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(body.Currency))
+	if _, err := currencyService.Rate(currency); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := userService.UpdateCurrency(userID, currency); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "preferred currency updated", "currency": currency})
+}
+
 func ForgotPassword(c *gin.Context) {
 	type Request struct {
 		Email string `json:"email" binding:"required,email"`
@@ -160,9 +266,21 @@ func ResetPassword(c *gin.Context) {
 		return
 	}
 
-	hashedPassword, _ := utils.HashPassword(req.NewPassword)
-	_ = userService.UpdatePassword(user.ID, hashedPassword)
-	_ = userService.ClearResetToken(user.ID)
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not reset password"})
+		return
+	}
+	// The token is kept until the password is changed and every session
+	// signed out, so a failure here can be retried with the same link
+	if err := userService.UpdatePassword(user.ID, hashedPassword); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not reset password"})
+		return
+	}
+	if err := userService.ClearResetToken(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not reset password"})
+		return
+	}
 
 	subject := "Your password has been reset"
 	html := fmt.Sprintf(`
@@ -7,7 +7,6 @@ import (
 
 	"beauty-ecommerce-backend/config"
 	"beauty-ecommerce-backend/controllers"
-	"beauty-ecommerce-backend/middlewares"
 	"beauty-ecommerce-backend/routes"
 	"beauty-ecommerce-backend/utils"
 
@@ -22,10 +21,6 @@ func main() {
 		log.Println("⚠️ Could not load .env file, relying on environment variables")
 	}
 
-	// Set JWT secret
-	middlewares.JwtSecret = []byte(os.Getenv("JWT_SECRET"))
-	fmt.Println("✅ JwtSecret set")
-
 	// Connect to MongoDB
 	config.ConnectDB()
 	fmt.Println("✅ Database connected")
@@ -35,18 +30,11 @@ func main() {
 	utils.StartEmailWorker()
 	fmt.Println("✅ Brevo initialized and email worker started")
 
-	// Add temporary test order (optional)
-	utils.AddTestOrder(&utils.Order{
-		ID:              "order_123",
-		PaymentIntentID: "pi_3SajFMRhIgDY5Lro1wAWon5R",
-		Status:          "pending",
-	})
-
 	// Initialize Gin router
 	router := gin.Default()
 
 	// Payment webhook
-	router.POST("/payment/webhook", controllers.StripeWebhook)
+	router.POST("/payment/webhook", controllers.PaymentWebhook)
 
 	// Security headers
 	router.Use(func(c *gin.Context) {
@@ -62,7 +50,8 @@ func main() {
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Cart-Token", "X-Currency"},
+		ExposeHeaders:    []string{"X-Cart-Token"},
 		AllowCredentials: true,
 	}))
 
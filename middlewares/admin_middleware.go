@@ -1,63 +1,65 @@
 package middlewares
 
 import (
-	"fmt"
+	"beauty-ecommerce-backend/models"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var JwtSecret []byte
-
-type AdminClaims struct {
-	UserID string `json:"user_id"`
-	Role   string `json:"role"`
-	jwt.RegisteredClaims
+// roleFromContext reads the role from the claims JWTMiddleware verified
+func roleFromContext(c *gin.Context) (models.Role, bool) {
+	value, exists := c.Get("user")
+	if !exists {
+		return "", false
+	}
+	claims, ok := value.(jwt.MapClaims)
+	if !ok {
+		return "", false
+	}
+	role, _ := claims["role"].(string)
+	return models.ParseRole(role), true
 }
 
+// AdminMiddleware lets any staff account into the admin API; what it may do
+// there is decided per route by RequirePermission. Runs after JWTMiddleware.
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing Authorization header"})
+		role, ok := roleFromContext(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid token"})
 			c.Abort()
 			return
 		}
-
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
+		if !role.Staff() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
 			c.Abort()
 			return
 		}
 
-		tokenStr := parts[1]
-
-		claims := &AdminClaims{}
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return JwtSecret, nil
-		})
+		c.Set("role", string(role))
+		c.Next()
+	}
+}
 
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+// RequirePermission only lets through staff whose role has every one of the
+// permissions. Runs after JWTMiddleware.
+func RequirePermission(permissions ...models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, ok := roleFromContext(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid token"})
 			c.Abort()
 			return
 		}
-
-		if strings.ToLower(claims.Role) != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
-			c.Abort()
-			return
+		for _, permission := range permissions {
+			if !role.Can(permission) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Missing permission: " + string(permission)})
+				c.Abort()
+				return
+			}
 		}
-
-		c.Set("user_id", claims.UserID)
-		c.Set("role", claims.Role)
-
 		c.Next()
 	}
 }
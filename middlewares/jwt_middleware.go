@@ -1,6 +1,8 @@
 package middlewares
 
 import (
+	"beauty-ecommerce-backend/repositories"
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -9,8 +11,18 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// tokenUsers looks up the token version each access token must match
+var tokenUsers *repositories.UserRepository
+
+// InitJWTMiddleware gives the middleware the users whose token versions it
+// checks; until it is called every token is refused
+func InitJWTMiddleware(users *repositories.UserRepository) {
+	tokenUsers = users
+}
+
 // JWTMiddleware verifies JWT token and sets claims in Gin context
 func JWTMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -70,9 +82,48 @@ func JWTMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Logging out everywhere, a password reset or a role change bumps the
+		// user's token version, which retires every token issued before it
+		if !currentTokenVersion(c.Request.Context(), userID, claims["ver"]) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked, please log in again"})
+			c.Abort()
+			return
+		}
+
 		c.Set("user_id", userID)
 		c.Set("user", claims)
 
 		c.Next()
 	}
 }
+
+// currentTokenVersion reports whether the token's version is the user's
+// current one. Tokens from before versions were issued have none.
+func currentTokenVersion(ctx context.Context, userID string, claim interface{}) bool {
+	version, ok := claim.(float64)
+	if !ok || tokenUsers == nil {
+		return false
+	}
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false
+	}
+	current, err := tokenUsers.TokenVersion(ctx, id)
+	if err != nil {
+		return false
+	}
+	return int(version) == current
+}
+
+// OptionalJWTMiddleware lets anonymous requests through, but still rejects a
+// bad token when an Authorization header is sent
+func OptionalJWTMiddleware() gin.HandlerFunc {
+	required := JWTMiddleware()
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		required(c)
+	}
+}
@@ -9,8 +9,88 @@ import (
 type CartItem struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	ProductID primitive.ObjectID `bson:"product_id" json:"product_id"`
-	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	VariantID primitive.ObjectID `bson:"variant_id,omitempty" json:"variant_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	CartToken string             `bson:"cart_token,omitempty" json:"-"` // set on guest lines instead of user_id
 	Quantity  int                `bson:"quantity" json:"quantity"`
+	UnitPrice Money              `bson:"unit_price" json:"unit_price"` // catalog price when the item was added
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
 }
+
+// CartOwner identifies a cart: a signed-in user or a guest cart token
+type CartOwner struct {
+	UserID primitive.ObjectID
+	Token  string
+}
+
+func (o CartOwner) IsGuest() bool {
+	return o.UserID.IsZero()
+}
+
+// Owns reports whether the cart line belongs to this owner
+func (o CartOwner) Owns(item *CartItem) bool {
+	if o.IsGuest() {
+		return o.Token != "" && item.CartToken == o.Token
+	}
+	return item.UserID == o.UserID
+}
+
+// PricedCartItem is a cart line priced against the current catalog
+type PricedCartItem struct {
+	ID           primitive.ObjectID `json:"id"`
+	ProductID    primitive.ObjectID `json:"product_id"`
+	VariantID    string             `json:"variant_id,omitempty"`
+	Name         string             `json:"name"`
+	VariantLabel string             `json:"variant_label,omitempty"`
+	ImageURL     string             `json:"image_url"`
+	UnitPrice    Money              `json:"unit_price"`
+	Quantity     int                `json:"quantity"`
+	LineTotal    Money              `json:"line_total"`
+	Available    int                `json:"available"`
+	InStock      bool               `json:"in_stock"`
+}
+
+// PricedCart is what GET /cart returns. Lines that are no longer available are
+// listed but left out of the subtotal.
+type PricedCart struct {
+	Items       []PricedCartItem `json:"items"`
+	ItemCount   int              `json:"item_count"`
+	Subtotal    Money            `json:"subtotal"`
+	WeightGrams int              `json:"weight_grams"`
+}
+
+// Reasons a cart line can differ from the catalog at checkout
+const (
+	CartChangePriceChanged    = "price_changed"
+	CartChangeOutOfStock      = "out_of_stock"
+	CartChangeQuantityReduced = "quantity_reduced"
+	CartChangeUnavailable     = "unavailable"
+)
+
+// CartChange describes a cart line that changed since it was added
+type CartChange struct {
+	ProductID   string `json:"product_id"`
+	VariantID   string `json:"variant_id,omitempty"`
+	ProductName string `json:"product_name,omitempty"`
+	Reason      string `json:"reason"`
+	OldPrice    *Money `json:"old_price,omitempty"`
+	NewPrice    *Money `json:"new_price,omitempty"`
+	Requested   int    `json:"requested_quantity,omitempty"`
+	Available   int    `json:"available_quantity,omitempty"`
+}
+
+// Convert reprices the cart in another currency. Each unit price is converted
+// and the totals rebuilt from it, so lines always add up to the subtotal.
+func (c *PricedCart) Convert(currency string, rate float64) {
+	subtotal := Money{Currency: currency}
+	for i := range c.Items {
+		line := &c.Items[i]
+		line.UnitPrice = line.UnitPrice.Convert(currency, rate)
+		line.LineTotal = line.UnitPrice.Mul(line.Quantity)
+		if line.InStock {
+			subtotal = subtotal.Add(line.LineTotal)
+		}
+	}
+	c.Subtotal = subtotal
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Coupon types
+const (
+	CouponPercentage   = "percentage"    // Value is a percentage off eligible items
+	CouponFixed        = "fixed"         // Amount is taken off eligible items
+	CouponFreeShipping = "free_shipping" // waives the shipping fee
+	CouponBuyXGetY     = "buy_x_get_y"   // for every BuyQuantity+GetQuantity units, the cheapest GetQuantity are free
+)
+
+type Coupon struct {
+	ID           primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Code         string               `bson:"code" json:"code"` // stored upper-case
+	Description  string               `bson:"description" json:"description"`
+	Type         string               `bson:"type" json:"type"`
+	Value        float64              `bson:"value" json:"value"`   // percentage, for percentage coupons
+	Amount       Money                `bson:"amount" json:"amount"` // for fixed coupons
+	BuyQuantity  int                  `bson:"buy_quantity,omitempty" json:"buy_quantity,omitempty"`
+	GetQuantity  int                  `bson:"get_quantity,omitempty" json:"get_quantity,omitempty"`
+	MinSpend     Money                `bson:"min_spend" json:"min_spend"`
+	StartsAt     *time.Time           `bson:"starts_at,omitempty" json:"starts_at,omitempty"`
+	EndsAt       *time.Time           `bson:"ends_at,omitempty" json:"ends_at,omitempty"`
+	UsageLimit   int                  `bson:"usage_limit" json:"usage_limit"`       // 0 means unlimited
+	PerUserLimit int                  `bson:"per_user_limit" json:"per_user_limit"` // 0 means unlimited
+	UsedCount    int                  `bson:"used_count" json:"used_count"`
+	ProductIDs   []primitive.ObjectID `bson:"product_ids,omitempty" json:"product_ids,omitempty"`
+	Categories   []string             `bson:"categories,omitempty" json:"categories,omitempty"`
+	Active       bool                 `bson:"active" json:"active"`
+	CreatedAt    time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// Restricted reports whether the coupon only applies to some products or categories
+func (c *Coupon) Restricted() bool {
+	return len(c.ProductIDs) > 0 || len(c.Categories) > 0
+}
+
+// AppliesTo reports whether a product is eligible for the coupon
+func (c *Coupon) AppliesTo(productID primitive.ObjectID, category string) bool {
+	if !c.Restricted() {
+		return true
+	}
+	for _, id := range c.ProductIDs {
+		if id == productID {
+			return true
+		}
+	}
+	for _, cat := range c.Categories {
+		if cat == category {
+			return true
+		}
+	}
+	return false
+}
+
+// CouponRedemption records one use of a coupon by an order. It is removed again
+// if the order is cancelled, fails or expires before payment.
+type CouponRedemption struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CouponID  primitive.ObjectID `bson:"coupon_id" json:"coupon_id"`
+	Code      string             `bson:"code" json:"code"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	OrderID   primitive.ObjectID `bson:"order_id" json:"order_id"`
+	Amount    Money              `bson:"amount" json:"amount"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// DiscountLine is a discount applied to an order
+type DiscountLine struct {
+	CouponID    primitive.ObjectID `bson:"coupon_id" json:"-"`
+	Code        string             `bson:"code" json:"code"`
+	Type        string             `bson:"type" json:"type"`
+	Description string             `bson:"description" json:"description"`
+	Amount      Money              `bson:"amount" json:"amount"`
+}
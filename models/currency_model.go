@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExchangeRate prices the catalog in a currency other than DefaultCurrency.
+// Rate is units of Currency per one unit of DefaultCurrency, e.g. 1.17 for EUR.
+type ExchangeRate struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Currency  string             `bson:"currency" json:"currency"` // ISO 4217, e.g. "EUR"
+	Rate      float64            `bson:"rate" json:"rate"`
+	Active    bool               `bson:"active" json:"active"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DisputeStatus follows the provider's dispute lifecycle. Warnings are
+// inquiries from the customer's bank that have not taken any money yet.
+type DisputeStatus string
+
+const (
+	DisputeWarningNeedsResponse DisputeStatus = "warning_needs_response"
+	DisputeWarningUnderReview   DisputeStatus = "warning_under_review"
+	DisputeWarningClosed        DisputeStatus = "warning_closed"
+	DisputeNeedsResponse        DisputeStatus = "needs_response"
+	DisputeUnderReview          DisputeStatus = "under_review"
+	DisputeChargeRefunded       DisputeStatus = "charge_refunded"
+	DisputeWon                  DisputeStatus = "won"
+	DisputeLost                 DisputeStatus = "lost"
+)
+
+func (s DisputeStatus) Valid() bool {
+	switch s {
+	case DisputeWarningNeedsResponse, DisputeWarningUnderReview, DisputeWarningClosed,
+		DisputeNeedsResponse, DisputeUnderReview, DisputeChargeRefunded, DisputeWon, DisputeLost:
+		return true
+	}
+	return false
+}
+
+// Closed reports whether the dispute has an outcome
+func (s DisputeStatus) Closed() bool {
+	switch s {
+	case DisputeWarningClosed, DisputeChargeRefunded, DisputeWon, DisputeLost:
+		return true
+	}
+	return false
+}
+
+// Warning reports whether the dispute is still only an inquiry
+func (s DisputeStatus) Warning() bool {
+	switch s {
+	case DisputeWarningNeedsResponse, DisputeWarningUnderReview, DisputeWarningClosed:
+		return true
+	}
+	return false
+}
+
+// Won reports whether the shop kept the money
+func (s DisputeStatus) Won() bool {
+	return s == DisputeWon || s == DisputeWarningClosed
+}
+
+// Dispute is a chargeback or inquiry raised against an order's payment
+type Dispute struct {
+	ID               primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Provider         string              `bson:"provider" json:"provider"`
+	ProviderID       string              `bson:"provider_id" json:"provider_id"`
+	OrderID          *primitive.ObjectID `bson:"order_id,omitempty" json:"order_id,omitempty"` // unset when no order matched the payment
+	PaymentReference string              `bson:"payment_reference,omitempty" json:"payment_reference,omitempty"`
+	ChargeID         string              `bson:"charge_id,omitempty" json:"charge_id,omitempty"`
+	Amount           Money               `bson:"amount" json:"amount"`
+	Reason           string              `bson:"reason,omitempty" json:"reason,omitempty"`
+	Status           DisputeStatus       `bson:"status" json:"status"`
+	EvidenceDueBy    *time.Time          `bson:"evidence_due_by,omitempty" json:"evidence_due_by,omitempty"` // evidence must reach the provider by then
+	History          []DisputeChange     `bson:"history,omitempty" json:"history,omitempty"`
+	CreatedAt        time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time           `bson:"updated_at" json:"updated_at"`
+	ClosedAt         *time.Time          `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
+}
+
+// DisputeChange is one status the dispute passed through
+type DisputeChange struct {
+	Status DisputeStatus `bson:"status" json:"status"`
+	At     time.Time     `bson:"at" json:"at"`
+}
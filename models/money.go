@@ -0,0 +1,212 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// DefaultCurrency is the shop's base currency
+const DefaultCurrency = "GBP"
+
+// Money is an amount in integer minor units (pence, cents) of a currency.
+// Storing minor units keeps totals exact; convert from floats only at the edges.
+type Money struct {
+	Amount   int64  `bson:"amount" json:"amount"`
+	Currency string `bson:"currency" json:"currency"` // ISO 4217, upper-case
+}
+
+// UnmarshalBSONValue also reads prices stored as plain numbers of major units
+// before Money existed, taking them to be in DefaultCurrency. Documents can
+// then still be read before, or while, tools/migrate_money runs.
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	raw := bson.RawValue{Type: t, Value: data}
+	switch t {
+	case bsontype.Double:
+		*m = MoneyFromFloat(raw.Double(), DefaultCurrency)
+	case bsontype.Int32:
+		*m = MoneyFromFloat(float64(raw.Int32()), DefaultCurrency)
+	case bsontype.Int64:
+		*m = MoneyFromFloat(float64(raw.Int64()), DefaultCurrency)
+	case bsontype.Null, bsontype.Undefined:
+		*m = Money{}
+	default:
+		// A Money document; the plain type decodes it without recursing here
+		type plain Money
+		var decoded plain
+		if err := raw.Unmarshal(&decoded); err != nil {
+			return err
+		}
+		*m = Money(decoded)
+	}
+	return nil
+}
+
+// currencyExponents lists currencies that do not use two decimal places
+var currencyExponents = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+}
+
+var currencySymbols = map[string]string{
+	"GBP": "£",
+	"USD": "$",
+	"EUR": "€",
+	"NGN": "₦",
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// GBP builds an amount in pence
+func GBP(pence int64) Money {
+	return Money{Amount: pence, Currency: DefaultCurrency}
+}
+
+// MoneyFromFloat converts a major-unit amount such as 12.99, rounding to the
+// nearest minor unit
+func MoneyFromFloat(value float64, currency string) Money {
+	currency = strings.ToUpper(currency)
+	scale := math.Pow10(CurrencyExponent(currency))
+	return Money{Amount: int64(math.Round(value * scale)), Currency: currency}
+}
+
+// CurrencyExponent is the number of minor-unit digits of a currency
+func CurrencyExponent(currency string) int {
+	if exp, ok := currencyExponents[strings.ToUpper(currency)]; ok {
+		return exp
+	}
+	return 2
+}
+
+// Float returns the amount in major units. Use it for display only.
+func (m Money) Float() float64 {
+	return float64(m.Amount) / math.Pow10(CurrencyExponent(m.Currency))
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsPositive() bool { return m.Amount > 0 }
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+
+// currencyWith picks the currency for the result of combining two amounts. A
+// zero value without a currency adopts the other side's.
+func (m Money) currencyWith(o Money) string {
+	if m.Currency == "" {
+		return o.Currency
+	}
+	if o.Currency != "" && o.Currency != m.Currency {
+		panic(fmt.Sprintf("money: cannot combine %s with %s", m.Currency, o.Currency))
+	}
+	return m.Currency
+}
+
+func (m Money) Add(o Money) Money {
+	return Money{Amount: m.Amount + o.Amount, Currency: m.currencyWith(o)}
+}
+
+func (m Money) Sub(o Money) Money {
+	return Money{Amount: m.Amount - o.Amount, Currency: m.currencyWith(o)}
+}
+
+// Mul multiplies by a quantity
+func (m Money) Mul(quantity int) Money {
+	return Money{Amount: m.Amount * int64(quantity), Currency: m.Currency}
+}
+
+// Percent returns pct percent of the amount, rounded to the nearest minor unit
+func (m Money) Percent(pct float64) Money {
+	return Money{Amount: int64(math.Round(float64(m.Amount) * pct / 100)), Currency: m.Currency}
+}
+
+// Ratio returns amount × num / den, rounded to the nearest minor unit
+func (m Money) Ratio(num, den int64) Money {
+	if den == 0 {
+		return Money{Currency: m.Currency}
+	}
+	return Money{Amount: int64(math.Round(float64(m.Amount) * float64(num) / float64(den))), Currency: m.Currency}
+}
+
+func (m Money) LessThan(o Money) bool {
+	m.currencyWith(o)
+	return m.Amount < o.Amount
+}
+
+// Convert prices the amount in another currency. rate is units of currency per
+// unit of m's currency; the result is rounded to the nearest minor unit.
+func (m Money) Convert(currency string, rate float64) Money {
+	currency = strings.ToUpper(currency)
+	if currency == m.Currency {
+		return m
+	}
+	return MoneyFromFloat(m.Float()*rate, currency)
+}
+
+// Min returns the smaller of two amounts
+func (m Money) Min(o Money) Money {
+	if o.LessThan(m) {
+		return o
+	}
+	return m
+}
+
+// String renders the amount with its currency code, e.g. "12.99 GBP"
+func (m Money) String() string {
+	return fmt.Sprintf("%.*f %s", CurrencyExponent(m.Currency), m.Float(), m.Currency)
+}
+
+// Format renders the amount with its currency symbol, e.g. "£12.99"
+func (m Money) Format() string {
+	symbol, ok := currencySymbols[m.Currency]
+	if !ok {
+		return m.String()
+	}
+	sign := ""
+	if m.Amount < 0 {
+		sign = "-"
+	}
+	abs := Money{Amount: m.Amount, Currency: m.Currency}
+	if abs.Amount < 0 {
+		abs.Amount = -abs.Amount
+	}
+	return fmt.Sprintf("%s%s%.*f", sign, symbol, CurrencyExponent(m.Currency), abs.Float())
+}
+
+// ParseMoney reads an amount written by String, e.g. "12.99 GBP". A bare
+// number is taken to be in DefaultCurrency. Parsing is exact, without floats.
+func ParseMoney(s string) (Money, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return Money{}, fmt.Errorf("money: invalid amount %q", s)
+	}
+	currency := DefaultCurrency
+	if len(fields) == 2 {
+		currency = strings.ToUpper(fields[1])
+	}
+
+	number := fields[0]
+	negative := strings.HasPrefix(number, "-")
+	number = strings.TrimPrefix(number, "-")
+
+	whole, frac, _ := strings.Cut(number, ".")
+	exp := CurrencyExponent(currency)
+	if len(frac) > exp {
+		return Money{}, fmt.Errorf("money: too many decimal places in %q", s)
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	var amount int64
+	for _, r := range whole + frac {
+		if r < '0' || r > '9' {
+			return Money{}, fmt.Errorf("money: invalid amount %q", s)
+		}
+		amount = amount*10 + int64(r-'0')
+	}
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
@@ -14,17 +14,46 @@ type Order struct {
 	CustomerPhone    string             `bson:"customer_phone" json:"customer_phone"`
 	ShippingAddress  Address            `bson:"shipping_address" json:"shipping_address"`
 	Items            []OrderItem        `bson:"items" json:"items"`
-	Subtotal         float64            `bson:"subtotal" json:"subtotal"`
-	ShippingFee      float64            `bson:"shipping_fee" json:"shipping_fee"`
+	Subtotal         Money              `bson:"subtotal" json:"subtotal"`
+	ShippingFee      Money              `bson:"shipping_fee" json:"shipping_fee"`
+	CouponCode       string             `bson:"coupon_code,omitempty" json:"coupon_code,omitempty"`
+	Discounts        []DiscountLine     `bson:"discounts,omitempty" json:"discounts,omitempty"`
+	DiscountTotal    Money              `bson:"discount_total" json:"discount_total"`
+	ShippingTax      Money              `bson:"shipping_tax" json:"shipping_tax"`
+	TaxLines         []TaxLine          `bson:"tax_lines,omitempty" json:"tax_lines,omitempty"`
+	TaxTotal         Money              `bson:"tax_total" json:"tax_total"`
 	DeliveryType     string             `bson:"delivery_type" json:"delivery_type"`
-	TotalPrice       float64            `bson:"total_price" json:"total_price"`
-	Status           string             `bson:"status" json:"status"`
+	TotalPrice       Money              `bson:"total_price" json:"total_price"`
+	Currency         string             `bson:"currency" json:"currency"`           // every amount on the order is in this currency
+	ExchangeRate     float64            `bson:"exchange_rate" json:"exchange_rate"` // DefaultCurrency to Currency, locked at checkout
+	Status           OrderStatus        `bson:"status" json:"status"`
+	StatusHistory    []StatusChange     `bson:"status_history,omitempty" json:"status_history,omitempty"`
 	PaymentReference string             `bson:"payment_reference" json:"payment_reference"`
 	PaymentStatus    string             `bson:"payment_status" json:"payment_status"`
+	Reservation      *StockReservation  `bson:"reservation,omitempty" json:"reservation,omitempty"`
+	Shipments        []Shipment         `bson:"shipments,omitempty" json:"shipments,omitempty"`
+	Refunds          []Refund           `bson:"refunds,omitempty" json:"refunds,omitempty"`
 	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
+// Reservation states
+const (
+	ReservationHeld      = "held"
+	ReservationCommitted = "committed"
+	ReservationReleased  = "released"
+)
+
+// StockReservation is the time-limited hold placed on an order's quantities when
+// it is created. Held stock is taken out of Product.Stock straight away, so the
+// catalog never oversells; payment commits the hold, while cancellation, payment
+// failure or expiry release it back.
+type StockReservation struct {
+	Status     string     `bson:"status" json:"status"`
+	ExpiresAt  time.Time  `bson:"expires_at" json:"expires_at"`
+	ReleasedAt *time.Time `bson:"released_at,omitempty" json:"released_at,omitempty"`
+}
+
 type Address struct {
 	Street     string `bson:"street" json:"street"`
 	City       string `bson:"city" json:"city"`
@@ -34,8 +63,38 @@ type Address struct {
 }
 
 type OrderItem struct {
-	ProductID   string  `bson:"product_id" json:"product_id"`
-	ProductName string  `bson:"product_name" json:"product_name"`
-	Quantity    int     `bson:"quantity" json:"quantity"`
-	Price       float64 `bson:"price" json:"price"`
+	ProductID    string `bson:"product_id" json:"product_id"`
+	VariantID    string `bson:"variant_id,omitempty" json:"variant_id,omitempty"`
+	SKU          string `bson:"sku,omitempty" json:"sku,omitempty"`
+	VariantLabel string `bson:"variant_label,omitempty" json:"variant_label,omitempty"`
+	ProductName  string `bson:"product_name" json:"product_name"`
+	Category     string `bson:"category,omitempty" json:"category,omitempty"`
+	Quantity     int    `bson:"quantity" json:"quantity"`
+	Price        Money  `bson:"price" json:"price"`
+
+	// Set at checkout: this line's share of the order discount and its tax
+	DiscountAmount Money   `bson:"discount_amount" json:"discount_amount"`
+	TaxName        string  `bson:"tax_name,omitempty" json:"tax_name,omitempty"`
+	TaxRate        float64 `bson:"tax_rate" json:"tax_rate"`
+	TaxAmount      Money   `bson:"tax_amount" json:"tax_amount"`
+}
+
+// VariantObjectID returns the parsed variant ID, or NilObjectID for plain products
+func (i OrderItem) VariantObjectID() primitive.ObjectID {
+	if i.VariantID == "" {
+		return primitive.NilObjectID
+	}
+	id, err := primitive.ObjectIDFromHex(i.VariantID)
+	if err != nil {
+		return primitive.NilObjectID
+	}
+	return id
+}
+
+// DisplayName returns the product name with the variant label, if any
+func (i OrderItem) DisplayName() string {
+	if i.VariantLabel == "" {
+		return i.ProductName
+	}
+	return i.ProductName + " (" + i.VariantLabel + ")"
 }
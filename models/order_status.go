@@ -0,0 +1,125 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// OrderStatus is where an order is in its lifecycle. Orders only move along
+// the transitions in orderTransitions.
+type OrderStatus string
+
+const (
+	OrderPending    OrderStatus = "pending"    // placed, stock held, awaiting payment
+	OrderPaid       OrderStatus = "paid"       // payment captured
+	OrderProcessing OrderStatus = "processing" // being picked and packed
+	OrderShipped    OrderStatus = "shipped"
+	OrderDelivered  OrderStatus = "delivered"
+	OrderCancelled  OrderStatus = "cancelled" // cancelled before payment
+	OrderFailed     OrderStatus = "failed"    // payment failed
+	OrderExpired    OrderStatus = "expired"   // stock hold ran out before payment
+	OrderRefunded   OrderStatus = "refunded"
+	OrderDisputed   OrderStatus = "disputed" // the customer's bank opened a chargeback
+
+	// OrderPartiallyRefunded is a paid order with part of its money returned.
+	// What is left can still be fulfilled.
+	OrderPartiallyRefunded OrderStatus = "partially_refunded"
+)
+
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPending:    {OrderPaid, OrderFailed, OrderCancelled, OrderExpired, OrderRefunded, OrderDisputed},
+	OrderExpired:    {OrderPaid}, // a late payment still completes the order if stock allows
+	OrderPaid:       {OrderProcessing, OrderShipped, OrderRefunded, OrderPartiallyRefunded, OrderDisputed},
+	OrderProcessing: {OrderShipped, OrderRefunded, OrderPartiallyRefunded, OrderDisputed},
+	OrderShipped:    {OrderDelivered, OrderRefunded, OrderPartiallyRefunded, OrderDisputed},
+	OrderDelivered:  {OrderRefunded, OrderPartiallyRefunded, OrderDisputed},
+	OrderDisputed:   {OrderPaid, OrderRefunded}, // dispute won or lost; see RestorableAfterDispute
+
+	OrderPartiallyRefunded: {OrderProcessing, OrderShipped, OrderDelivered, OrderRefunded, OrderDisputed},
+}
+
+// adminStatuses are the statuses an admin may set by hand. The others follow
+// from payments, refunds and the reservation sweeper.
+var adminStatuses = map[OrderStatus]bool{
+	OrderProcessing: true,
+	OrderShipped:    true,
+	OrderDelivered:  true,
+	OrderCancelled:  true,
+}
+
+// Valid reports whether s is a known status
+func (s OrderStatus) Valid() bool {
+	if _, ok := orderTransitions[s]; ok {
+		return true
+	}
+	switch s {
+	case OrderCancelled, OrderFailed, OrderRefunded:
+		return true
+	}
+	return false
+}
+
+// Next lists the statuses an order can move to from s
+func (s OrderStatus) Next() []OrderStatus {
+	return orderTransitions[s]
+}
+
+func (s OrderStatus) CanTransitionTo(to OrderStatus) bool {
+	for _, next := range orderTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// RestorableAfterDispute reports whether a won dispute may put an order back
+// to s, which is any paid status it could have been disputed from. These moves
+// are left out of orderTransitions so only the dispute outcome can make them.
+func (s OrderStatus) RestorableAfterDispute() bool {
+	return s != OrderPending && s.CanTransitionTo(OrderDisputed)
+}
+
+// AdminSettable reports whether admins may move an order to s directly
+func (s OrderStatus) AdminSettable() bool {
+	return adminStatuses[s]
+}
+
+// StatusTransitionError is returned for a change the state machine does not allow
+type StatusTransitionError struct {
+	From OrderStatus
+	To   OrderStatus
+}
+
+func (e *StatusTransitionError) Error() string {
+	if !e.To.Valid() {
+		return fmt.Sprintf("unknown order status %q", e.To)
+	}
+	return fmt.Sprintf("an order cannot move from %s to %s", e.From, e.To)
+}
+
+// CheckTransition returns a *StatusTransitionError unless from → to is allowed
+func CheckTransition(from, to OrderStatus) error {
+	if !from.CanTransitionTo(to) {
+		return &StatusTransitionError{From: from, To: to}
+	}
+	return nil
+}
+
+// Who changed an order's status
+const (
+	ActorCustomer = "customer"
+	ActorAdmin    = "admin"
+	ActorPayment  = "payment" // the payment provider, via webhooks
+	ActorSystem   = "system"  // background jobs such as the reservation sweeper
+)
+
+// StatusChange is one entry in an order's append-only status history
+type StatusChange struct {
+	From    OrderStatus `bson:"from,omitempty" json:"from,omitempty"`
+	To      OrderStatus `bson:"to" json:"to"`
+	Actor   string      `bson:"actor" json:"actor"`
+	ActorID string      `bson:"actor_id,omitempty" json:"actor_id,omitempty"` // user ID for customers and admins
+	Reason  string      `bson:"reason,omitempty" json:"reason,omitempty"`
+	At      time.Time   `bson:"at" json:"at"`
+}
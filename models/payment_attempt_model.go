@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// How the customer was asked to pay
+const (
+	PaymentAttemptIntent   = "intent"   // card form on the storefront
+	PaymentAttemptCheckout = "checkout" // the provider's hosted checkout page
+)
+
+// Payment attempt statuses
+const (
+	PaymentAttemptPending   = "pending"
+	PaymentAttemptSucceeded = "succeeded"
+	PaymentAttemptFailed    = "failed"
+	PaymentAttemptExpired   = "expired" // a hosted checkout that closed unpaid
+)
+
+// PaymentAttempt is one payment opened with the provider for an order. Every
+// attempt is kept so late events for any of them still find the order.
+type PaymentAttempt struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrderID         primitive.ObjectID `bson:"order_id" json:"order_id"`
+	Provider        string             `bson:"provider" json:"provider"`
+	Kind            string             `bson:"kind" json:"kind"`
+	Reference       string             `bson:"reference,omitempty" json:"reference,omitempty"` // the payment intent; set on checkouts once completed
+	CheckoutSession string             `bson:"checkout_session,omitempty" json:"checkout_session,omitempty"`
+	CheckoutURL     string             `bson:"checkout_url,omitempty" json:"checkout_url,omitempty"`
+	IdempotencyKey  string             `bson:"idempotency_key,omitempty" json:"-"` // the key the provider opened it with
+	Amount          Money              `bson:"amount" json:"amount"`
+	Status          string             `bson:"status" json:"status"`
+	ErrorCode       string             `bson:"error_code,omitempty" json:"error_code,omitempty"`
+	ErrorMessage    string             `bson:"error_message,omitempty" json:"error_message,omitempty"`
+	ExpiresAt       *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+	CompletedAt     *time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
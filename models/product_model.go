@@ -1,6 +1,9 @@
 package models
 
 import (
+	"errors"
+	"sort"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -10,12 +13,126 @@ type Product struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Name        string             `bson:"name" json:"name"`
 	Description string             `bson:"description" json:"description"`
-	Price       float64            `bson:"price" json:"price"`
+	Price       Money              `bson:"price" json:"price"`
 	Category    string             `bson:"category" json:"category"`
 	ImageURL    string             `bson:"image_url" json:"image_url"`
 	ImageID     string             `bson:"image_id" json:"image_id"` // <- make sure this is exact
 	Stock       int                `bson:"stock" json:"stock"`
+	WeightGrams int                `bson:"weight_grams" json:"weight_grams"`
 	OutOfStock  bool               `bson:"out_of_stock"` // new field for convenience
+	Rating      float64            `bson:"rating" json:"rating"`
+	ReviewCount int                `bson:"review_count" json:"review_count"`
+	Variants    []ProductVariant   `bson:"variants,omitempty" json:"variants,omitempty"`
 	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
 }
+
+// ProductVariant is one purchasable option of a product (a shade, size or finish).
+// When a product has variants, stock lives on the variants and Product.Stock
+// holds their sum.
+type ProductVariant struct {
+	ID         primitive.ObjectID `bson:"_id" json:"id"`
+	SKU        string             `bson:"sku" json:"sku"`
+	Attributes map[string]string  `bson:"attributes" json:"attributes"`
+	Price      *Money             `bson:"price,omitempty" json:"price,omitempty"` // overrides Product.Price when set
+	Stock      int                `bson:"stock" json:"stock"`
+	ImageURL   string             `bson:"image_url,omitempty" json:"image_url,omitempty"`
+}
+
+var (
+	ErrVariantRequired = errors.New("please choose a variant for this product")
+	ErrVariantNotFound = errors.New("variant not found")
+)
+
+// ResolveVariant returns the variant identified by variantID. Products without
+// variants resolve to nil, products with variants require a valid variantID.
+func (p *Product) ResolveVariant(variantID primitive.ObjectID) (*ProductVariant, error) {
+	if len(p.Variants) == 0 {
+		if !variantID.IsZero() {
+			return nil, ErrVariantNotFound
+		}
+		return nil, nil
+	}
+	if variantID.IsZero() {
+		return nil, ErrVariantRequired
+	}
+	for i := range p.Variants {
+		if p.Variants[i].ID == variantID {
+			return &p.Variants[i], nil
+		}
+	}
+	return nil, ErrVariantNotFound
+}
+
+// UnitPrice returns the price of the product or of the given variant
+func (p *Product) UnitPrice(v *ProductVariant) Money {
+	if v != nil && v.Price != nil {
+		return *v.Price
+	}
+	return p.Price
+}
+
+// Convert reprices the product and its variants for display in another
+// currency. rate is units of currency per unit of DefaultCurrency.
+func (p *Product) Convert(currency string, rate float64) {
+	p.Price = p.Price.Convert(currency, rate)
+	for i := range p.Variants {
+		if p.Variants[i].Price != nil {
+			price := p.Variants[i].Price.Convert(currency, rate)
+			p.Variants[i].Price = &price
+		}
+	}
+}
+
+// AvailableStock returns the stock of the product or of the given variant
+func (p *Product) AvailableStock(v *ProductVariant) int {
+	if v != nil {
+		return v.Stock
+	}
+	return p.Stock
+}
+
+// Label renders the variant attributes, e.g. "shade: Ruby, size: 30ml"
+func (v *ProductVariant) Label() string {
+	keys := make([]string, 0, len(v.Attributes))
+	for k := range v.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+": "+v.Attributes[k])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Catalog sort options accepted by GET /products
+const (
+	ProductSortNewest    = "newest"
+	ProductSortPriceAsc  = "price_asc"
+	ProductSortPriceDesc = "price_desc"
+	ProductSortRating    = "rating"
+)
+
+// ProductQuery holds the catalog search, filter, sort and pagination options
+type ProductQuery struct {
+	Search   string
+	Category string
+	MinPrice *Money
+	MaxPrice *Money
+	InStock  bool
+	Sort     string
+	Page     int
+	Limit    int
+	Cursor   string // opaque keyset cursor; takes precedence over Page
+}
+
+// ProductPage is one page of catalog results
+type ProductPage struct {
+	Products   []Product `json:"products"`
+	Total      int64     `json:"total"`
+	Page       int       `json:"page,omitempty"`
+	Limit      int       `json:"limit"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Refund is money returned to the customer for some or all of an order
+type Refund struct {
+	ID         primitive.ObjectID `bson:"_id" json:"id"`
+	ProviderID string             `bson:"provider_id,omitempty" json:"provider_id,omitempty"` // the payment provider's refund ID
+	Status     string             `bson:"status" json:"status"`                               // as reported by the provider, e.g. succeeded or pending
+	Amount     Money              `bson:"amount" json:"amount"`
+	Items      []RefundItem       `bson:"items,omitempty" json:"items,omitempty"`
+	Shipping   Money              `bson:"shipping" json:"shipping"` // part of Amount that covers shipping
+	Restocked  bool               `bson:"restocked" json:"restocked"`
+	Reason     string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	CreatedBy  string             `bson:"created_by,omitempty" json:"-"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// RefundItem is a quantity of one order line being refunded
+type RefundItem struct {
+	ProductID string `bson:"product_id" json:"product_id"`
+	VariantID string `bson:"variant_id,omitempty" json:"variant_id,omitempty"`
+	Quantity  int    `bson:"quantity" json:"quantity"`
+	Amount    Money  `bson:"amount" json:"amount"`
+}
+
+// Key identifies the order line the refund item belongs to
+func (i RefundItem) Key() string {
+	return lineKey(i.ProductID, i.VariantID)
+}
+
+// RefundRequest is what an admin sends to refund an order. With no items and
+// no shipping the whole remaining balance is refunded.
+type RefundRequest struct {
+	Items    []RefundItem `json:"items"`
+	Shipping bool         `json:"shipping"` // also refund what was paid for shipping
+	Restock  bool         `json:"restock"`  // put the refunded items back into stock
+	Reason   string       `json:"reason"`
+
+	// Set by callers that must not refund twice for the same thing
+	RefundID       primitive.ObjectID `json:"-"` // ID to record the refund under
+	IdempotencyKey string             `json:"-"` // sent to the payment provider
+}
+
+// IsFull reports whether the request refunds everything that is left
+func (r RefundRequest) IsFull() bool {
+	return len(r.Items) == 0 && !r.Shipping
+}
+
+// RefundedTotal is how much has been refunded so far
+func (o *Order) RefundedTotal() Money {
+	total := Money{Currency: o.TotalPrice.Currency}
+	for _, refund := range o.Refunds {
+		total = total.Add(refund.Amount)
+	}
+	return total
+}
+
+// LinePaid is what the customer paid for an order line: its price less its
+// share of the discount, plus any tax added on top
+func (o *Order) LinePaid(item OrderItem) Money {
+	paid := item.Price.Mul(item.Quantity).Sub(item.DiscountAmount)
+	for _, line := range o.TaxLines {
+		if !line.Inclusive && line.Name == item.TaxName && line.Rate == item.TaxRate {
+			paid = paid.Add(item.TaxAmount)
+			break
+		}
+	}
+	return paid
+}
+
+// ShippingPaid is what is left of the total once the lines are paid for:
+// shipping and its tax, less any shipping discount
+func (o *Order) ShippingPaid() Money {
+	paid := o.TotalPrice
+	for _, item := range o.Items {
+		paid = paid.Sub(o.LinePaid(item))
+	}
+	if paid.IsNegative() {
+		return Money{Currency: o.TotalPrice.Currency}
+	}
+	return paid
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReturnStatus is where a return (RMA) is in its lifecycle
+type ReturnStatus string
+
+const (
+	ReturnRequested ReturnStatus = "requested" // waiting for the shop to review it
+	ReturnApproved  ReturnStatus = "approved"  // the customer can send the goods back
+	ReturnRejected  ReturnStatus = "rejected"
+	ReturnReceived  ReturnStatus = "received"  // the goods are back at the warehouse
+	ReturnRefunding ReturnStatus = "refunding" // claimed by a refund that is being paid out
+	ReturnRefunded  ReturnStatus = "refunded"
+)
+
+// A refunding return that fails to pay out goes back to received; the return
+// service does that itself, so it is not a transition anyone can ask for.
+var returnTransitions = map[ReturnStatus][]ReturnStatus{
+	ReturnRequested: {ReturnApproved, ReturnRejected},
+	ReturnApproved:  {ReturnReceived, ReturnRejected},
+	ReturnReceived:  {ReturnRefunding},
+	ReturnRefunding: {ReturnRefunded},
+}
+
+func (s ReturnStatus) Valid() bool {
+	switch s {
+	case ReturnRequested, ReturnApproved, ReturnRejected, ReturnReceived, ReturnRefunding, ReturnRefunded:
+		return true
+	}
+	return false
+}
+
+func (s ReturnStatus) CanTransitionTo(to ReturnStatus) bool {
+	for _, next := range returnTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Open reports whether the return still holds on to its items
+func (s ReturnStatus) Open() bool {
+	return s != ReturnRejected
+}
+
+// CheckReturnTransition returns an error unless from → to is allowed
+func CheckReturnTransition(from, to ReturnStatus) error {
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("a %s return cannot be %s", from, to)
+	}
+	return nil
+}
+
+// Return is a customer's request to send back some of an order's items
+type Return struct {
+	ID            primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	OrderID       primitive.ObjectID   `bson:"order_id" json:"order_id"`
+	UserID        primitive.ObjectID   `bson:"user_id" json:"user_id"`
+	Items         []ReturnItem         `bson:"items" json:"items"`
+	Reason        string               `bson:"reason" json:"reason"`
+	Comment       string               `bson:"comment,omitempty" json:"comment,omitempty"`
+	Status        ReturnStatus         `bson:"status" json:"status"`
+	StatusHistory []ReturnStatusChange `bson:"status_history" json:"status_history"`
+	RefundID      *primitive.ObjectID  `bson:"refund_id,omitempty" json:"refund_id,omitempty"` // the order refund it was settled with
+	CreatedAt     time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// ReturnItem is a quantity of one order line being sent back
+type ReturnItem struct {
+	ProductID string `bson:"product_id" json:"product_id"`
+	VariantID string `bson:"variant_id,omitempty" json:"variant_id,omitempty"`
+	Quantity  int    `bson:"quantity" json:"quantity"`
+}
+
+// Key identifies the order line the return item belongs to
+func (i ReturnItem) Key() string {
+	return lineKey(i.ProductID, i.VariantID)
+}
+
+// ReturnStatusChange is one entry in a return's history
+type ReturnStatusChange struct {
+	From    ReturnStatus `bson:"from,omitempty" json:"from,omitempty"`
+	To      ReturnStatus `bson:"to" json:"to"`
+	Actor   string       `bson:"actor" json:"actor"`
+	ActorID string       `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	Note    string       `bson:"note,omitempty" json:"note,omitempty"` // shown to the customer
+	At      time.Time    `bson:"at" json:"at"`
+}
+
+// ReturnRequest is what a customer sends to start a return
+type ReturnRequest struct {
+	Items   []ReturnItem `json:"items" binding:"required"`
+	Reason  string       `json:"reason" binding:"required"`
+	Comment string       `json:"comment"`
+}
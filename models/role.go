@@ -0,0 +1,100 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// Role is what a user account may do. Customers have no admin permissions;
+// staff roles are granted the permissions in rolePermissions.
+type Role string
+
+const (
+	RoleCustomer       Role = "USER"
+	RoleSupport        Role = "SUPPORT"         // answers customers: reads orders, handles returns
+	RoleCatalogManager Role = "CATALOG_MANAGER" // products, coupons and reviews
+	RoleOrderManager   Role = "ORDER_MANAGER"   // fulfilment, refunds and payments
+	RoleSuperAdmin     Role = "SUPER_ADMIN"     // everything, including staff accounts and shop settings
+)
+
+// Permission allows one kind of admin action
+type Permission string
+
+const (
+	PermCatalogManage    Permission = "catalog:manage"    // products and variants
+	PermPromotionsManage Permission = "promotions:manage" // coupons
+	PermReviewsModerate  Permission = "reviews:moderate"  // edit or delete anyone's review
+	PermOrdersRead       Permission = "orders:read"
+	PermOrdersManage     Permission = "orders:manage" // status changes and shipments
+	PermRefundsIssue     Permission = "refunds:issue"
+	PermReturnsRead      Permission = "returns:read"
+	PermReturnsManage    Permission = "returns:manage" // approve, reject and receive returns
+	PermPaymentsRead     Permission = "payments:read"  // payment attempts, disputes and webhook events
+	PermPaymentsManage   Permission = "payments:manage"
+	PermUsersRead        Permission = "users:read"
+	PermUsersManage      Permission = "users:manage" // edit, delete and grant roles to accounts
+	PermSettingsManage   Permission = "settings:manage"
+	PermAnalyticsRead    Permission = "analytics:read"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleCustomer: {},
+	RoleSupport: {
+		PermOrdersRead, PermReturnsRead, PermReturnsManage, PermPaymentsRead,
+		PermUsersRead, PermReviewsModerate,
+	},
+	RoleCatalogManager: {
+		PermCatalogManage, PermPromotionsManage, PermReviewsModerate,
+	},
+	RoleOrderManager: {
+		PermOrdersRead, PermOrdersManage, PermRefundsIssue, PermReturnsRead,
+		PermReturnsManage, PermPaymentsRead, PermAnalyticsRead,
+	},
+	RoleSuperAdmin: {
+		PermCatalogManage, PermPromotionsManage, PermReviewsModerate,
+		PermOrdersRead, PermOrdersManage, PermRefundsIssue, PermReturnsRead,
+		PermReturnsManage, PermPaymentsRead, PermPaymentsManage, PermUsersRead,
+		PermUsersManage, PermSettingsManage, PermAnalyticsRead,
+	},
+}
+
+// ParseRole reads a role whatever its case. Unknown roles, including the
+// old ADMIN, parse as themselves and carry no permissions.
+func ParseRole(s string) Role {
+	return Role(strings.ToUpper(strings.TrimSpace(s)))
+}
+
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Staff reports whether the role may use the admin API at all
+func (r Role) Staff() bool {
+	return len(rolePermissions[r]) > 0
+}
+
+func (r Role) Can(permission Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Role) Permissions() []Permission {
+	return append([]Permission{}, rolePermissions[r]...)
+}
+
+// StaffRoles lists the roles that can be granted to admin accounts, by name
+func StaffRoles() []Role {
+	var roles []Role
+	for role := range rolePermissions {
+		if role.Staff() {
+			roles = append(roles, role)
+		}
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i] < roles[j] })
+	return roles
+}
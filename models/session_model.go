@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is one signed-in device. Its refresh token is stored hashed and
+// replaced on every refresh; the hashes it replaced are kept so a stolen
+// token that is used again can be spotted.
+type Session struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
+	TokenHash      string             `bson:"token_hash" json:"-"`
+	PreviousHashes []string           `bson:"previous_hashes,omitempty" json:"-"`
+	UserAgent      string             `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	IP             string             `bson:"ip,omitempty" json:"ip,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	LastUsedAt     time.Time          `bson:"last_used_at" json:"last_used_at"`
+	ExpiresAt      time.Time          `bson:"expires_at" json:"expires_at"` // refreshing pushes it back
+	RevokedAt      *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShipmentStatus is the carrier's view of a parcel
+type ShipmentStatus string
+
+const (
+	ShipmentLabelCreated   ShipmentStatus = "label_created"
+	ShipmentInTransit      ShipmentStatus = "in_transit"
+	ShipmentOutForDelivery ShipmentStatus = "out_for_delivery"
+	ShipmentDelivered      ShipmentStatus = "delivered"
+	ShipmentException      ShipmentStatus = "exception" // delayed, damaged or returned to sender
+)
+
+// Valid reports whether s is a known shipment status
+func (s ShipmentStatus) Valid() bool {
+	switch s {
+	case ShipmentLabelCreated, ShipmentInTransit, ShipmentOutForDelivery, ShipmentDelivered, ShipmentException:
+		return true
+	}
+	return false
+}
+
+// Shipment is one parcel sent for an order. An order can go out in several
+// shipments, each carrying some of its items.
+type Shipment struct {
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	Items          []ShipmentItem     `bson:"items" json:"items"`
+	Carrier        string             `bson:"carrier" json:"carrier"`
+	Service        string             `bson:"service,omitempty" json:"service,omitempty"`
+	TrackingNumber string             `bson:"tracking_number" json:"tracking_number"`
+	TrackingURL    string             `bson:"tracking_url,omitempty" json:"tracking_url,omitempty"`
+	LabelURL       string             `bson:"label_url,omitempty" json:"-"` // for the warehouse, not the customer
+	Status         ShipmentStatus     `bson:"status" json:"status"`
+	Events         []TrackingEvent    `bson:"events,omitempty" json:"events,omitempty"`
+	CreatedBy      string             `bson:"created_by,omitempty" json:"-"`
+	ShippedAt      time.Time          `bson:"shipped_at" json:"shipped_at"`
+	DeliveredAt    *time.Time         `bson:"delivered_at,omitempty" json:"delivered_at,omitempty"`
+	LastCheckedAt  *time.Time         `bson:"last_checked_at,omitempty" json:"-"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// ShipmentItem is a quantity of one order line inside a shipment
+type ShipmentItem struct {
+	ProductID string `bson:"product_id" json:"product_id"`
+	VariantID string `bson:"variant_id,omitempty" json:"variant_id,omitempty"`
+	Quantity  int    `bson:"quantity" json:"quantity"`
+}
+
+// TrackingEvent is one scan or status update reported by the carrier
+type TrackingEvent struct {
+	Status      ShipmentStatus `bson:"status" json:"status"`
+	Description string         `bson:"description" json:"description"`
+	Location    string         `bson:"location,omitempty" json:"location,omitempty"`
+	At          time.Time      `bson:"at" json:"at"`
+}
+
+// ShipmentRequest is what an admin sends to ship some or all of an order.
+// With no items the rest of the order is shipped; with no tracking number the
+// carrier is asked to create a label.
+type ShipmentRequest struct {
+	Carrier        string         `json:"carrier"`
+	Service        string         `json:"service"`
+	TrackingNumber string         `json:"tracking_number"`
+	TrackingURL    string         `json:"tracking_url"`
+	Items          []ShipmentItem `json:"items"`
+}
+
+// lineKey identifies an order line by product and variant
+func lineKey(productID, variantID string) string {
+	return productID + "/" + variantID
+}
+
+// Key identifies the order line the shipment item belongs to
+func (i ShipmentItem) Key() string {
+	return lineKey(i.ProductID, i.VariantID)
+}
+
+// Key identifies the order line
+func (i OrderItem) Key() string {
+	return lineKey(i.ProductID, i.VariantID)
+}
+
+// UnshippedItems returns, per order line, the quantity not yet in a shipment
+func (o *Order) UnshippedItems() map[string]int {
+	remaining := make(map[string]int, len(o.Items))
+	for _, item := range o.Items {
+		remaining[item.Key()] += item.Quantity
+	}
+	for _, shipment := range o.Shipments {
+		for _, item := range shipment.Items {
+			remaining[item.Key()] -= item.Quantity
+		}
+	}
+	return remaining
+}
+
+// FullyShipped reports whether every item on the order is in a shipment
+func (o *Order) FullyShipped() bool {
+	for _, qty := range o.UnshippedItems() {
+		if qty > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// FullyDelivered reports whether every item has shipped and every shipment arrived
+func (o *Order) FullyDelivered() bool {
+	if len(o.Shipments) == 0 || !o.FullyShipped() {
+		return false
+	}
+	for _, shipment := range o.Shipments {
+		if shipment.Status != ShipmentDelivered {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// What a shipping method's rate tiers are measured against
+const (
+	ShippingBasisFlat     = "flat"     // the first tier applies to every parcel
+	ShippingBasisWeight   = "weight"   // tiers are by total weight in grams
+	ShippingBasisSubtotal = "subtotal" // tiers are by items subtotal
+)
+
+// ShippingZone groups destinations that share shipping methods. A zone matches
+// an address by country and, when PostcodePrefixes is set, by postcode.
+type ShippingZone struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name             string             `bson:"name" json:"name"`
+	Countries        []string           `bson:"countries" json:"countries"` // matched case-insensitively, e.g. "GB", "United Kingdom"
+	PostcodePrefixes []string           `bson:"postcode_prefixes,omitempty" json:"postcode_prefixes,omitempty"`
+	Methods          []ShippingMethod   `bson:"methods" json:"methods"`
+	Active           bool               `bson:"active" json:"active"`
+	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type ShippingMethod struct {
+	Code          string         `bson:"code" json:"code"` // stored as Order.DeliveryType, e.g. "standard"
+	Name          string         `bson:"name" json:"name"`
+	Basis         string         `bson:"basis" json:"basis"`
+	Rates         []ShippingRate `bson:"rates" json:"rates"`
+	FreeOver      *Money         `bson:"free_over,omitempty" json:"free_over,omitempty"` // subtotal at which shipping is free, nil for never
+	EstimatedDays string         `bson:"estimated_days,omitempty" json:"estimated_days,omitempty"`
+}
+
+// ShippingRate is one tier of a method. UpTo is the inclusive upper bound in
+// grams or in minor currency units depending on the basis; 0 means no upper bound.
+type ShippingRate struct {
+	UpTo  int64 `bson:"up_to" json:"up_to"`
+	Price Money `bson:"price" json:"price"`
+}
+
+// ShippingParcel is what a quote is priced on
+type ShippingParcel struct {
+	Subtotal    Money
+	WeightGrams int
+}
+
+// ShippingQuote is one method available for an address and parcel
+type ShippingQuote struct {
+	Code          string `json:"code"`
+	Name          string `json:"name"`
+	Price         Money  `json:"price"`
+	EstimatedDays string `json:"estimated_days,omitempty"`
+	Zone          string `json:"zone"`
+}
+
+// NormalizePostcode upper-cases a postcode and strips spaces so prefixes compare reliably
+func NormalizePostcode(postcode string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(postcode), " ", ""))
+}
+
+// Match reports whether the zone covers the address. The returned length is the
+// matching postcode prefix length, so more specific zones can win.
+func (z *ShippingZone) Match(address Address) (bool, int) {
+	countryMatched := false
+	for _, country := range z.Countries {
+		if strings.EqualFold(strings.TrimSpace(country), strings.TrimSpace(address.Country)) {
+			countryMatched = true
+			break
+		}
+	}
+	if !countryMatched {
+		return false, 0
+	}
+	if len(z.PostcodePrefixes) == 0 {
+		return true, 0
+	}
+
+	postcode := NormalizePostcode(address.PostalCode)
+	best := -1
+	for _, prefix := range z.PostcodePrefixes {
+		prefix = NormalizePostcode(prefix)
+		if strings.HasPrefix(postcode, prefix) && len(prefix) > best {
+			best = len(prefix)
+		}
+	}
+	return best >= 0, best
+}
+
+// Price works out the method's cost for a parcel. ok is false when no tier covers it.
+func (m *ShippingMethod) Price(parcel ShippingParcel) (price Money, ok bool) {
+	free := Money{Currency: parcel.Subtotal.Currency}
+	if m.FreeOver != nil && m.FreeOver.IsPositive() && !parcel.Subtotal.LessThan(*m.FreeOver) {
+		return free, true
+	}
+
+	var measure int64
+	switch m.Basis {
+	case ShippingBasisWeight:
+		measure = int64(parcel.WeightGrams)
+	case ShippingBasisSubtotal:
+		measure = parcel.Subtotal.Amount
+	default:
+		if len(m.Rates) == 0 {
+			return free, false
+		}
+		return m.Rates[0].Price, true
+	}
+
+	// Rates are kept sorted by UpTo with the unbounded tier last
+	for _, rate := range m.Rates {
+		if rate.UpTo == 0 || measure <= rate.UpTo {
+			return rate.Price, true
+		}
+	}
+	return free, false
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaxRate is a tax that applies to products shipped to a country. A rate with
+// an empty Category is the country's default, also used for shipping.
+type TaxRate struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"` // e.g. "VAT 20%"
+	Country   string             `bson:"country" json:"country"`
+	Category  string             `bson:"category,omitempty" json:"category,omitempty"`
+	Rate      float64            `bson:"rate" json:"rate"`           // percentage, e.g. 20
+	Inclusive bool               `bson:"inclusive" json:"inclusive"` // catalog prices already include this tax (UK VAT)
+	Active    bool               `bson:"active" json:"active"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// TaxLine is the tax charged on an order at one rate
+type TaxLine struct {
+	Name      string  `bson:"name" json:"name"`
+	Rate      float64 `bson:"rate" json:"rate"`
+	Inclusive bool    `bson:"inclusive" json:"inclusive"`
+	Taxable   Money   `bson:"taxable" json:"taxable"` // amount the rate was applied to, after discounts
+	Amount    Money   `bson:"amount" json:"amount"`
+}
+
+// ExclusiveTax is the tax added on top of the order's prices. Inclusive tax is
+// already part of the subtotal and shipping.
+func (o *Order) ExclusiveTax() Money {
+	total := Money{Currency: o.Subtotal.Currency}
+	for _, line := range o.TaxLines {
+		if !line.Inclusive {
+			total = total.Add(line.Amount)
+		}
+	}
+	return total
+}
@@ -12,7 +12,10 @@ type User struct {
 	Email               string             `bson:"email" json:"email"`
 	PhoneNumber         string             `bson:"phone_number" json:"phone_number"`
 	Role                string             `bson:"role" json:"role"`
+	Currency            string             `bson:"currency,omitempty" json:"currency,omitempty"` // preferred display and checkout currency
+	PaymentCustomerID   string             `bson:"payment_customer_id,omitempty" json:"-"`       // the user's customer at the payment provider
 	Password            string             `bson:"password" json:"password"`
+	TokenVersion        int                `bson:"token_version" json:"-"` // bumped to invalidate every access token issued so far
 	CreatedAt           time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt           time.Time          `bson:"updated_at" json:"updated_at"`
 	ResetPasswordToken  string             `bson:"reset_password_token,omitempty"`
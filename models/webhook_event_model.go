@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Webhook event processing states
+const (
+	WebhookProcessing = "processing"
+	WebhookProcessed  = "processed"
+	WebhookFailed     = "failed"  // will be retried by the provider, or replayed by an admin
+	WebhookIgnored    = "ignored" // an event type we do not act on
+)
+
+// WebhookEvent is a payment provider event we have received, stored under the
+// provider's event ID so each event is acted on exactly once
+type WebhookEvent struct {
+	ID          string     `bson:"_id" json:"id"`
+	Provider    string     `bson:"provider" json:"provider"`
+	Type        string     `bson:"type" json:"type"`
+	Data        string     `bson:"data" json:"data"` // the event's object as JSON, kept for replays
+	Status      string     `bson:"status" json:"status"`
+	Attempts    int        `bson:"attempts" json:"attempts"`
+	LastError   string     `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	LockedUntil *time.Time `bson:"locked_until,omitempty" json:"-"`
+	ReceivedAt  time.Time  `bson:"received_at" json:"received_at"`
+	ProcessedAt *time.Time `bson:"processed_at,omitempty" json:"processed_at,omitempty"`
+	UpdatedAt   time.Time  `bson:"updated_at" json:"updated_at"`
+}
+
+// Claimable reports whether a delivery may take the processing claim: the
+// event failed, or the delivery holding the claim ran out of time without
+// finishing
+func (e *WebhookEvent) Claimable(now time.Time) bool {
+	switch e.Status {
+	case WebhookFailed:
+		return true
+	case WebhookProcessing:
+		return e.LockedUntil == nil || e.LockedUntil.Before(now)
+	}
+	return false
+}
+
+// Handled reports whether the event has been dealt with for good
+func (e *WebhookEvent) Handled() bool {
+	return e.Status == WebhookProcessed || e.Status == WebhookIgnored
+}
@@ -3,11 +3,13 @@ package repositories
 import (
 	"beauty-ecommerce-backend/models"
 	"context"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type CartRepository struct {
@@ -18,13 +20,161 @@ func NewCartRepository(db *mongo.Database) *CartRepository {
 	return &CartRepository{collection: db.Collection("cart")}
 }
 
+// Guest cart lines are removed this long after they were last touched
+const GuestCartTTL = 30 * 24 * time.Hour
+
+// EnsureIndexes enforces one cart line per owner, product and variant and
+// expires abandoned guest carts
+func (r *CartRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// The unique index cannot be built while duplicate lines exist
+	if err := r.mergeDuplicateLines(ctx); err != nil {
+		return err
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "cart_token", Value: 1},
+				{Key: "product_id", Value: 1},
+				{Key: "variant_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true).SetName("cart_owner_line_unique"),
+		},
+		{
+			Keys: bson.D{{Key: "updated_at", Value: 1}},
+			Options: options.Index().
+				SetName("guest_cart_ttl").
+				SetExpireAfterSeconds(int32(GuestCartTTL.Seconds())).
+				SetPartialFilterExpression(bson.M{"cart_token": bson.M{"$exists": true}}),
+		},
+	})
+	return err
+}
+
+// mergeDuplicateLines folds every set of lines for the same owner, product and
+// variant into the oldest one, adding their quantities together
+func (r *CartRepository) mergeDuplicateLines(ctx context.Context) error {
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"user_id":    bson.M{"$ifNull": bson.A{"$user_id", nil}},
+				"cart_token": bson.M{"$ifNull": bson.A{"$cart_token", nil}},
+				"product_id": "$product_id",
+				"variant_id": bson.M{"$ifNull": bson.A{"$variant_id", nil}},
+			},
+			"ids":      bson.M{"$push": "$_id"},
+			"quantity": bson.M{"$sum": "$quantity"},
+			"count":    bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	merged := 0
+	for cursor.Next(ctx) {
+		var group struct {
+			IDs      []primitive.ObjectID `bson:"ids"`
+			Quantity int                  `bson:"quantity"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return err
+		}
+
+		keep := group.IDs[0]
+		if _, err := r.collection.UpdateByID(ctx, keep, bson.M{
+			"$set": bson.M{"quantity": group.Quantity, "updated_at": time.Now()},
+		}); err != nil {
+			return err
+		}
+		if _, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": group.IDs[1:]}}); err != nil {
+			return err
+		}
+		merged++
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if merged > 0 {
+		fmt.Println("🧹 Merged", merged, "sets of duplicate cart lines")
+	}
+	return nil
+}
+
+func ownerFilter(owner models.CartOwner) bson.M {
+	if owner.IsGuest() {
+		return bson.M{"cart_token": owner.Token}
+	}
+	return bson.M{"user_id": owner.UserID}
+}
+
+// lineFilter matches the single cart line for an owner, product and variant
+func lineFilter(owner models.CartOwner, productID, variantID primitive.ObjectID) bson.M {
+	filter := ownerFilter(owner)
+	filter["product_id"] = productID
+	if variantID.IsZero() {
+		filter["variant_id"] = bson.M{"$exists": false}
+	} else {
+		filter["variant_id"] = variantID
+	}
+	return filter
+}
+
+// AddToLine adds quantity to the owner's line for a product/variant in one
+// $inc upsert, so concurrent adds are never lost, then caps the line at max
+func (r *CartRepository) AddToLine(ctx context.Context, owner models.CartOwner, productID, variantID primitive.ObjectID, quantity int, unitPrice models.Money, max int) (*models.CartItem, error) {
+	now := time.Now()
+	update := bson.M{
+		"$inc":         bson.M{"quantity": quantity},
+		"$set":         bson.M{"unit_price": unitPrice, "updated_at": now},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var saved models.CartItem
+	err := r.collection.FindOneAndUpdate(ctx, lineFilter(owner, productID, variantID), update, opts).Decode(&saved)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another request inserted the line first; add to it instead
+		err = r.collection.FindOneAndUpdate(ctx, lineFilter(owner, productID, variantID), update, opts).Decode(&saved)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if saved.Quantity <= max {
+		return &saved, nil
+	}
+
+	// Only ever lowers the quantity, so it cannot undo another request's add
+	_, err = r.collection.UpdateOne(ctx,
+		bson.M{"_id": saved.ID, "quantity": bson.M{"$gt": max}},
+		bson.M{"$set": bson.M{"quantity": max}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	saved.Quantity = max
+	return &saved, nil
+}
+
 func (r *CartRepository) AddToCart(ctx context.Context, item *models.CartItem) error {
 	_, err := r.collection.InsertOne(ctx, item)
 	return err
 }
 
 func (r *CartRepository) GetUserCart(ctx context.Context, userID primitive.ObjectID) ([]models.CartItem, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID})
+	return r.GetCart(ctx, models.CartOwner{UserID: userID})
+}
+
+// GetCart returns every line in a user's or guest's cart
+func (r *CartRepository) GetCart(ctx context.Context, owner models.CartOwner) ([]models.CartItem, error) {
+	cursor, err := r.collection.Find(ctx, ownerFilter(owner))
 	if err != nil {
 		return nil, err
 	}
@@ -64,3 +214,18 @@ func (r *CartRepository) FindByID(id primitive.ObjectID) (*models.CartItem, erro
 	}
 	return &cartItem, nil
 }
+
+// DeleteItems removes the given lines from a user's cart
+func (r *CartRepository) DeleteItems(ctx context.Context, userID primitive.ObjectID, cartItemIDs []primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{
+		"_id":     bson.M{"$in": cartItemIDs},
+		"user_id": userID,
+	})
+	return err
+}
+
+// DeleteByOwner removes every line in a user's or guest's cart
+func (r *CartRepository) DeleteByOwner(ctx context.Context, owner models.CartOwner) error {
+	_, err := r.collection.DeleteMany(ctx, ownerFilter(owner))
+	return err
+}
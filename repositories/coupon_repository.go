@@ -0,0 +1,270 @@
+package repositories
+
+import (
+	"beauty-ecommerce-backend/models"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrCouponUsageExceeded is returned when a coupon has no uses left
+	ErrCouponUsageExceeded = errors.New("coupon usage limit reached")
+	// ErrCouponUserLimitReached is returned when a user has used up their uses of a coupon
+	ErrCouponUserLimitReached = errors.New("you have already used this coupon")
+)
+
+type CouponRepository struct {
+	collection  *mongo.Collection
+	redemptions *mongo.Collection
+	usage       *mongo.Collection // one counter per coupon and user
+}
+
+func NewCouponRepository(db *mongo.Database) *CouponRepository {
+	return &CouponRepository{
+		collection:  db.Collection("coupons"),
+		redemptions: db.Collection("coupon_redemptions"),
+		usage:       db.Collection("coupon_user_usage"),
+	}
+}
+
+// EnsureIndexes creates the unique code index and the redemption lookups
+func (r *CouponRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "code", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.redemptions.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "order_id", Value: 1}, {Key: "coupon_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "coupon_id", Value: 1}, {Key: "user_id", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.usage.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "coupon_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Start the per-user counters from the redemptions recorded before they existed
+	cursor, err := r.redemptions.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":        bson.M{"coupon_id": "$coupon_id", "user_id": "$user_id"},
+			"used_count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":        0,
+			"coupon_id":  "$_id.coupon_id",
+			"user_id":    "$_id.user_id",
+			"used_count": 1,
+		}}},
+		{{Key: "$merge", Value: bson.M{
+			"into":           "coupon_user_usage",
+			"on":             bson.A{"coupon_id", "user_id"},
+			"whenMatched":    "keepExisting",
+			"whenNotMatched": "insert",
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+	return cursor.Close(ctx)
+}
+
+func (r *CouponRepository) Create(coupon *models.Coupon) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, coupon)
+	return err
+}
+
+func (r *CouponRepository) FindAll() ([]models.Coupon, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	coupons := []models.Coupon{}
+	if err := cursor.All(ctx, &coupons); err != nil {
+		return nil, err
+	}
+	return coupons, nil
+}
+
+func (r *CouponRepository) FindByID(id primitive.ObjectID) (*models.Coupon, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var coupon models.Coupon
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&coupon); err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (r *CouponRepository) FindByCode(code string) (*models.Coupon, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var coupon models.Coupon
+	if err := r.collection.FindOne(ctx, bson.M{"code": code}).Decode(&coupon); err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (r *CouponRepository) Update(id primitive.ObjectID, update bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *CouponRepository) Delete(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// UserUsage returns how many times a user has used a coupon
+func (r *CouponRepository) UserUsage(ctx context.Context, couponID, userID primitive.ObjectID) (int, error) {
+	var usage struct {
+		UsedCount int `bson:"used_count"`
+	}
+	err := r.usage.FindOne(ctx, bson.M{"coupon_id": couponID, "user_id": userID}).Decode(&usage)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return usage.UsedCount, nil
+}
+
+// Redeem takes one use of the coupon and one of the user's uses of it, then
+// records the redemption. Both limits are checked by the updates themselves,
+// so concurrent checkouts cannot go over either. It fails with
+// mongo.ErrNoDocuments if the coupon no longer exists.
+func (r *CouponRepository) Redeem(ctx context.Context, redemption *models.CouponRedemption) error {
+	var coupon models.Coupon
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{
+			"_id": redemption.CouponID,
+			"$or": bson.A{
+				bson.M{"usage_limit": bson.M{"$lte": 0}},
+				bson.M{"$expr": bson.M{"$lt": bson.A{"$used_count", "$usage_limit"}}},
+			},
+		},
+		bson.M{"$inc": bson.M{"used_count": 1}},
+	).Decode(&coupon)
+	if err == mongo.ErrNoDocuments {
+		count, countErr := r.collection.CountDocuments(ctx, bson.M{"_id": redemption.CouponID})
+		if countErr != nil {
+			return countErr
+		}
+		if count == 0 {
+			return mongo.ErrNoDocuments
+		}
+		return ErrCouponUsageExceeded
+	}
+	if err != nil {
+		return err
+	}
+
+	// The counter is kept even for unlimited coupons, in case a limit is added
+	// later. At the limit the filter misses and the upsert collides with the
+	// existing counter on the unique index.
+	filter := bson.M{"coupon_id": redemption.CouponID, "user_id": redemption.UserID}
+	if coupon.PerUserLimit > 0 {
+		filter["used_count"] = bson.M{"$lt": coupon.PerUserLimit}
+	}
+	_, err = r.usage.UpdateOne(ctx, filter,
+		bson.M{"$inc": bson.M{"used_count": 1}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrCouponUserLimitReached
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = r.redemptions.InsertOne(ctx, redemption)
+	return err
+}
+
+// ReleaseRedemptions gives back the coupon uses taken by an order
+func (r *CouponRepository) ReleaseRedemptions(ctx context.Context, orderID primitive.ObjectID) error {
+	cursor, err := r.redemptions.Find(ctx, bson.M{"order_id": orderID})
+	if err != nil {
+		return err
+	}
+	var redemptions []models.CouponRedemption
+	if err := cursor.All(ctx, &redemptions); err != nil {
+		return err
+	}
+
+	for _, redemption := range redemptions {
+		res, err := r.redemptions.DeleteOne(ctx, bson.M{"_id": redemption.ID})
+		if err != nil {
+			return err
+		}
+		if res.DeletedCount == 0 {
+			continue
+		}
+		_, err = r.collection.UpdateOne(ctx,
+			bson.M{"_id": redemption.CouponID, "used_count": bson.M{"$gt": 0}},
+			bson.M{"$inc": bson.M{"used_count": -1}},
+		)
+		if err != nil {
+			return err
+		}
+		_, err = r.usage.UpdateOne(ctx,
+			bson.M{"coupon_id": redemption.CouponID, "user_id": redemption.UserID, "used_count": bson.M{"$gt": 0}},
+			bson.M{"$inc": bson.M{"used_count": -1}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"beauty-ecommerce-backend/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CurrencyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCurrencyRepository(db *mongo.Database) *CurrencyRepository {
+	return &CurrencyRepository{collection: db.Collection("exchange_rates")}
+}
+
+// EnsureIndexes allows one rate per currency
+func (r *CurrencyRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "currency", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Upsert sets the rate for a currency, creating it if needed
+func (r *CurrencyRepository) Upsert(rate *models.ExchangeRate) (*models.ExchangeRate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	var saved models.ExchangeRate
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"currency": rate.Currency},
+		bson.M{
+			"$set": bson.M{
+				"rate":       rate.Rate,
+				"active":     rate.Active,
+				"updated_at": now,
+			},
+			"$setOnInsert": bson.M{"_id": primitive.NewObjectID(), "created_at": now},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&saved)
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+func (r *CurrencyRepository) FindAll() ([]models.ExchangeRate, error) {
+	return r.find(bson.M{})
+}
+
+func (r *CurrencyRepository) FindActive() ([]models.ExchangeRate, error) {
+	return r.find(bson.M{"active": true})
+}
+
+func (r *CurrencyRepository) find(filter bson.M) ([]models.ExchangeRate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "currency", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rates := []models.ExchangeRate{}
+	if err := cursor.All(ctx, &rates); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// FindActiveByCurrency returns the active rate for a currency
+func (r *CurrencyRepository) FindActiveByCurrency(currency string) (*models.ExchangeRate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var rate models.ExchangeRate
+	err := r.collection.FindOne(ctx, bson.M{"currency": currency, "active": true}).Decode(&rate)
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (r *CurrencyRepository) Delete(currency string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := r.collection.DeleteOne(ctx, bson.M{"currency": currency})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"beauty-ecommerce-backend/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type DisputeRepository struct {
+	collection *mongo.Collection
+}
+
+func NewDisputeRepository(db *mongo.Database) *DisputeRepository {
+	return &DisputeRepository{collection: db.Collection("disputes")}
+}
+
+func (r *DisputeRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "provider", Value: 1}, {Key: "provider_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "order_id", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "evidence_due_by", Value: 1}}},
+	})
+	return err
+}
+
+func (r *DisputeRepository) Create(ctx context.Context, dispute *models.Dispute) error {
+	_, err := r.collection.InsertOne(ctx, dispute)
+	return err
+}
+
+func (r *DisputeRepository) FindByID(id primitive.ObjectID) (*models.Dispute, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var dispute models.Dispute
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&dispute); err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+// FindByProviderID returns mongo.ErrNoDocuments for a dispute not seen before
+func (r *DisputeRepository) FindByProviderID(ctx context.Context, provider, providerID string) (*models.Dispute, error) {
+	var dispute models.Dispute
+	if err := r.collection.FindOne(ctx, bson.M{"provider": provider, "provider_id": providerID}).Decode(&dispute); err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+// Update sets fields on the dispute and, when change is given, appends it to
+// the dispute's history
+func (r *DisputeRepository) Update(ctx context.Context, id primitive.ObjectID, set bson.M, change *models.DisputeChange) error {
+	update := bson.M{"$set": set}
+	if change != nil {
+		update["$push"] = bson.M{"history": change}
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// FindAll lists disputes newest first, optionally only those in one status or
+// only those still open
+func (r *DisputeRepository) FindAll(status models.DisputeStatus, openOnly bool) ([]models.Dispute, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	switch {
+	case status != "":
+		filter["status"] = status
+	case openOnly:
+		filter["status"] = bson.M{"$nin": []models.DisputeStatus{
+			models.DisputeWarningClosed, models.DisputeChargeRefunded, models.DisputeWon, models.DisputeLost,
+		}}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	disputes := []models.Dispute{}
+	if err := cursor.All(ctx, &disputes); err != nil {
+		return nil, err
+	}
+	return disputes, nil
+}
@@ -10,6 +10,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type OrderRepository struct {
@@ -22,6 +23,23 @@ func NewOrderRepository(db *mongo.Database) *OrderRepository {
 	}
 }
 
+// --------------------------
+// INDEXES
+// --------------------------
+func (r *OrderRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "payment_reference", Value: 1}}},
+		{Keys: bson.D{{Key: "reservation.status", Value: 1}, {Key: "reservation.expires_at", Value: 1}}},
+		{Keys: bson.D{{Key: "shipments.status", Value: 1}, {Key: "shipments.carrier", Value: 1}}},
+		{Keys: bson.D{{Key: "shipments.tracking_number", Value: 1}}},
+	})
+	return err
+}
+
 // --------------------------
 // CREATE
 // --------------------------
@@ -44,6 +62,15 @@ func (r *OrderRepository) FindByID(orderID primitive.ObjectID) (*models.Order, e
 	return &order, nil
 }
 
+// Get loads an order with the caller's context, e.g. inside a unit of work
+func (r *OrderRepository) Get(ctx context.Context, orderID primitive.ObjectID) (*models.Order, error) {
+	var order models.Order
+	if err := r.collection.FindOne(ctx, bson.M{"_id": orderID}).Decode(&order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
 // --------------------------
 // FIND BY USER
 // --------------------------
@@ -101,7 +128,6 @@ func (r *OrderRepository) UpdateOrderReference(orderID, reference string) error
 	update := bson.M{
 		"$set": bson.M{
 			"payment_reference": reference,
-			"status":            "pending",
 			"updated_at":        time.Now(),
 		},
 	}
@@ -115,6 +141,15 @@ func (r *OrderRepository) UpdateOrderReference(orderID, reference string) error
 	return nil
 }
 
+// SetPaymentReference points the order at the payment that settled it
+func (r *OrderRepository) SetPaymentReference(ctx context.Context, orderID primitive.ObjectID, reference string) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": orderID},
+		bson.M{"$set": bson.M{"payment_reference": reference, "updated_at": time.Now()}},
+	)
+	return err
+}
+
 // --------------------------
 // ADMIN: FIND ALL ORDERS
 // --------------------------
@@ -151,57 +186,249 @@ func (r *OrderRepository) Update(orderID primitive.ObjectID, update bson.M) erro
 	return nil
 }
 
-func (r *OrderRepository) MarkFailed(paymentReference string) error {
-	filter := bson.M{"payment_reference": paymentReference}
-	update := bson.M{"$set": bson.M{
-		"status":     "failed",
-		"updated_at": time.Now(),
-	}}
+// --------------------------
+// CONDITIONAL STATUS UPDATE
+// --------------------------
 
-	_, err := r.collection.UpdateOne(context.Background(), filter, update)
-	return err
+// TransitionStatus moves an order to change.To and appends change to its
+// history, but only if the order is still in change.From. It reports false
+// when another writer got there first.
+func (r *OrderRepository) TransitionStatus(ctx context.Context, orderID primitive.ObjectID, change models.StatusChange) (bool, error) {
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": orderID, "status": change.From},
+		bson.M{
+			"$set":  bson.M{"status": change.To, "updated_at": change.At},
+			"$push": bson.M{"status_history": change},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
 }
 
-func (r *OrderRepository) MarkPaid(paymentReference string) error {
-	filter := bson.M{"payment_reference": paymentReference}
+// --------------------------
+// STOCK RESERVATIONS
+// --------------------------
 
-	update := bson.M{"$set": bson.M{
-		"status":     "paid",
-		"updated_at": time.Now(),
-	}}
+// SetReservationStatus flips the order's stock reservation from one state to
+// another. Only one caller can win a given transition, which is what guarantees
+// held stock is released or committed exactly once.
+func (r *OrderRepository) SetReservationStatus(ctx context.Context, orderID primitive.ObjectID, from, to string) (bool, error) {
+	set := bson.M{"reservation.status": to, "updated_at": time.Now()}
+	if to == models.ReservationReleased {
+		set["reservation.released_at"] = time.Now()
+	}
 
-	res, err := r.collection.UpdateOne(context.Background(), filter, update)
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": orderID, "reservation.status": from},
+		bson.M{"$set": set},
+	)
 	if err != nil {
-		return err
+		return false, err
 	}
+	return res.MatchedCount > 0, nil
+}
+
+// FindExpiredReservations returns pending orders whose stock hold has run out
+func (r *OrderRepository) FindExpiredReservations(now time.Time, limit int64) ([]models.Order, error) {
+	ctx := context.Background()
+	filter := bson.M{
+		"status":                 models.OrderPending,
+		"reservation.status":     models.ReservationHeld,
+		"reservation.expires_at": bson.M{"$lt": now},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []models.Order
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// --------------------------
+// SHIPMENTS
+// --------------------------
 
+// AddShipment appends a shipment to the order
+func (r *OrderRepository) AddShipment(ctx context.Context, orderID primitive.ObjectID, shipment models.Shipment) error {
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": orderID},
+		bson.M{
+			"$push": bson.M{"shipments": shipment},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return err
+	}
 	if res.MatchedCount == 0 {
-		return fmt.Errorf("no order found to mark as paid")
+		return fmt.Errorf("no order found to add shipment")
 	}
+	return nil
+}
 
+// UpdateShipment replaces one of the order's shipments, matched by ID
+func (r *OrderRepository) UpdateShipment(ctx context.Context, orderID primitive.ObjectID, shipment models.Shipment) error {
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": orderID, "shipments._id": shipment.ID},
+		bson.M{"$set": bson.M{"shipments.$": shipment, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("no shipment found to update")
+	}
 	return nil
 }
 
-func (r *OrderRepository) MarkRefunded(paymentReference string) error {
-	filter := bson.M{"payment_reference": paymentReference}
-	update := bson.M{
-		"$set": bson.M{
-			"status":     "refunded",
-			"updated_at": time.Now(),
-		},
+// FindUndeliveredShipments returns orders with a shipment from one of the
+// given carriers that has not been delivered yet
+func (r *OrderRepository) FindUndeliveredShipments(carriers []string, limit int64) ([]models.Order, error) {
+	ctx := context.Background()
+	filter := bson.M{
+		"shipments": bson.M{"$elemMatch": bson.M{
+			"carrier": bson.M{"$in": carriers},
+			"status":  bson.M{"$ne": models.ShipmentDelivered},
+		}},
 	}
-	_, err := r.collection.UpdateOne(context.Background(), filter, update)
-	return err
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []models.Order
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
 }
 
-func (r *OrderRepository) MarkDisputed(paymentReference string) error {
-	filter := bson.M{"payment_reference": paymentReference}
-	update := bson.M{
-		"$set": bson.M{
-			"status":     "disputed",
-			"updated_at": time.Now(),
+// --------------------------
+// REFUNDS
+// --------------------------
+
+// AddRefund appends a refund entry to the order
+func (r *OrderRepository) AddRefund(ctx context.Context, orderID primitive.ObjectID, refund models.Refund) error {
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": orderID},
+		bson.M{
+			"$push": bson.M{"refunds": refund},
+			"$set":  bson.M{"updated_at": time.Now()},
 		},
+	)
+	if err != nil {
+		return err
 	}
-	_, err := r.collection.UpdateOne(context.Background(), filter, update)
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("no order found to add refund")
+	}
+	return nil
+}
+
+// UpdateRefund replaces one of the order's refunds, matched by ID
+func (r *OrderRepository) UpdateRefund(ctx context.Context, orderID primitive.ObjectID, refund models.Refund) error {
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": orderID, "refunds._id": refund.ID},
+		bson.M{"$set": bson.M{"refunds.$": refund, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("no refund found to update")
+	}
+	return nil
+}
+
+// RemoveRefund drops a refund entry that the payment provider turned down
+func (r *OrderRepository) RemoveRefund(ctx context.Context, orderID, refundID primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": orderID},
+		bson.M{"$pull": bson.M{"refunds": bson.M{"_id": refundID}}},
+	)
 	return err
 }
+
+// --------------------------
+// ANALYTICS
+// --------------------------
+
+// SalesSummary aggregates revenue, discounts and tax over orders in the given
+// statuses: overall totals, tax per rate and a monthly breakdown. Amounts are
+// in minor units and grouped by currency, since they cannot be summed across.
+func (r *OrderRepository) SalesSummary(statuses []models.OrderStatus) (bson.M, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": bson.M{"$in": statuses}}}},
+		{{Key: "$facet", Value: bson.M{
+			"totals": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":          "$total_price.currency",
+					"orders":       bson.M{"$sum": 1},
+					"revenue":      bson.M{"$sum": "$total_price.amount"},
+					"subtotal":     bson.M{"$sum": "$subtotal.amount"},
+					"shipping":     bson.M{"$sum": "$shipping_fee.amount"},
+					"discounts":    bson.M{"$sum": "$discount_total.amount"},
+					"tax":          bson.M{"$sum": "$tax_total.amount"},
+					"shipping_tax": bson.M{"$sum": "$shipping_tax.amount"},
+					"refunded":     bson.M{"$sum": bson.M{"$sum": "$refunds.amount.amount"}},
+				}},
+				bson.M{"$set": bson.M{"currency": "$_id"}},
+				bson.M{"$project": bson.M{"_id": 0}},
+				bson.M{"$sort": bson.M{"currency": 1}},
+			},
+			"tax_by_rate": bson.A{
+				bson.M{"$unwind": "$tax_lines"},
+				bson.M{"$group": bson.M{
+					"_id": bson.M{
+						"name":      "$tax_lines.name",
+						"rate":      "$tax_lines.rate",
+						"inclusive": "$tax_lines.inclusive",
+						"currency":  "$tax_lines.amount.currency",
+					},
+					"taxable": bson.M{"$sum": "$tax_lines.taxable.amount"},
+					"amount":  bson.M{"$sum": "$tax_lines.amount.amount"},
+					"orders":  bson.M{"$sum": 1},
+				}},
+				bson.M{"$sort": bson.M{"_id.rate": -1}},
+			},
+			"by_month": bson.A{
+				bson.M{"$group": bson.M{
+					"_id": bson.M{
+						"month":    bson.M{"$dateToString": bson.M{"format": "%Y-%m", "date": "$created_at"}},
+						"currency": "$total_price.currency",
+					},
+					"orders":  bson.M{"$sum": 1},
+					"revenue": bson.M{"$sum": "$total_price.amount"},
+					"tax":     bson.M{"$sum": "$tax_total.amount"},
+				}},
+				bson.M{"$sort": bson.M{"_id.month": 1}},
+			},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []bson.M
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return bson.M{}, nil
+	}
+	return results[0], nil
+}
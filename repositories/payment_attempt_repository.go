@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"beauty-ecommerce-backend/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PaymentAttemptRepository struct {
+	collection *mongo.Collection
+}
+
+func NewPaymentAttemptRepository(db *mongo.Database) *PaymentAttemptRepository {
+	return &PaymentAttemptRepository{collection: db.Collection("payment_attempts")}
+}
+
+func (r *PaymentAttemptRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "order_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "reference", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "checkout_session", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "idempotency_key", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+	})
+	return err
+}
+
+// Create stores a new attempt. An attempt with the same idempotency key is
+// already recorded when it returns a duplicate key error.
+func (r *PaymentAttemptRepository) Create(ctx context.Context, attempt *models.PaymentAttempt) error {
+	_, err := r.collection.InsertOne(ctx, attempt)
+	return err
+}
+
+// CountByOrder counts the order's attempts of the given kind, finished or not
+func (r *PaymentAttemptRepository) CountByOrder(ctx context.Context, orderID primitive.ObjectID, kind string) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"order_id": orderID, "kind": kind})
+}
+
+// FindByReference finds the attempt that opened the given payment
+func (r *PaymentAttemptRepository) FindByReference(ctx context.Context, reference string) (*models.PaymentAttempt, error) {
+	return r.findOne(ctx, bson.M{"reference": reference}, nil)
+}
+
+func (r *PaymentAttemptRepository) FindByCheckoutSession(ctx context.Context, sessionID string) (*models.PaymentAttempt, error) {
+	return r.findOne(ctx, bson.M{"checkout_session": sessionID}, nil)
+}
+
+// FindLatestPending returns the order's newest attempt of the given kind that
+// has not finished yet
+func (r *PaymentAttemptRepository) FindLatestPending(ctx context.Context, orderID primitive.ObjectID, kind string) (*models.PaymentAttempt, error) {
+	return r.findOne(ctx,
+		bson.M{"order_id": orderID, "kind": kind, "status": models.PaymentAttemptPending},
+		options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+}
+
+func (r *PaymentAttemptRepository) findOne(ctx context.Context, filter bson.M, opts *options.FindOneOptions) (*models.PaymentAttempt, error) {
+	var attempt models.PaymentAttempt
+	if opts == nil {
+		opts = options.FindOne()
+	}
+	if err := r.collection.FindOne(ctx, filter, opts).Decode(&attempt); err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// FindByOrder lists the order's attempts, oldest first
+func (r *PaymentAttemptRepository) FindByOrder(orderID primitive.ObjectID) ([]models.PaymentAttempt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, bson.M{"order_id": orderID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	attempts := []models.PaymentAttempt{}
+	if err := cursor.All(ctx, &attempts); err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
+
+// Update sets fields on the attempt
+func (r *PaymentAttemptRepository) Update(ctx context.Context, id primitive.ObjectID, set bson.M) error {
+	set["updated_at"] = time.Now()
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	return err
+}
+
+// Finish records the attempt's outcome. An attempt that already succeeded is
+// left alone, so a late failure cannot overwrite it.
+func (r *PaymentAttemptRepository) Finish(ctx context.Context, id primitive.ObjectID, status, errorCode, errorMessage string) error {
+	now := time.Now()
+	set := bson.M{"status": status, "updated_at": now, "completed_at": now}
+	if errorCode != "" {
+		set["error_code"] = errorCode
+	}
+	if errorMessage != "" {
+		set["error_message"] = errorMessage
+	}
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": bson.M{"$ne": models.PaymentAttemptSucceeded}},
+		bson.M{"$set": set},
+	)
+	return err
+}
@@ -3,12 +3,15 @@ package repositories
 import (
 	"beauty-ecommerce-backend/models"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type ProductRepository struct {
@@ -54,6 +57,200 @@ func (r *ProductRepository) FindAll() ([]models.Product, error) {
 	return products, nil
 }
 
+// INDEXES
+// EnsureIndexes creates the indexes backing catalog search, filtering and sorting
+func (r *ProductRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.Collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "name", Value: "text"}, {Key: "description", Value: "text"}},
+			Options: options.Index().
+				SetName("product_text_search").
+				SetWeights(bson.D{{Key: "name", Value: 10}, {Key: "description", Value: 2}}),
+		},
+		{Keys: bson.D{{Key: "category", Value: 1}, {Key: "price.amount", Value: 1}}},
+		{Keys: bson.D{{Key: "price.amount", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "rating", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "stock", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "variants.sku", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"variants.sku": bson.M{"$type": "string"}}),
+		},
+	})
+	return err
+}
+
+// SEARCH
+// productCursor is the decoded form of the opaque keyset cursor returned to clients
+type productCursor struct {
+	Value interface{} `json:"v"`
+	ID    string      `json:"id"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Search runs a filtered, sorted and paginated catalog query. It returns the
+// page of products, the total number of matches and a cursor for the next page.
+func (r *ProductRepository) Search(query models.ProductQuery) ([]models.Product, int64, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if query.Search != "" {
+		filter["$text"] = bson.M{"$search": query.Search}
+	}
+	if query.Category != "" {
+		filter["category"] = query.Category
+	}
+	if query.MinPrice != nil || query.MaxPrice != nil {
+		priceRange := bson.M{}
+		if query.MinPrice != nil {
+			priceRange["$gte"] = query.MinPrice.Amount
+		}
+		if query.MaxPrice != nil {
+			priceRange["$lte"] = query.MaxPrice.Amount
+		}
+		filter["price.amount"] = priceRange
+	}
+	if query.InStock {
+		filter["stock"] = bson.M{"$gt": 0}
+	}
+
+	total, err := r.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	// Relevance ordering only applies to text searches without an explicit sort
+	// and cannot be expressed as a keyset cursor, so it is page-based only.
+	sortField, direction := productSortKey(query.Sort)
+	findOpts := options.Find().SetLimit(int64(query.Limit + 1))
+	byRelevance := query.Search != "" && query.Sort == ""
+	if byRelevance {
+		score := bson.M{"$meta": "textScore"}
+		findOpts.SetProjection(bson.M{"score": score})
+		findOpts.SetSort(bson.D{{Key: "score", Value: score}, {Key: "_id", Value: 1}})
+	} else {
+		findOpts.SetSort(bson.D{{Key: sortField, Value: direction}, {Key: "_id", Value: direction}})
+	}
+
+	findFilter := filter
+	if query.Cursor != "" && !byRelevance {
+		cond, err := cursorCondition(query.Cursor, sortField, direction)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		findFilter = bson.M{"$and": []bson.M{filter, cond}}
+	} else if query.Page > 1 {
+		findOpts.SetSkip(int64((query.Page - 1) * query.Limit))
+	}
+
+	cursor, err := r.Collection.Find(ctx, findFilter, findOpts)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	defer cursor.Close(ctx)
+
+	products := []models.Product{}
+	if err := cursor.All(ctx, &products); err != nil {
+		return nil, 0, "", err
+	}
+
+	nextCursor := ""
+	if len(products) > query.Limit {
+		products = products[:query.Limit]
+		if !byRelevance {
+			nextCursor = encodeProductCursor(products[len(products)-1], sortField)
+		}
+	}
+
+	return products, total, nextCursor, nil
+}
+
+func productSortKey(sort string) (string, int) {
+	switch sort {
+	case models.ProductSortPriceAsc:
+		return "price.amount", 1
+	case models.ProductSortPriceDesc:
+		return "price.amount", -1
+	case models.ProductSortRating:
+		return "rating", -1
+	default:
+		return "created_at", -1
+	}
+}
+
+func encodeProductCursor(last models.Product, sortField string) string {
+	c := productCursor{ID: last.ID.Hex()}
+	switch sortField {
+	case "price.amount":
+		c.Value = last.Price.Amount
+	case "rating":
+		c.Value = last.Rating
+	default:
+		c.Value = last.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// cursorCondition turns a cursor into a filter selecting documents strictly after it
+func cursorCondition(encoded, sortField string, direction int) (bson.M, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c productCursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := primitive.ObjectIDFromHex(c.ID)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var value interface{}
+	switch sortField {
+	case "created_at":
+		str, ok := c.Value.(string)
+		if !ok {
+			return nil, ErrInvalidCursor
+		}
+		t, err := time.Parse(time.RFC3339Nano, str)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		value = t
+	case "price.amount":
+		// JSON numbers decode as float64; prices are whole minor units
+		num, ok := c.Value.(float64)
+		if !ok {
+			return nil, ErrInvalidCursor
+		}
+		value = int64(num)
+	default:
+		num, ok := c.Value.(float64)
+		if !ok {
+			return nil, ErrInvalidCursor
+		}
+		value = num
+	}
+
+	op := "$gt"
+	if direction < 0 {
+		op = "$lt"
+	}
+	return bson.M{"$or": []bson.M{
+		{sortField: bson.M{op: value}},
+		{sortField: value, "_id": bson.M{op: id}},
+	}}, nil
+}
+
 // FIND BY ID
 func (r *ProductRepository) FindByID(id primitive.ObjectID) (*models.Product, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -92,6 +289,124 @@ func (r *ProductRepository) Delete(id primitive.ObjectID) error {
 	return nil
 }
 
+// --------------------------
+// STOCK
+// --------------------------
+
+// DecrementStock atomically takes quantity from a product, or from one of its
+// variants, only when enough is available. It reports false when stock is short.
+func (r *ProductRepository) DecrementStock(ctx context.Context, productID, variantID primitive.ObjectID, quantity int) (bool, error) {
+	filter := bson.M{"_id": productID, "stock": bson.M{"$gte": quantity}}
+	inc := bson.M{"stock": -quantity}
+	if !variantID.IsZero() {
+		filter = bson.M{
+			"_id":      productID,
+			"variants": bson.M{"$elemMatch": bson.M{"_id": variantID, "stock": bson.M{"$gte": quantity}}},
+		}
+		inc["variants.$.stock"] = -quantity
+	}
+
+	res, err := r.Collection.UpdateOne(ctx, filter, bson.M{
+		"$inc": inc,
+		"$set": bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// IncrementStock puts quantity back on a product, or on one of its variants
+func (r *ProductRepository) IncrementStock(ctx context.Context, productID, variantID primitive.ObjectID, quantity int) error {
+	filter := bson.M{"_id": productID}
+	inc := bson.M{"stock": quantity}
+	if !variantID.IsZero() {
+		filter["variants._id"] = variantID
+		inc["variants.$.stock"] = quantity
+	}
+
+	res, err := r.Collection.UpdateOne(ctx, filter, bson.M{
+		"$inc": inc,
+		"$set": bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("product or variant not found")
+	}
+	return nil
+}
+
+// --------------------------
+// VARIANTS
+// --------------------------
+
+// AddVariant appends a variant and adds its stock to the product total
+func (r *ProductRepository) AddVariant(productID primitive.ObjectID, variant models.ProductVariant) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := r.Collection.UpdateOne(ctx, bson.M{"_id": productID}, bson.M{
+		"$push": bson.M{"variants": variant},
+		"$inc":  bson.M{"stock": variant.Stock},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("product not found")
+	}
+	return nil
+}
+
+// ReplaceVariant overwrites a variant. The update only applies if the variant
+// still holds previousStock, so concurrent sales are never overwritten.
+func (r *ProductRepository) ReplaceVariant(productID primitive.ObjectID, variant models.ProductVariant, previousStock int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":      productID,
+		"variants": bson.M{"$elemMatch": bson.M{"_id": variant.ID, "stock": previousStock}},
+	}
+	res, err := r.Collection.UpdateOne(ctx, filter, bson.M{
+		"$set": bson.M{"variants.$": variant, "updated_at": time.Now()},
+		"$inc": bson.M{"stock": variant.Stock - previousStock},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("variant was modified concurrently, please retry")
+	}
+	return nil
+}
+
+// RemoveVariant pulls a variant and removes its stock from the product total
+func (r *ProductRepository) RemoveVariant(productID primitive.ObjectID, variant models.ProductVariant) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":      productID,
+		"variants": bson.M{"$elemMatch": bson.M{"_id": variant.ID, "stock": variant.Stock}},
+	}
+	res, err := r.Collection.UpdateOne(ctx, filter, bson.M{
+		"$pull": bson.M{"variants": bson.M{"_id": variant.ID}},
+		"$inc":  bson.M{"stock": -variant.Stock},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("variant was modified concurrently, please retry")
+	}
+	return nil
+}
+
 func (r *ProductRepository) UpdateWithFilter(filter bson.M, update bson.M) (*mongo.UpdateResult, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
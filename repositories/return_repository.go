@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"beauty-ecommerce-backend/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReturnRepository struct {
+	collection *mongo.Collection
+}
+
+func NewReturnRepository(db *mongo.Database) *ReturnRepository {
+	return &ReturnRepository{collection: db.Collection("returns")}
+}
+
+func (r *ReturnRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "order_id", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	return err
+}
+
+func (r *ReturnRepository) Create(ctx context.Context, ret *models.Return) error {
+	_, err := r.collection.InsertOne(ctx, ret)
+	return err
+}
+
+// Get loads a return with the caller's context, e.g. inside a unit of work
+func (r *ReturnRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Return, error) {
+	var ret models.Return
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+// FindByOrder returns the order's returns, oldest first
+func (r *ReturnRepository) FindByOrder(ctx context.Context, orderID primitive.ObjectID) ([]models.Return, error) {
+	return r.find(ctx, bson.M{"order_id": orderID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+}
+
+// FindAll lists returns newest first, optionally only those in one status
+func (r *ReturnRepository) FindAll(status models.ReturnStatus) ([]models.Return, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	return r.find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+}
+
+func (r *ReturnRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.Return, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	returns := []models.Return{}
+	if err := cursor.All(ctx, &returns); err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
+
+// TransitionStatus moves a return to change.To, appends change to its history
+// and applies any extra fields in set, but only if the return is still in
+// change.From. It reports false when another writer got there first.
+func (r *ReturnRepository) TransitionStatus(ctx context.Context, id primitive.ObjectID, change models.ReturnStatusChange, set bson.M) (bool, error) {
+	fields := bson.M{"status": change.To, "updated_at": change.At}
+	for k, v := range set {
+		fields[k] = v
+	}
+
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": change.From},
+		bson.M{
+			"$set":  fields,
+			"$push": bson.M{"status_history": change},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
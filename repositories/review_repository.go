@@ -61,3 +61,34 @@ func (r *ReviewRepository) GetByProduct(productID primitive.ObjectID) ([]models.
 	}
 	return reviews, nil
 }
+
+// RatingSummary returns the average rating and review count for a product
+func (r *ReviewRepository) RatingSummary(productID primitive.ObjectID) (float64, int, error) {
+	ctx := context.Background()
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"product_id": productID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   nil,
+			"avg":   bson.M{"$avg": "$rating"},
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	cursor, err := r.Collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Avg   float64 `bson:"avg"`
+		Count int     `bson:"count"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return 0, 0, err
+	}
+	if len(result) == 0 {
+		return 0, 0, nil
+	}
+	return result[0].Avg, result[0].Count, nil
+}
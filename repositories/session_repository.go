@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"beauty-ecommerce-backend/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxPreviousHashes bounds how many replaced refresh tokens a session keeps
+const maxPreviousHashes = 50
+
+type SessionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSessionRepository(db *mongo.Database) *SessionRepository {
+	return &SessionRepository{collection: db.Collection("sessions")}
+}
+
+// EnsureIndexes also lets Mongo delete sessions once they expire
+func (r *SessionRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "previous_hashes", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+func (r *SessionRepository) Create(ctx context.Context, session *models.Session) error {
+	_, err := r.collection.InsertOne(ctx, session)
+	return err
+}
+
+// Rotate swaps a live session's refresh token for a new one and returns the
+// session. It matches nothing when the token is unknown, already replaced,
+// revoked or expired.
+func (r *SessionRepository) Rotate(ctx context.Context, tokenHash, newHash string, expiresAt time.Time) (*models.Session, error) {
+	now := time.Now()
+	var session models.Session
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{
+			"token_hash": tokenHash,
+			"revoked_at": bson.M{"$exists": false},
+			"expires_at": bson.M{"$gt": now},
+		},
+		bson.M{
+			"$set": bson.M{"token_hash": newHash, "last_used_at": now, "expires_at": expiresAt},
+			"$push": bson.M{"previous_hashes": bson.M{
+				"$each":  bson.A{tokenHash},
+				"$slice": -maxPreviousHashes,
+			}},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindByPreviousHash finds the session a replaced refresh token belonged to
+func (r *SessionRepository) FindByPreviousHash(ctx context.Context, tokenHash string) (*models.Session, error) {
+	var session models.Session
+	if err := r.collection.FindOne(ctx, bson.M{"previous_hashes": tokenHash}).Decode(&session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SessionRepository) Revoke(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	return err
+}
+
+// RevokeByTokenHash ends the session holding the refresh token, reporting
+// whether there was one
+func (r *SessionRepository) RevokeByTokenHash(ctx context.Context, tokenHash string) (bool, error) {
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"token_hash": tokenHash, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+func (r *SessionRepository) RevokeAllForUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	return err
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"beauty-ecommerce-backend/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ShippingRepository struct {
+	collection *mongo.Collection
+}
+
+func NewShippingRepository(db *mongo.Database) *ShippingRepository {
+	return &ShippingRepository{collection: db.Collection("shipping_zones")}
+}
+
+func (r *ShippingRepository) Create(zone *models.ShippingZone) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, zone)
+	return err
+}
+
+func (r *ShippingRepository) FindAll() ([]models.ShippingZone, error) {
+	return r.find(bson.M{})
+}
+
+func (r *ShippingRepository) FindActive() ([]models.ShippingZone, error) {
+	return r.find(bson.M{"active": true})
+}
+
+func (r *ShippingRepository) find(filter bson.M) ([]models.ShippingZone, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	zones := []models.ShippingZone{}
+	if err := cursor.All(ctx, &zones); err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+func (r *ShippingRepository) FindByID(id primitive.ObjectID) (*models.ShippingZone, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var zone models.ShippingZone
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&zone); err != nil {
+		return nil, err
+	}
+	return &zone, nil
+}
+
+// Replace overwrites a zone's settings, keeping its creation time
+func (r *ShippingRepository) Replace(zone *models.ShippingZone) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": zone.ID}, bson.M{"$set": bson.M{
+		"name":              zone.Name,
+		"countries":         zone.Countries,
+		"postcode_prefixes": zone.PostcodePrefixes,
+		"methods":           zone.Methods,
+		"active":            zone.Active,
+		"updated_at":        zone.UpdatedAt,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *ShippingRepository) Delete(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
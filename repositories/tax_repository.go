@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"beauty-ecommerce-backend/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TaxRepository struct {
+	collection *mongo.Collection
+}
+
+func NewTaxRepository(db *mongo.Database) *TaxRepository {
+	return &TaxRepository{collection: db.Collection("tax_rates")}
+}
+
+// EnsureIndexes allows one rate per country and category
+func (r *TaxRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "country", Value: 1}, {Key: "category", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (r *TaxRepository) Create(rate *models.TaxRate) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, rate)
+	return err
+}
+
+func (r *TaxRepository) FindAll() ([]models.TaxRate, error) {
+	return r.find(bson.M{})
+}
+
+// FindActiveByCountry returns the active rates for a normalized country code
+func (r *TaxRepository) FindActiveByCountry(country string) ([]models.TaxRate, error) {
+	return r.find(bson.M{"country": country, "active": true})
+}
+
+func (r *TaxRepository) find(filter bson.M) ([]models.TaxRate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "country", Value: 1}, {Key: "category", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rates := []models.TaxRate{}
+	if err := cursor.All(ctx, &rates); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (r *TaxRepository) FindByID(id primitive.ObjectID) (*models.TaxRate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var rate models.TaxRate
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&rate); err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (r *TaxRepository) Update(id primitive.ObjectID, update bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *TaxRepository) Delete(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// ErrInsufficientStock is returned when a stock decrement finds too little stock
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// UnitOfWork runs order and product writes inside a single Mongo multi-document
+// transaction. Repository calls made with the context handed to the callback join
+// the transaction; returning an error from the callback rolls everything back.
+//
+// Transactions need MongoDB running as a replica set (or sharded cluster).
+type UnitOfWork struct {
+	client *mongo.Client
+}
+
+func NewUnitOfWork(db *mongo.Database) *UnitOfWork {
+	return &UnitOfWork{client: db.Client()}
+}
+
+// Do executes fn in a transaction. Transient errors such as write conflicts are
+// retried by the driver, so fn must not have side effects outside the database.
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := u.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	txnOpts := options.Transaction().
+		SetReadConcern(readconcern.Snapshot()).
+		SetWriteConcern(writeconcern.Majority())
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	}, txnOpts)
+	return err
+}
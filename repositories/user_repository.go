@@ -9,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type UserRepository struct {
@@ -107,6 +108,46 @@ func (r *UserRepository) Delete(userID string) error {
 	return nil
 }
 
+// TokenVersion is the version the user's access tokens must carry
+func (r *UserRepository) TokenVersion(ctx context.Context, userID primitive.ObjectID) (int, error) {
+	var user models.User
+	err := r.Collection.FindOne(ctx,
+		bson.M{"_id": userID},
+		options.FindOne().SetProjection(bson.M{"token_version": 1}),
+	).Decode(&user)
+	if err != nil {
+		return 0, err
+	}
+	return user.TokenVersion, nil
+}
+
+// BumpTokenVersion invalidates every access token issued to the user so far
+func (r *UserRepository) BumpTokenVersion(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.Collection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$inc": bson.M{"token_version": 1}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	return err
+}
+
+// SetPaymentCustomerID links the user to their payment provider customer.
+// The first customer saved wins, and the linked ID is returned.
+func (r *UserRepository) SetPaymentCustomerID(ctx context.Context, userID primitive.ObjectID, customerID string) (string, error) {
+	var user models.User
+	err := r.Collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": userID, "payment_customer_id": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"payment_customer_id": customerID, "updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = r.Collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+	}
+	if err != nil {
+		return "", err
+	}
+	return user.PaymentCustomerID, nil
+}
+
 func (r *UserRepository) FindByID(userID primitive.ObjectID) (*models.User, error) {
 	var user models.User
 	err := r.Collection.FindOne(
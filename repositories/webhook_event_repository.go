@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"beauty-ecommerce-backend/models"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrEventInProgress is returned when another delivery of the event holds the claim
+	ErrEventInProgress = errors.New("event is already being processed")
+	// ErrEventDone is returned when the event has already been handled
+	ErrEventDone = errors.New("event has already been handled")
+)
+
+type WebhookEventRepository struct {
+	collection *mongo.Collection
+}
+
+func NewWebhookEventRepository(db *mongo.Database) *WebhookEventRepository {
+	return &WebhookEventRepository{collection: db.Collection("webhook_events")}
+}
+
+func (r *WebhookEventRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "received_at", Value: -1}},
+	})
+	return err
+}
+
+// Claim records a newly received event and takes the processing claim on it
+// until lockFor has passed. An event seen before can only be claimed again if
+// it failed, or if the delivery that claimed it died without finishing.
+func (r *WebhookEventRepository) Claim(ctx context.Context, event *models.WebhookEvent, lockFor time.Duration) (*models.WebhookEvent, error) {
+	now := time.Now()
+	lockedUntil := now.Add(lockFor)
+	event.Status = models.WebhookProcessing
+	event.Attempts = 1
+	event.LockedUntil = &lockedUntil
+	event.ReceivedAt = now
+	event.UpdatedAt = now
+
+	_, err := r.collection.InsertOne(ctx, event)
+	if err == nil {
+		return event, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, err
+	}
+	return r.Reclaim(ctx, event.ID, lockFor)
+}
+
+// Reclaim takes the processing claim on a stored event that failed or whose
+// previous claim has run out. The filter is WebhookEvent.Claimable.
+func (r *WebhookEventRepository) Reclaim(ctx context.Context, id string, lockFor time.Duration) (*models.WebhookEvent, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id": id,
+		"$or": bson.A{
+			bson.M{"status": models.WebhookFailed},
+			bson.M{"status": models.WebhookProcessing, "locked_until": bson.M{"$not": bson.M{"$gte": now}}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{"status": models.WebhookProcessing, "locked_until": now.Add(lockFor), "updated_at": now},
+		"$inc": bson.M{"attempts": 1},
+	}
+
+	var event models.WebhookEvent
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&event)
+	if err == nil {
+		return &event, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	existing, err := r.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return nil, ClaimConflict(existing)
+}
+
+// ClaimConflict says why an event could not be claimed. An event that looks
+// claimable here was claimed by another delivery between the two reads.
+func ClaimConflict(existing *models.WebhookEvent) error {
+	if existing.Handled() {
+		return ErrEventDone
+	}
+	return ErrEventInProgress
+}
+
+// Finish releases the claim and records the outcome
+func (r *WebhookEventRepository) Finish(ctx context.Context, id, status, lastError string) error {
+	now := time.Now()
+	set := bson.M{"status": status, "last_error": lastError, "updated_at": now}
+	if status != models.WebhookFailed {
+		set["processed_at"] = now
+	}
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": set, "$unset": bson.M{"locked_until": ""}},
+	)
+	return err
+}
+
+func (r *WebhookEventRepository) FindByID(id string) (*models.WebhookEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var event models.WebhookEvent
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// FindAll lists events newest first, optionally only those in one status
+func (r *WebhookEventRepository) FindAll(status string, limit int64) ([]models.WebhookEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "received_at", Value: -1}}).SetLimit(limit)
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []models.WebhookEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -4,10 +4,13 @@ import (
 	"beauty-ecommerce-backend/config"
 	"beauty-ecommerce-backend/controllers"
 	"beauty-ecommerce-backend/middlewares"
+	"beauty-ecommerce-backend/models"
 	"beauty-ecommerce-backend/repositories"
 	"beauty-ecommerce-backend/services"
 	servicesimpl "beauty-ecommerce-backend/services_impl"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,33 +26,121 @@ func SetUpRoutes(r *gin.Engine) {
 	orderRepo := repositories.NewOrderRepository(db)
 	cartRepo := repositories.NewCartRepository(db)
 	reviewRepo := repositories.NewReviewRepository(db)
+	couponRepo := repositories.NewCouponRepository(db)
+	shippingRepo := repositories.NewShippingRepository(db)
+	taxRepo := repositories.NewTaxRepository(db)
+	currencyRepo := repositories.NewCurrencyRepository(db)
+	returnRepo := repositories.NewReturnRepository(db)
+	disputeRepo := repositories.NewDisputeRepository(db)
+	webhookEventRepo := repositories.NewWebhookEventRepository(db)
+	paymentAttemptRepo := repositories.NewPaymentAttemptRepository(db)
+	sessionRepo := repositories.NewSessionRepository(db)
 	wishlistCollection := db.Collection("wishlists")
 	wishlistRepo := repositories.NewWishlistRepository(wishlistCollection)
+	unitOfWork := repositories.NewUnitOfWork(db)
+
+	if err := productRepo.EnsureIndexes(); err != nil {
+		fmt.Println("⚠️ Failed to create product indexes:", err)
+	}
+	if err := orderRepo.EnsureIndexes(); err != nil {
+		fmt.Println("⚠️ Failed to create order indexes:", err)
+	}
+	if err := cartRepo.EnsureIndexes(); err != nil {
+		fmt.Println("⚠️ Failed to create cart indexes:", err)
+	}
+	if err := couponRepo.EnsureIndexes(); err != nil {
+		fmt.Println("⚠️ Failed to create coupon indexes:", err)
+	}
+	if err := taxRepo.EnsureIndexes(); err != nil {
+		fmt.Println("⚠️ Failed to create tax rate indexes:", err)
+	}
+	if err := currencyRepo.EnsureIndexes(); err != nil {
+		fmt.Println("⚠️ Failed to create exchange rate indexes:", err)
+	}
+	if err := returnRepo.EnsureIndexes(); err != nil {
+		fmt.Println("⚠️ Failed to create return indexes:", err)
+	}
+	if err := disputeRepo.EnsureIndexes(); err != nil {
+		fmt.Println("⚠️ Failed to create dispute indexes:", err)
+	}
+	if err := webhookEventRepo.EnsureIndexes(); err != nil {
+		fmt.Println("⚠️ Failed to create webhook event indexes:", err)
+	}
+	if err := paymentAttemptRepo.EnsureIndexes(); err != nil {
+		fmt.Println("⚠️ Failed to create payment attempt indexes:", err)
+	}
+	if err := sessionRepo.EnsureIndexes(); err != nil {
+		fmt.Println("⚠️ Failed to create session indexes:", err)
+	}
 
 	// --------------------------
 	// SERVICES
 	// --------------------------
-	userService := servicesimpl.NewUserService(userRepo)
 	productService := servicesimpl.NewProductService(productRepo)
-	orderService := servicesimpl.NewOrderService(orderRepo, productRepo, userRepo)
-	cartService := servicesimpl.NewCartService(cartRepo)
-	reviewService := services.NewReviewService(reviewRepo) // stays as `services`
+	couponService := servicesimpl.NewCouponService(couponRepo)
+	shippingService := servicesimpl.NewShippingService(shippingRepo)
+	taxService := servicesimpl.NewTaxService(taxRepo)
+	currencyService := servicesimpl.NewCurrencyService(currencyRepo)
+
+	// Payments go through Stripe. The fake provider keeps payments in memory
+	// and is only for local development and testing.
+	var paymentProvider services.PaymentProvider = servicesimpl.NewStripePaymentProvider(os.Getenv("STRIPE_SECRET_KEY"), os.Getenv("STRIPE_WEBHOOK_SECRET"))
+	if os.Getenv("PAYMENT_PROVIDER") == servicesimpl.FakePaymentProviderName {
+		if os.Getenv("FAKE_PAYMENT_WEBHOOK_SECRET") == "" {
+			fmt.Println("⚠️ FAKE_PAYMENT_WEBHOOK_SECRET is not set, payment webhooks will be rejected")
+		}
+		paymentProvider = servicesimpl.NewFakePaymentProvider(os.Getenv("FAKE_PAYMENT_WEBHOOK_SECRET"))
+	}
+
+	sessionService := servicesimpl.NewSessionService(sessionRepo, userRepo)
+	userService := servicesimpl.NewUserService(userRepo, paymentProvider, sessionService)
+
+	orderService := servicesimpl.NewOrderService(orderRepo, productRepo, userRepo, cartRepo, paymentAttemptRepo, couponService, shippingService, taxService, currencyService, paymentProvider, unitOfWork)
+	cartService := servicesimpl.NewCartService(cartRepo, productRepo)
+	reviewService := services.NewReviewService(reviewRepo, productRepo) // stays as `services`
 	wishlistService := servicesimpl.NewWishlistService(wishlistRepo, productService)
 
+	// Carrier integrations. The fake carrier books labels in memory and is
+	// only for local development and testing.
+	var carriers []services.Carrier
+	if os.Getenv("ENABLE_FAKE_CARRIER") == "true" {
+		carriers = append(carriers, servicesimpl.NewFakeCarrier())
+	}
+	shipmentService := servicesimpl.NewShipmentService(orderRepo, orderService, unitOfWork, carriers...)
+	refundService := servicesimpl.NewRefundService(orderRepo, orderService, paymentProvider, unitOfWork)
+	returnService := servicesimpl.NewReturnService(returnRepo, orderRepo, userRepo, refundService, unitOfWork)
+	disputeService := servicesimpl.NewDisputeService(disputeRepo, orderRepo, orderService, paymentProvider, unitOfWork)
+	webhookService := servicesimpl.NewWebhookService(webhookEventRepo, orderService, disputeService, paymentProvider)
+
+	// Expire unpaid orders and release their held stock
+	orderService.StartReservationSweeper(time.Minute)
+
+	// Pull parcel tracking from the carriers
+	shipmentService.StartTrackingPoller(15 * time.Minute)
+
 	// --------------------------
 	// CONTROLLERS
 	// --------------------------
-	controllers.InitUserController(userRepo)
+	middlewares.InitJWTMiddleware(userRepo)
+	controllers.InitUserController(userService, sessionService)
 	controllers.InitOrderController(orderService)
-	controllers.InitPaymentController(orderService, userService)
+	controllers.InitPaymentController(orderService, userService, webhookService, paymentProvider)
 	controllers.InitProductController(productService)
 	controllers.InitCartController(cartService)
+	controllers.InitCurrencyController(currencyService)
 
 	productController := controllers.ProductControllerSingleton()
 	adminController := controllers.NewAdminController(productService, orderService, userService)
-	adminAuthController := controllers.NewAdminAuthController()
+	adminAuthController := controllers.NewAdminAuthController(userService)
 	reviewController := controllers.NewReviewController(reviewService)
 	wishlistController := controllers.NewWishlistController(wishlistService)
+	couponController := controllers.NewCouponController(couponService)
+	shippingController := controllers.NewShippingController(shippingService, cartService)
+	taxController := controllers.NewTaxController(taxService)
+	shipmentController := controllers.NewShipmentController(shipmentService)
+	refundController := controllers.NewRefundController(refundService)
+	returnController := controllers.NewReturnController(returnService)
+	disputeController := controllers.NewDisputeController(disputeService)
 
 	// --------------------------
 	// ROUTES
@@ -58,24 +149,85 @@ func SetUpRoutes(r *gin.Engine) {
 	// ADMIN AUTH
 	r.POST("/admin/login", adminAuthController.AdminLogin)
 
-	// ADMIN (JWT + ADMIN)
+	// ADMIN (JWT + staff role + per-route permission)
+	catalog := middlewares.RequirePermission(models.PermCatalogManage)
+	promotions := middlewares.RequirePermission(models.PermPromotionsManage)
+	ordersRead := middlewares.RequirePermission(models.PermOrdersRead)
+	ordersManage := middlewares.RequirePermission(models.PermOrdersManage)
+	refunds := middlewares.RequirePermission(models.PermRefundsIssue)
+	returnsRead := middlewares.RequirePermission(models.PermReturnsRead)
+	returnsManage := middlewares.RequirePermission(models.PermReturnsManage)
+	paymentsRead := middlewares.RequirePermission(models.PermPaymentsRead)
+	paymentsManage := middlewares.RequirePermission(models.PermPaymentsManage)
+	usersRead := middlewares.RequirePermission(models.PermUsersRead)
+	usersManage := middlewares.RequirePermission(models.PermUsersManage)
+	settings := middlewares.RequirePermission(models.PermSettingsManage)
+	analytics := middlewares.RequirePermission(models.PermAnalyticsRead)
+
 	adminRoutes := r.Group("/admin")
 	adminRoutes.Use(middlewares.JWTMiddleware(), middlewares.AdminMiddleware())
 	{
-		adminRoutes.POST("/products", adminController.CreateProduct)
-		adminRoutes.PUT("/products/:id", adminController.UpdateProduct)
-		adminRoutes.DELETE("/products/:id", adminController.DeleteProduct)
+		adminRoutes.GET("/roles", usersRead, adminAuthController.ListRoles)
+
+		adminRoutes.POST("/products", catalog, adminController.CreateProduct)
+		adminRoutes.PUT("/products/:id", catalog, adminController.UpdateProduct)
+		adminRoutes.DELETE("/products/:id", catalog, adminController.DeleteProduct)
+		adminRoutes.POST("/products/:id/variants", catalog, adminController.CreateVariant)
+		adminRoutes.PUT("/products/:id/variants/:variantId", catalog, adminController.UpdateVariant)
+		adminRoutes.DELETE("/products/:id/variants/:variantId", catalog, adminController.DeleteVariant)
+
+		adminRoutes.GET("/orders", ordersRead, adminController.ListOrders)
+		adminRoutes.PATCH("/orders/:id/status", ordersManage, adminController.UpdateOrderStatus)
+		adminRoutes.GET("/orders/:id/payments", paymentsRead, adminController.ListPaymentAttempts)
+		adminRoutes.POST("/orders/:id/shipments", ordersManage, shipmentController.CreateShipment)
+		adminRoutes.PATCH("/orders/:id/shipments/:shipmentId", ordersManage, shipmentController.UpdateShipmentStatus)
+		adminRoutes.POST("/orders/:id/refunds", refunds, refundController.RefundOrder)
+		adminRoutes.GET("/carriers", ordersManage, shipmentController.ListCarriers)
 
-		adminRoutes.GET("/orders", adminController.ListOrders)
-		adminRoutes.PATCH("/orders/:id/status", adminController.UpdateOrderStatus)
+		adminRoutes.GET("/returns", returnsRead, returnController.ListReturns)
+		adminRoutes.GET("/returns/:id", returnsRead, returnController.GetReturn)
+		adminRoutes.POST("/returns/:id/approve", returnsManage, returnController.ApproveReturn)
+		adminRoutes.POST("/returns/:id/reject", returnsManage, returnController.RejectReturn)
+		adminRoutes.POST("/returns/:id/receive", returnsManage, returnController.MarkReturnReceived)
+		adminRoutes.POST("/returns/:id/refund", refunds, returnController.RefundReturn)
+		adminRoutes.GET("/disputes", paymentsRead, disputeController.ListDisputes)
+		adminRoutes.GET("/disputes/:id", paymentsRead, disputeController.GetDispute)
+		adminRoutes.POST("/shipments/refresh", ordersManage, shipmentController.RefreshTracking)
 
-		adminRoutes.GET("/users", adminController.ListUsers)
-		adminRoutes.PATCH("/users/:id", adminController.UpdateUser)
-		adminRoutes.DELETE("/users/:id", adminController.DeleteUser)
+		adminRoutes.GET("/users", usersRead, adminController.ListUsers)
+		adminRoutes.PATCH("/users/:id", usersManage, adminController.UpdateUser)
+		adminRoutes.DELETE("/users/:id", usersManage, adminController.DeleteUser)
 
-		adminRoutes.GET("/analytics/sales", adminController.SalesAnalytics)
+		adminRoutes.GET("/analytics/sales", analytics, adminController.SalesAnalytics)
+
+		adminRoutes.GET("/coupons", promotions, couponController.ListCoupons)
+		adminRoutes.POST("/coupons", promotions, couponController.CreateCoupon)
+		adminRoutes.GET("/coupons/:id", promotions, couponController.GetCoupon)
+		adminRoutes.PUT("/coupons/:id", promotions, couponController.UpdateCoupon)
+		adminRoutes.DELETE("/coupons/:id", promotions, couponController.DeleteCoupon)
+
+		adminRoutes.GET("/shipping/zones", settings, shippingController.ListZones)
+		adminRoutes.POST("/shipping/zones", settings, shippingController.CreateZone)
+		adminRoutes.GET("/shipping/zones/:id", settings, shippingController.GetZone)
+		adminRoutes.PUT("/shipping/zones/:id", settings, shippingController.UpdateZone)
+		adminRoutes.DELETE("/shipping/zones/:id", settings, shippingController.DeleteZone)
+
+		adminRoutes.GET("/tax/rates", settings, taxController.ListRates)
+		adminRoutes.POST("/tax/rates", settings, taxController.CreateRate)
+		adminRoutes.PUT("/tax/rates/:id", settings, taxController.UpdateRate)
+		adminRoutes.DELETE("/tax/rates/:id", settings, taxController.DeleteRate)
+
+		adminRoutes.GET("/webhooks/events", paymentsRead, controllers.ListWebhookEvents)
+		adminRoutes.POST("/webhooks/events/:id/replay", paymentsManage, controllers.ReplayWebhookEvent)
+
+		adminRoutes.GET("/currencies/rates", settings, controllers.ListExchangeRates)
+		adminRoutes.PUT("/currencies/rates/:currency", settings, controllers.SetExchangeRate)
+		adminRoutes.DELETE("/currencies/rates/:currency", settings, controllers.DeleteExchangeRate)
 	}
 
+	// CURRENCIES
+	r.GET("/currencies", controllers.ListCurrencies)
+
 	// PUBLIC PRODUCTS
 	r.GET("/products", productController.GetAllProducts)
 	r.GET("/products/:id", productController.GetProductByID)
@@ -93,6 +245,9 @@ func SetUpRoutes(r *gin.Engine) {
 	// AUTH
 	r.POST("/signup", controllers.Register)
 	r.POST("/login", controllers.Login)
+	r.POST("/auth/refresh", controllers.RefreshSession)
+	r.POST("/auth/logout", controllers.Logout)
+	r.POST("/auth/logout-all", middlewares.JWTMiddleware(), controllers.LogoutAll)
 	r.POST("/auth/forgot-password", controllers.ForgotPassword)
 	r.GET("/reset-password", controllers.ResetPassword)
 	r.POST("/auth/reset-password", controllers.ResetPassword)
@@ -100,15 +255,20 @@ func SetUpRoutes(r *gin.Engine) {
 
 	// CART
 	cartRoutes := r.Group("/cart")
-	cartRoutes.Use(middlewares.JWTMiddleware())
+	// Guests can build a cart with an X-Cart-Token; checkout still needs an account
+	cartRoutes.Use(middlewares.OptionalJWTMiddleware())
 	{
 		cartRoutes.POST("", controllers.CreateCart)
 		cartRoutes.GET("", controllers.GetCart)
 		cartRoutes.PUT("/:id", controllers.UpdateCartItem)
 		cartRoutes.DELETE("/:id", controllers.DeleteCartItem)
 		cartRoutes.DELETE("", controllers.ClearCart)
+		cartRoutes.POST("/checkout", middlewares.JWTMiddleware(), controllers.CheckoutCart)
 	}
 
+	// SHIPPING
+	r.GET("/shipping/quote", middlewares.OptionalJWTMiddleware(), shippingController.Quote)
+
 	// ORDERS + PAYMENTS
 	orderRoutes := r.Group("/orders")
 	orderRoutes.Use(middlewares.JWTMiddleware())
@@ -118,6 +278,8 @@ func SetUpRoutes(r *gin.Engine) {
 		orderRoutes.GET("/:id", controllers.GetOrderByID)
 		orderRoutes.PUT("/:id/cancel", controllers.CancelOrder)
 		orderRoutes.POST("/:id/pay", controllers.InitializePayment)
+		orderRoutes.POST("/:id/returns", returnController.RequestReturn)
+		orderRoutes.GET("/:id/returns", returnController.GetOrderReturns)
 	}
 
 	// WISHLIST
@@ -134,6 +296,10 @@ func SetUpRoutes(r *gin.Engine) {
 	userRoutes.Use(middlewares.JWTMiddleware())
 	{
 		userRoutes.GET("/me", controllers.GetProfile)
+		userRoutes.PUT("/me/currency", controllers.UpdatePreferredCurrency)
+		userRoutes.GET("/me/payment-methods", controllers.ListPaymentMethods)
+		userRoutes.POST("/me/payment-methods", controllers.SetupPaymentMethod)
+		userRoutes.DELETE("/me/payment-methods/:methodId", controllers.RemovePaymentMethod)
 	}
 
 	// VERSION
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+
+	"beauty-ecommerce-backend/models"
+)
+
+// Carrier is a shipping company integration. Each carrier is registered with
+// the shipment service under its Code; admins can still record shipments for
+// carriers without an integration by giving the tracking details by hand.
+type Carrier interface {
+	Code() string
+
+	// CreateLabel books the parcel with the carrier and returns its tracking details
+	CreateLabel(ctx context.Context, order *models.Order, shipment *models.Shipment) (*CarrierLabel, error)
+
+	// Track returns the parcel's current status and its scan history
+	Track(ctx context.Context, trackingNumber string) (*TrackingUpdate, error)
+}
+
+// CarrierLabel is what a carrier returns when a parcel is booked
+type CarrierLabel struct {
+	TrackingNumber string
+	TrackingURL    string
+	LabelURL       string
+}
+
+// TrackingUpdate is a carrier's latest word on a parcel
+type TrackingUpdate struct {
+	Status models.ShipmentStatus
+	Events []models.TrackingEvent
+}
@@ -9,8 +9,10 @@ import (
 type CartService interface {
 	CreateCartItem(cartItem models.CartItem) (models.CartItem, error)
 	GetCartByUser(userID primitive.ObjectID) ([]models.CartItem, error)
+	GetPricedCart(owner models.CartOwner) (*models.PricedCart, error)
 	UpdateCartItem(cartItem models.CartItem) (models.CartItem, error)
 	DeleteCartItem(cartItemID primitive.ObjectID) error
-	ClearCart(userID primitive.ObjectID) error
+	ClearCart(owner models.CartOwner) error
+	MergeGuestCart(token string, userID primitive.ObjectID) error
 	GetCartItemByID(cartItemID primitive.ObjectID) (*models.CartItem, error) // needed for controller
 }
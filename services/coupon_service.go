@@ -0,0 +1,22 @@
+package services
+
+import (
+	"beauty-ecommerce-backend/models"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CouponService interface {
+	// Admin operations
+	CreateCoupon(coupon models.Coupon) (*models.Coupon, error)
+	GetCoupons() ([]models.Coupon, error)
+	GetCoupon(id primitive.ObjectID) (*models.Coupon, error)
+	UpdateCoupon(id primitive.ObjectID, coupon models.Coupon) (*models.Coupon, error)
+	DeleteCoupon(id primitive.ObjectID) error
+
+	// Checkout operations. categories maps product ID (hex) to its category.
+	ApplyCoupon(order *models.Order, categories map[string]string) error
+	RedeemCoupon(ctx context.Context, order *models.Order) error
+	ReleaseCoupon(ctx context.Context, orderID primitive.ObjectID) error
+}
@@ -0,0 +1,18 @@
+package services
+
+import "beauty-ecommerce-backend/models"
+
+type CurrencyService interface {
+	// Admin operations
+	GetRates() ([]models.ExchangeRate, error)
+	SetRate(rate models.ExchangeRate) (*models.ExchangeRate, error)
+	DeleteRate(currency string) error
+
+	// Currencies lists the currencies prices can be shown and charged in,
+	// DefaultCurrency first
+	Currencies() ([]string, error)
+
+	// Rate returns the units of currency per unit of DefaultCurrency. It fails
+	// for currencies without an active rate.
+	Rate(currency string) (float64, error)
+}
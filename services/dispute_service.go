@@ -0,0 +1,17 @@
+package services
+
+import (
+	"beauty-ecommerce-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DisputeService interface {
+	// RecordDispute stores a dispute event from the payment provider and
+	// moves the disputed order along with it
+	RecordDispute(event *PaymentEvent) (*models.Dispute, error)
+
+	// Admin
+	ListDisputes(status models.DisputeStatus, openOnly bool) ([]models.Dispute, error)
+	GetDispute(id primitive.ObjectID) (*models.Dispute, error)
+}
@@ -0,0 +1,31 @@
+package services
+
+import "errors"
+
+// Errors the services return for callers to tell apart with errors.Is.
+// Some are wrapped with more detail, so never compare their text.
+var (
+	ErrProductNotFound       = errors.New("product not found")
+	ErrInvalidQuantity       = errors.New("quantity must be greater than zero")
+	ErrOutOfStock            = errors.New("product is out of stock")
+	ErrCartOwnerRequired     = errors.New("cart owner is required")
+	ErrOrderNotFound         = errors.New("order not found")
+	ErrShipmentNotFound      = errors.New("shipment not found")
+	ErrReturnNotFound        = errors.New("return not found")
+	ErrUserNotFound          = errors.New("user not found")
+	ErrInvalidUserID         = errors.New("invalid user ID")
+	ErrInvalidCredentials    = errors.New("invalid email or password")
+	ErrInvalidRole           = errors.New("invalid role")
+	ErrInvalidRefreshToken   = errors.New("invalid refresh token")
+	ErrPaymentMethodNotFound = errors.New("payment method not found")
+	ErrPaymentNotSaved       = errors.New("failed to save payment reference")
+	ErrCheckoutNotSaved      = errors.New("failed to save checkout session")
+	ErrWebhookEventNotFound  = errors.New("webhook event not found")
+	ErrMalformedEvent        = errors.New("malformed payment event")
+	ErrEventNotApplied       = errors.New("payment event cannot be applied")
+	ErrRefundNotRecorded     = errors.New("refund was issued but could not be saved")
+	ErrCouponNotFound        = errors.New("coupon not found")
+	ErrTaxRateNotFound       = errors.New("tax rate not found")
+	ErrShippingZoneNotFound  = errors.New("shipping zone not found")
+	ErrExchangeRateNotFound  = errors.New("exchange rate not found")
+)
@@ -9,19 +9,24 @@ import (
 type OrderService interface {
 	// User operations (existing)
 	CreateOrder(order models.Order) (models.Order, error)
+	CheckoutCart(userID primitive.ObjectID, details models.Order) (*models.Order, []models.CartChange, error)
 	GetOrdersByUser(userID primitive.ObjectID) ([]models.Order, error)
 	GetOrderByID(orderID primitive.ObjectID) (*models.Order, error)
 	CancelOrder(orderID primitive.ObjectID, userID primitive.ObjectID) (*models.Order, error)
-	InitializePayment(orderID, userID primitive.ObjectID, email string) (string, string, error)
+	InitializePayment(orderID, userID primitive.ObjectID, options PaymentOptions) (*models.Order, *PaymentIntent, error)
+	InitializeCheckout(orderID, userID primitive.ObjectID) (*models.Order, *CheckoutSession, error)
+	CompleteCheckout(sessionID, paymentReference string, paid bool) error
+	ExpireCheckout(sessionID string) error
 	MarkOrderAsPaid(reference string) error
 	SaveOrderReference(orderID string, reference string) error
-	MarkOrderAsFailed(paymentReference string) error
+	MarkOrderAsFailed(paymentReference, errorCode, errorMessage string) error
+	GetPaymentAttempts(orderID primitive.ObjectID) ([]models.PaymentAttempt, error)
 	GetProductByID(productID primitive.ObjectID) (*models.Product, error)
 
 	// Admin operations (new)
 	GetAllOrders() ([]models.Order, error)
-	UpdateOrderStatus(orderID primitive.ObjectID, status string) error
+	UpdateOrderStatus(orderID primitive.ObjectID, status models.OrderStatus, adminID primitive.ObjectID, reason string) (*models.Order, error)
 	GetSalesAnalytics() (map[string]interface{}, error) // optional
 	MarkOrderAsRefunded(paymentReference string) error
-	MarkOrderAsDisputed(paymentReference string) error
+	MarkOrderAsPartiallyRefunded(paymentReference string) error
 }
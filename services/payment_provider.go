@@ -0,0 +1,208 @@
+package services
+
+import (
+	"beauty-ecommerce-backend/models"
+	"context"
+	"net/http"
+	"time"
+)
+
+// PaymentProvider is the payment gateway orders are paid through. Stripe is
+// the production implementation; an in-memory fake stands in for it in tests
+// and local development.
+type PaymentProvider interface {
+	Name() string
+
+	// CreateIntent starts a payment the customer completes in the browser
+	CreateIntent(ctx context.Context, params IntentParams) (*PaymentIntent, error)
+
+	// GetIntent fetches the intent's current state
+	GetIntent(ctx context.Context, intentID string) (*PaymentIntent, error)
+
+	// ConfirmIntent pays the intent with one of its customer's saved payment
+	// methods. The customer may still need to authenticate in the browser.
+	ConfirmIntent(ctx context.Context, intentID, paymentMethodID string) (*PaymentIntent, error)
+
+	// CreateCheckoutSession starts a payment on the provider's hosted checkout
+	// page; the payment itself is created when the customer completes it
+	CreateCheckoutSession(ctx context.Context, params CheckoutParams) (*CheckoutSession, error)
+
+	// CaptureIntent collects a payment that was only authorised
+	CaptureIntent(ctx context.Context, intentID string) (*PaymentIntent, error)
+
+	// Refund returns money from a captured payment. IdempotencyKey makes
+	// retries of the same refund safe.
+	Refund(ctx context.Context, params RefundParams) (*ProviderRefund, error)
+
+	// CreateCustomer registers a shopper with the provider, so their payment
+	// methods can be saved
+	CreateCustomer(ctx context.Context, params CustomerParams) (string, error)
+
+	// CreateSetupIntent starts saving a payment method to a customer without
+	// taking a payment; the customer enters it in the browser
+	CreateSetupIntent(ctx context.Context, customerID string) (*SetupIntent, error)
+
+	ListPaymentMethods(ctx context.Context, customerID string) ([]PaymentMethod, error)
+
+	// DetachPaymentMethod removes a saved payment method from the customer.
+	// It refuses methods saved to other customers.
+	DetachPaymentMethod(ctx context.Context, customerID, paymentMethodID string) error
+
+	// ChargePaymentReference finds the payment a charge belongs to, for events
+	// such as disputes that may only name the charge
+	ChargePaymentReference(ctx context.Context, chargeID string) (string, error)
+
+	// VerifyWebhook checks a webhook delivery's signature, read from whichever
+	// request header the provider signs with, and unwraps the event
+	VerifyWebhook(payload []byte, headers http.Header) (*WebhookDelivery, error)
+
+	// DecodeEvent reads what a verified event means for an order. Event types
+	// the shop does not act on decode to an empty Kind.
+	DecodeEvent(eventType string, data []byte) (*PaymentEvent, error)
+}
+
+type IntentParams struct {
+	Amount            models.Money
+	ManualCapture     bool   // authorise now, capture later with CaptureIntent
+	CustomerID        string // the shopper's provider customer, if they have one
+	SavePaymentMethod bool   // keep the method the customer pays with for next time
+	IdempotencyKey    string
+	Metadata          map[string]string
+}
+
+// PaymentIntent is the provider's record of a payment
+type PaymentIntent struct {
+	ID           string
+	ClientSecret string // handed to the frontend to confirm the payment
+	Status       string
+	Amount       models.Money
+}
+
+// Reusable reports whether the customer can still complete the intent
+func (i *PaymentIntent) Reusable() bool {
+	switch i.Status {
+	case "requires_payment_method", "requires_confirmation", "requires_action", "processing", "requires_capture":
+		return true
+	}
+	return false
+}
+
+// PaymentOptions are the shopper's choices when paying for an order
+type PaymentOptions struct {
+	PaymentMethodID   string `json:"payment_method_id"`   // pay with a saved payment method
+	SavePaymentMethod bool   `json:"save_payment_method"` // save the method entered in the browser
+}
+
+type CustomerParams struct {
+	Email          string
+	Name           string
+	IdempotencyKey string
+	Metadata       map[string]string
+}
+
+// SetupIntent is the provider's record of a payment method being saved
+type SetupIntent struct {
+	ID           string `json:"id"`
+	ClientSecret string `json:"client_secret"` // handed to the frontend to collect the method
+	Status       string `json:"status"`
+}
+
+// PaymentMethod is a payment method saved to a customer. Only cards are
+// offered for saving.
+type PaymentMethod struct {
+	ID       string `json:"id"`
+	Brand    string `json:"brand"`
+	Last4    string `json:"last4"`
+	ExpMonth int    `json:"exp_month"`
+	ExpYear  int    `json:"exp_year"`
+}
+
+// CheckoutParams describes the order shown on a hosted checkout page. The
+// lines, shipping and tax, less the discount, must add up to the order total.
+type CheckoutParams struct {
+	Lines          []CheckoutLine
+	Shipping       models.Money
+	ShippingName   string
+	Discount       models.Money
+	CustomerID     string // shows the customer's saved payment methods; replaces CustomerEmail
+	CustomerEmail  string
+	SuccessURL     string
+	CancelURL      string
+	ExpiresAt      *time.Time // when the page stops accepting payment
+	IdempotencyKey string
+	Metadata       map[string]string
+}
+
+type CheckoutLine struct {
+	Name      string
+	UnitPrice models.Money
+	Quantity  int
+}
+
+// CheckoutSession is the provider's record of a hosted checkout
+type CheckoutSession struct {
+	ID  string
+	URL string // where to send the customer
+}
+
+type RefundParams struct {
+	PaymentReference string // the payment the order was paid with
+	Amount           models.Money
+	Reason           string
+	IdempotencyKey   string
+	Metadata         map[string]string
+}
+
+// ProviderRefund is the provider's record of a refund
+type ProviderRefund struct {
+	ID     string
+	Status string
+}
+
+// WebhookDelivery is a verified webhook event before it is decoded
+type WebhookDelivery struct {
+	ID   string // the provider's event ID, unique per event
+	Type string
+	Data []byte // the event's object, kept so the event can be replayed
+}
+
+// What a payment event means for the order it belongs to
+const (
+	PaymentSucceeded         = "payment_succeeded"
+	PaymentFailed            = "payment_failed"
+	PaymentRefunded          = "payment_refunded" // the whole payment
+	PaymentPartiallyRefunded = "payment_partially_refunded"
+	PaymentDisputed          = "payment_disputed" // a dispute was opened
+	DisputeUpdated           = "dispute_updated"
+	DisputeClosed            = "dispute_closed"
+	CheckoutCompleted        = "checkout_completed"
+	CheckoutExpired          = "checkout_expired"
+)
+
+// PaymentEvent is a decoded webhook event
+type PaymentEvent struct {
+	Kind             string `json:"kind"`
+	PaymentReference string `json:"payment_reference,omitempty"` // the intent the event is about
+	ChargeID         string `json:"charge_id,omitempty"`
+
+	// Set on failed payments
+	ErrorCode    string `json:"error_code,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+
+	// Set on checkout events. A completed checkout may still be waiting on
+	// the payment, which then reports back with its own event.
+	CheckoutSessionID string `json:"checkout_session_id,omitempty"`
+	Paid              bool   `json:"paid,omitempty"`
+
+	// Set on dispute events
+	Dispute *ProviderDispute `json:"dispute,omitempty"`
+}
+
+// ProviderDispute is the provider's view of a dispute
+type ProviderDispute struct {
+	ID            string               `json:"id"`
+	Status        models.DisputeStatus `json:"status"`
+	Reason        string               `json:"reason,omitempty"`
+	Amount        models.Money         `json:"amount"`
+	EvidenceDueBy *time.Time           `json:"evidence_due_by,omitempty"`
+}
@@ -5,7 +5,13 @@ import "beauty-ecommerce-backend/models"
 type ProductService interface {
 	CreateProduct(product *models.Product) error // <-- pointer
 	GetAllProducts() ([]models.Product, error)
+	SearchProducts(query models.ProductQuery) (*models.ProductPage, error)
 	GetProductByID(id string) (*models.Product, error)
 	UpdateProduct(id string, product models.Product) error
 	DeleteProduct(id string) error
+
+	// Variants
+	AddVariant(productID string, variant models.ProductVariant) (*models.Product, error)
+	UpdateVariant(productID, variantID string, variant models.ProductVariant) (*models.Product, error)
+	DeleteVariant(productID, variantID string) error
 }
@@ -0,0 +1,12 @@
+package services
+
+import (
+	"beauty-ecommerce-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RefundService interface {
+	// Admin operations
+	RefundOrder(orderID primitive.ObjectID, req models.RefundRequest, adminID primitive.ObjectID) (*models.Order, *models.Refund, error)
+}
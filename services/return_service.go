@@ -0,0 +1,21 @@
+package services
+
+import (
+	"beauty-ecommerce-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReturnService interface {
+	// Customer operations
+	RequestReturn(orderID, userID primitive.ObjectID, req models.ReturnRequest) (*models.Return, error)
+	GetOrderReturns(orderID, userID primitive.ObjectID) ([]models.Return, error)
+
+	// Admin operations
+	ListReturns(status models.ReturnStatus) ([]models.Return, error)
+	GetReturn(id primitive.ObjectID) (*models.Return, error)
+	ApproveReturn(id, adminID primitive.ObjectID, note string) (*models.Return, error)
+	RejectReturn(id, adminID primitive.ObjectID, note string) (*models.Return, error)
+	MarkReturnReceived(id, adminID primitive.ObjectID, note string) (*models.Return, error)
+	RefundReturn(id, adminID primitive.ObjectID, restock bool) (*models.Return, *models.Order, error)
+}
@@ -4,6 +4,8 @@ import (
 	"beauty-ecommerce-backend/models"
 	"beauty-ecommerce-backend/repositories"
 	"errors"
+	"fmt"
+	"math"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,11 +13,12 @@ import (
 )
 
 type ReviewService struct {
-	repo *repositories.ReviewRepository
+	repo        *repositories.ReviewRepository
+	productRepo *repositories.ProductRepository
 }
 
-func NewReviewService(repo *repositories.ReviewRepository) *ReviewService {
-	return &ReviewService{repo}
+func NewReviewService(repo *repositories.ReviewRepository, productRepo *repositories.ProductRepository) *ReviewService {
+	return &ReviewService{repo: repo, productRepo: productRepo}
 }
 
 // -------------------------------
@@ -31,7 +34,12 @@ func (s *ReviewService) CreateReview(userID, productID primitive.ObjectID, ratin
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
-	return s.repo.Create(&review)
+	if err := s.repo.Create(&review); err != nil {
+		return err
+	}
+
+	s.refreshProductRating(productID)
+	return nil
 }
 
 // -------------------------------
@@ -55,7 +63,12 @@ func (s *ReviewService) UpdateReview(reviewID primitive.ObjectID, userID primiti
 		"updated_at": time.Now(),
 	}
 
-	return s.repo.Update(reviewID, update)
+	if err := s.repo.Update(reviewID, update); err != nil {
+		return err
+	}
+
+	s.refreshProductRating(review.ProductID)
+	return nil
 }
 
 // -------------------------------
@@ -72,7 +85,12 @@ func (s *ReviewService) DeleteReview(reviewID primitive.ObjectID, userID primiti
 		return errors.New("unauthorized: you can only delete your own review")
 	}
 
-	return s.repo.Delete(reviewID)
+	if err := s.repo.Delete(reviewID); err != nil {
+		return err
+	}
+
+	s.refreshProductRating(review.ProductID)
+	return nil
 }
 
 // -------------------------------
@@ -81,3 +99,22 @@ func (s *ReviewService) DeleteReview(reviewID primitive.ObjectID, userID primiti
 func (s *ReviewService) GetProductReviews(productID primitive.ObjectID) ([]models.Review, error) {
 	return s.repo.GetByProduct(productID)
 }
+
+// -------------------------------
+// Keep product rating in sync (used by catalog sort)
+// -------------------------------
+func (s *ReviewService) refreshProductRating(productID primitive.ObjectID) {
+	avg, count, err := s.repo.RatingSummary(productID)
+	if err != nil {
+		fmt.Println("⚠️ Failed to compute product rating:", err)
+		return
+	}
+
+	update := bson.M{
+		"rating":       math.Round(avg*10) / 10,
+		"review_count": count,
+	}
+	if err := s.productRepo.Update(productID, update); err != nil {
+		fmt.Println("⚠️ Failed to update product rating:", err)
+	}
+}
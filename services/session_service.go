@@ -0,0 +1,36 @@
+package services
+
+import (
+	"beauty-ecommerce-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SessionService issues and revokes sign-ins. Each sign-in gets a short-lived
+// access token and a refresh token that is replaced every time it is used.
+type SessionService interface {
+	StartSession(user *models.User, client SessionClient) (*TokenPair, error)
+
+	// Refresh trades a refresh token for a new pair. A refresh token that was
+	// already traded in ends its session, as it has likely been stolen.
+	Refresh(refreshToken string, client SessionClient) (*TokenPair, error)
+
+	// Logout ends the session holding the refresh token
+	Logout(refreshToken string) error
+
+	// RevokeAll ends every session and invalidates every access token the
+	// user holds, e.g. on "log out all devices" or a password change
+	RevokeAll(userID primitive.ObjectID) error
+}
+
+// SessionClient is the device a session was started from
+type SessionClient struct {
+	UserAgent string
+	IP        string
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // seconds until the access token expires
+}
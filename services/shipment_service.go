@@ -0,0 +1,19 @@
+package services
+
+import (
+	"beauty-ecommerce-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ShipmentService interface {
+	// Admin operations
+	CreateShipment(orderID primitive.ObjectID, req models.ShipmentRequest, adminID primitive.ObjectID) (*models.Order, *models.Shipment, error)
+	// UpdateShipmentStatus records a status by hand, for carriers without an integration
+	UpdateShipmentStatus(orderID, shipmentID primitive.ObjectID, status models.ShipmentStatus, description string, adminID primitive.ObjectID) (*models.Order, error)
+	Carriers() []string
+
+	// RefreshTracking polls the carriers for parcels still on their way and
+	// returns how many shipments changed
+	RefreshTracking() (int, error)
+}
@@ -0,0 +1,19 @@
+package services
+
+import (
+	"beauty-ecommerce-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ShippingService interface {
+	// Admin operations
+	CreateZone(zone models.ShippingZone) (*models.ShippingZone, error)
+	GetZones() ([]models.ShippingZone, error)
+	GetZone(id primitive.ObjectID) (*models.ShippingZone, error)
+	UpdateZone(id primitive.ObjectID, zone models.ShippingZone) (*models.ShippingZone, error)
+	DeleteZone(id primitive.ObjectID) error
+
+	// QuoteShipping lists the methods available for an address, cheapest first
+	QuoteShipping(address models.Address, parcel models.ShippingParcel) ([]models.ShippingQuote, error)
+}
@@ -0,0 +1,19 @@
+package services
+
+import (
+	"beauty-ecommerce-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TaxService interface {
+	// Admin operations
+	CreateRate(rate models.TaxRate) (*models.TaxRate, error)
+	GetRates() ([]models.TaxRate, error)
+	UpdateRate(id primitive.ObjectID, rate models.TaxRate) (*models.TaxRate, error)
+	DeleteRate(id primitive.ObjectID) error
+
+	// CalculateTax fills in the per-line tax, the per-rate TaxLines and
+	// TaxTotal. Items, shipping and discounts must already be priced.
+	CalculateTax(order *models.Order) error
+}
@@ -9,7 +9,8 @@ import (
 
 type UserService interface {
 	Register(user models.User) error
-	Login(email, password string) (string, error)
+	Login(email, password string, client SessionClient) (*TokenPair, error)
+	StaffLogin(email, password string, client SessionClient) (*TokenPair, *models.User, error)
 
 	GetAllUsers() ([]models.User, error)
 	UpdateUser(userID string, update models.User) error
@@ -17,10 +18,16 @@ type UserService interface {
 	GetUserByID(id primitive.ObjectID) (models.User, error)
 	GetProfile(userID primitive.ObjectID) (*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
+	UpdateCurrency(userID primitive.ObjectID, currency string) error
 
 	// 🔐 Password reset
 	SavePasswordResetToken(userID primitive.ObjectID, hashedToken string, expiry time.Time) error
 	GetUserByResetToken(hashedToken string) (*models.User, error)
 	UpdatePassword(userID primitive.ObjectID, hashedPassword string) error
 	ClearResetToken(userID primitive.ObjectID) error
+
+	// 💳 Saved payment methods
+	ListPaymentMethods(userID primitive.ObjectID) ([]PaymentMethod, error)
+	SetupPaymentMethod(userID primitive.ObjectID) (*SetupIntent, error)
+	RemovePaymentMethod(userID primitive.ObjectID, paymentMethodID string) error
 }
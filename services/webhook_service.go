@@ -0,0 +1,13 @@
+package services
+
+import "beauty-ecommerce-backend/models"
+
+type WebhookService interface {
+	// HandleEvent acts on a verified payment provider event exactly once.
+	// Events already handled return repositories.ErrEventDone.
+	HandleEvent(id, eventType string, data []byte) error
+
+	// Admin operations
+	ListEvents(status string) ([]models.WebhookEvent, error)
+	ReplayEvent(id string) (*models.WebhookEvent, error)
+}
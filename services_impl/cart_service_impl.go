@@ -2,43 +2,173 @@ package servicesimpl
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"time"
 
 	"beauty-ecommerce-backend/models"
 	"beauty-ecommerce-backend/repositories"
+	"beauty-ecommerce-backend/services"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type CartServiceImpl struct {
-	cartRepo *repositories.CartRepository
+	cartRepo    *repositories.CartRepository
+	productRepo *repositories.ProductRepository
 }
 
-func NewCartService(cartRepo *repositories.CartRepository) *CartServiceImpl {
-	return &CartServiceImpl{cartRepo: cartRepo}
+func NewCartService(cartRepo *repositories.CartRepository, productRepo *repositories.ProductRepository) *CartServiceImpl {
+	return &CartServiceImpl{cartRepo: cartRepo, productRepo: productRepo}
 }
 
+// CreateCartItem adds to the existing line for the same product and variant,
+// capping the combined quantity at what is in stock. The line belongs to
+// cartItem.UserID or, for guests, cartItem.CartToken.
 func (c *CartServiceImpl) CreateCartItem(cartItem models.CartItem) (models.CartItem, error) {
 	if cartItem.Quantity <= 0 {
-		return models.CartItem{}, errors.New("quantity must be greater than zero")
+		return models.CartItem{}, services.ErrInvalidQuantity
 	}
 
-	cartItem.ID = primitive.NewObjectID()
-	cartItem.CreatedAt = time.Now()
-	cartItem.UpdatedAt = time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	err := c.cartRepo.AddToCart(context.Background(), &cartItem)
-	return cartItem, err
+	owner := models.CartOwner{UserID: cartItem.UserID, Token: cartItem.CartToken}
+	if owner.IsGuest() && owner.Token == "" {
+		return models.CartItem{}, services.ErrCartOwnerRequired
+	}
+
+	saved, err := c.addToLine(ctx, owner, cartItem.ProductID, cartItem.VariantID, cartItem.Quantity)
+	if err != nil {
+		return models.CartItem{}, err
+	}
+	return *saved, nil
+}
+
+// addToLine adds quantity to the owner's line for a product/variant, creating
+// the line if needed and capping it at the available stock
+func (c *CartServiceImpl) addToLine(ctx context.Context, owner models.CartOwner, productID, variantID primitive.ObjectID, quantity int) (*models.CartItem, error) {
+	product, variant, err := c.resolveProduct(productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	available := product.AvailableStock(variant)
+	if available <= 0 {
+		return nil, services.ErrOutOfStock
+	}
+
+	// Remember the price so checkout can tell the shopper if it changed
+	return c.cartRepo.AddToLine(ctx, owner, productID, variantID, quantity, product.UnitPrice(variant), available)
+}
+
+// MergeGuestCart moves a guest cart into the user's cart after sign-in. Lines
+// for the same product and variant are added together and capped at stock;
+// lines that are no longer available are dropped.
+func (c *CartServiceImpl) MergeGuestCart(token string, userID primitive.ObjectID) error {
+	if token == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	guest := models.CartOwner{Token: token}
+	items, err := c.cartRepo.GetCart(ctx, guest)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	user := models.CartOwner{UserID: userID}
+	for _, item := range items {
+		if _, err := c.addToLine(ctx, user, item.ProductID, item.VariantID, item.Quantity); err != nil {
+			fmt.Println("⚠️ Dropping guest cart line", item.ID.Hex(), "during merge:", err)
+		}
+	}
+
+	return c.cartRepo.DeleteByOwner(ctx, guest)
 }
 
 func (c *CartServiceImpl) GetCartByUser(userID primitive.ObjectID) ([]models.CartItem, error) {
 	return c.cartRepo.GetUserCart(context.Background(), userID)
 }
 
+// GetPricedCart prices every line against the current catalog
+func (c *CartServiceImpl) GetPricedCart(owner models.CartOwner) (*models.PricedCart, error) {
+	items, err := c.cartRepo.GetCart(context.Background(), owner)
+	if err != nil {
+		return nil, err
+	}
+
+	cart := &models.PricedCart{
+		Items:    []models.PricedCartItem{},
+		Subtotal: models.Money{Currency: models.DefaultCurrency},
+	}
+	for _, item := range items {
+		line := models.PricedCartItem{
+			ID:        item.ID,
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+		}
+		if !item.VariantID.IsZero() {
+			line.VariantID = item.VariantID.Hex()
+		}
+
+		product, variant, err := c.resolveProduct(item.ProductID, item.VariantID)
+		if err != nil {
+			// Keep the line so the shopper can see and remove it
+			line.Name = "Unavailable product"
+			cart.Items = append(cart.Items, line)
+			continue
+		}
+
+		line.Name = product.Name
+		line.ImageURL = product.ImageURL
+		line.UnitPrice = product.UnitPrice(variant)
+		line.Available = product.AvailableStock(variant)
+		line.InStock = line.Available >= item.Quantity
+		if variant != nil {
+			line.VariantLabel = variant.Label()
+			if variant.ImageURL != "" {
+				line.ImageURL = variant.ImageURL
+			}
+		}
+		line.LineTotal = line.UnitPrice.Mul(item.Quantity)
+
+		if line.InStock {
+			cart.ItemCount += item.Quantity
+			cart.Subtotal = cart.Subtotal.Add(line.LineTotal)
+			cart.WeightGrams += product.WeightGrams * item.Quantity
+		}
+		cart.Items = append(cart.Items, line)
+	}
+	return cart, nil
+}
+
+// UpdateCartItem sets the quantity of a line, capped at what is in stock
 func (c *CartServiceImpl) UpdateCartItem(cartItem models.CartItem) (models.CartItem, error) {
+	if cartItem.Quantity <= 0 {
+		return models.CartItem{}, services.ErrInvalidQuantity
+	}
+
+	product, variant, err := c.resolveProduct(cartItem.ProductID, cartItem.VariantID)
+	if err != nil {
+		return models.CartItem{}, err
+	}
+
+	available := product.AvailableStock(variant)
+	if available <= 0 {
+		return models.CartItem{}, services.ErrOutOfStock
+	}
+	if cartItem.Quantity > available {
+		cartItem.Quantity = available
+	}
+
 	cartItem.UpdatedAt = time.Now()
-	err := c.cartRepo.UpdateQuantity(context.Background(), cartItem.ID, cartItem.Quantity)
+	err = c.cartRepo.UpdateQuantity(context.Background(), cartItem.ID, cartItem.Quantity)
 	return cartItem, err
 }
 
@@ -46,19 +176,23 @@ func (c *CartServiceImpl) DeleteCartItem(cartItemID primitive.ObjectID) error {
 	return c.cartRepo.DeleteCartItem(context.Background(), cartItemID)
 }
 
-func (c *CartServiceImpl) ClearCart(userID primitive.ObjectID) error {
-	cartItems, err := c.cartRepo.GetUserCart(context.Background(), userID)
-	if err != nil {
-		return err
-	}
-	for _, item := range cartItems {
-		if err := c.cartRepo.DeleteCartItem(context.Background(), item.ID); err != nil {
-			return err
-		}
-	}
-	return nil
+func (c *CartServiceImpl) ClearCart(owner models.CartOwner) error {
+	return c.cartRepo.DeleteByOwner(context.Background(), owner)
 }
 
 func (s *CartServiceImpl) GetCartItemByID(cartItemID primitive.ObjectID) (*models.CartItem, error) {
 	return s.cartRepo.FindByID(cartItemID)
 }
+
+// resolveProduct loads the product and variant a cart line refers to
+func (c *CartServiceImpl) resolveProduct(productID, variantID primitive.ObjectID) (*models.Product, *models.ProductVariant, error) {
+	product, err := c.productRepo.FindByID(productID)
+	if err != nil {
+		return nil, nil, services.ErrProductNotFound
+	}
+	variant, err := product.ResolveVariant(variantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return product, variant, nil
+}
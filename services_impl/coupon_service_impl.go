@@ -0,0 +1,373 @@
+package servicesimpl
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/repositories"
+	"beauty-ecommerce-backend/services"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var _ services.CouponService = (*couponServiceImpl)(nil)
+
+var couponCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+type couponServiceImpl struct {
+	couponRepo *repositories.CouponRepository
+}
+
+func NewCouponService(couponRepo *repositories.CouponRepository) *couponServiceImpl {
+	return &couponServiceImpl{couponRepo: couponRepo}
+}
+
+// -------------------- ADMIN CRUD --------------------
+func (s *couponServiceImpl) CreateCoupon(coupon models.Coupon) (*models.Coupon, error) {
+	coupon.Code = normalizeCouponCode(coupon.Code)
+	if err := validateCoupon(&coupon); err != nil {
+		return nil, err
+	}
+
+	coupon.ID = primitive.NewObjectID()
+	coupon.UsedCount = 0
+	coupon.CreatedAt = time.Now()
+	coupon.UpdatedAt = time.Now()
+
+	if err := s.couponRepo.Create(&coupon); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("coupon code already exists")
+		}
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (s *couponServiceImpl) GetCoupons() ([]models.Coupon, error) {
+	return s.couponRepo.FindAll()
+}
+
+func (s *couponServiceImpl) GetCoupon(id primitive.ObjectID) (*models.Coupon, error) {
+	coupon, err := s.couponRepo.FindByID(id)
+	if err != nil {
+		return nil, services.ErrCouponNotFound
+	}
+	return coupon, nil
+}
+
+// UpdateCoupon replaces the coupon's settings; its usage count is kept
+func (s *couponServiceImpl) UpdateCoupon(id primitive.ObjectID, coupon models.Coupon) (*models.Coupon, error) {
+	existing, err := s.couponRepo.FindByID(id)
+	if err != nil {
+		return nil, services.ErrCouponNotFound
+	}
+
+	coupon.Code = normalizeCouponCode(coupon.Code)
+	if err := validateCoupon(&coupon); err != nil {
+		return nil, err
+	}
+
+	update := bson.M{
+		"code":           coupon.Code,
+		"description":    coupon.Description,
+		"type":           coupon.Type,
+		"value":          coupon.Value,
+		"amount":         coupon.Amount,
+		"buy_quantity":   coupon.BuyQuantity,
+		"get_quantity":   coupon.GetQuantity,
+		"min_spend":      coupon.MinSpend,
+		"starts_at":      coupon.StartsAt,
+		"ends_at":        coupon.EndsAt,
+		"usage_limit":    coupon.UsageLimit,
+		"per_user_limit": coupon.PerUserLimit,
+		"product_ids":    coupon.ProductIDs,
+		"categories":     coupon.Categories,
+		"active":         coupon.Active,
+		"updated_at":     time.Now(),
+	}
+	if err := s.couponRepo.Update(id, update); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("coupon code already exists")
+		}
+		return nil, err
+	}
+
+	coupon.ID = id
+	coupon.UsedCount = existing.UsedCount
+	coupon.CreatedAt = existing.CreatedAt
+	coupon.UpdatedAt = update["updated_at"].(time.Time)
+	return &coupon, nil
+}
+
+func (s *couponServiceImpl) DeleteCoupon(id primitive.ObjectID) error {
+	if err := s.couponRepo.Delete(id); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return services.ErrCouponNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// -------------------- CHECKOUT --------------------
+
+// ApplyCoupon validates order.CouponCode against the order and fills in the
+// discount lines. Shipping must already be priced so free-shipping codes know
+// what to waive. An empty code clears any discount.
+func (s *couponServiceImpl) ApplyCoupon(order *models.Order, categories map[string]string) error {
+	zero := models.Money{Currency: order.Subtotal.Currency}
+	order.Discounts = nil
+	order.DiscountTotal = zero
+	for i := range order.Items {
+		order.Items[i].DiscountAmount = zero
+	}
+
+	code := normalizeCouponCode(order.CouponCode)
+	order.CouponCode = code
+	if code == "" {
+		return nil
+	}
+
+	coupon, err := s.couponRepo.FindByCode(code)
+	if err != nil || !coupon.Active {
+		return errors.New("invalid coupon code")
+	}
+
+	now := time.Now()
+	if coupon.StartsAt != nil && now.Before(*coupon.StartsAt) {
+		return errors.New("coupon is not active yet")
+	}
+	if coupon.EndsAt != nil && now.After(*coupon.EndsAt) {
+		return errors.New("coupon has expired")
+	}
+	if coupon.UsageLimit > 0 && coupon.UsedCount >= coupon.UsageLimit {
+		return repositories.ErrCouponUsageExceeded
+	}
+	if err := s.checkUserLimit(context.Background(), coupon, order.UserID); err != nil {
+		return err
+	}
+
+	// Coupon amounts are set in DefaultCurrency
+	if order.Currency != "" {
+		coupon.Amount = coupon.Amount.Convert(order.Currency, order.ExchangeRate)
+		coupon.MinSpend = coupon.MinSpend.Convert(order.Currency, order.ExchangeRate)
+	}
+
+	amount, err := couponDiscount(coupon, order, categories)
+	if err != nil {
+		return err
+	}
+
+	order.Discounts = []models.DiscountLine{{
+		CouponID:    coupon.ID,
+		Code:        coupon.Code,
+		Type:        coupon.Type,
+		Description: coupon.Description,
+		Amount:      amount,
+	}}
+	order.DiscountTotal = amount
+	allocateDiscount(coupon, order, categories, amount)
+	return nil
+}
+
+// RedeemCoupon takes a use of every coupon on the order. It runs inside the
+// order's unit of work so a failed order never consumes a use, and the usage
+// and per-user limits are enforced there in case of concurrent checkouts.
+func (s *couponServiceImpl) RedeemCoupon(ctx context.Context, order *models.Order) error {
+	for _, line := range order.Discounts {
+		err := s.couponRepo.Redeem(ctx, &models.CouponRedemption{
+			ID:        primitive.NewObjectID(),
+			CouponID:  line.CouponID,
+			Code:      line.Code,
+			UserID:    order.UserID,
+			OrderID:   order.ID,
+			Amount:    line.Amount,
+			CreatedAt: time.Now(),
+		})
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return errors.New("invalid coupon code")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReleaseCoupon gives back the uses taken by an order that will never be paid
+func (s *couponServiceImpl) ReleaseCoupon(ctx context.Context, orderID primitive.ObjectID) error {
+	return s.couponRepo.ReleaseRedemptions(ctx, orderID)
+}
+
+// checkUserLimit rejects a coupon the user has used up before the order is
+// priced. Redeem enforces the limit again when the order is placed.
+func (s *couponServiceImpl) checkUserLimit(ctx context.Context, coupon *models.Coupon, userID primitive.ObjectID) error {
+	if coupon.PerUserLimit <= 0 {
+		return nil
+	}
+
+	used, err := s.couponRepo.UserUsage(ctx, coupon.ID, userID)
+	if err != nil {
+		return err
+	}
+	if used >= coupon.PerUserLimit {
+		return repositories.ErrCouponUserLimitReached
+	}
+	return nil
+}
+
+// couponDiscount works out how much the coupon takes off the order
+func couponDiscount(coupon *models.Coupon, order *models.Order, categories map[string]string) (models.Money, error) {
+	eligibleSubtotal := models.Money{Currency: order.Subtotal.Currency}
+	var eligibleUnits []models.Money
+
+	for _, item := range order.Items {
+		productID, _ := primitive.ObjectIDFromHex(item.ProductID)
+		if !coupon.AppliesTo(productID, categories[item.ProductID]) {
+			continue
+		}
+		eligibleSubtotal = eligibleSubtotal.Add(item.Price.Mul(item.Quantity))
+		for i := 0; i < item.Quantity; i++ {
+			eligibleUnits = append(eligibleUnits, item.Price)
+		}
+	}
+
+	none := models.Money{Currency: eligibleSubtotal.Currency}
+	if len(eligibleUnits) == 0 {
+		return none, errors.New("coupon does not apply to any items in your order")
+	}
+	if eligibleSubtotal.LessThan(coupon.MinSpend) {
+		return none, fmt.Errorf("spend at least %s on eligible items to use this coupon", coupon.MinSpend.Format())
+	}
+
+	amount := none
+	switch coupon.Type {
+	case models.CouponPercentage:
+		amount = eligibleSubtotal.Percent(coupon.Value)
+	case models.CouponFixed:
+		amount = coupon.Amount
+	case models.CouponFreeShipping:
+		if !order.ShippingFee.IsPositive() {
+			return none, errors.New("this order already has free shipping")
+		}
+		return order.ShippingFee, nil
+	case models.CouponBuyXGetY:
+		group := coupon.BuyQuantity + coupon.GetQuantity
+		free := len(eligibleUnits) / group * coupon.GetQuantity
+		if free == 0 {
+			return none, fmt.Errorf("add %d eligible items to use this coupon", group)
+		}
+		// The cheapest units are the free ones
+		sort.Slice(eligibleUnits, func(a, b int) bool { return eligibleUnits[a].Amount < eligibleUnits[b].Amount })
+		for _, price := range eligibleUnits[:free] {
+			amount = amount.Add(price)
+		}
+	default:
+		return none, errors.New("invalid coupon type")
+	}
+
+	return amount.Min(eligibleSubtotal), nil
+}
+
+// allocateDiscount spreads an item discount over the eligible lines so each
+// line can be taxed on what the customer actually pays for it
+func allocateDiscount(coupon *models.Coupon, order *models.Order, categories map[string]string, amount models.Money) {
+	if coupon.Type == models.CouponFreeShipping {
+		return
+	}
+
+	var eligible []int
+	eligibleSubtotal := models.Money{Currency: amount.Currency}
+	for i, item := range order.Items {
+		productID, _ := primitive.ObjectIDFromHex(item.ProductID)
+		if coupon.AppliesTo(productID, categories[item.ProductID]) {
+			eligible = append(eligible, i)
+			eligibleSubtotal = eligibleSubtotal.Add(item.Price.Mul(item.Quantity))
+		}
+	}
+	if len(eligible) == 0 || !eligibleSubtotal.IsPositive() {
+		return
+	}
+
+	if coupon.Type == models.CouponBuyXGetY {
+		// The free units are the cheapest ones, so the discount sits on their lines
+		sort.SliceStable(eligible, func(a, b int) bool {
+			return order.Items[eligible[a]].Price.LessThan(order.Items[eligible[b]].Price)
+		})
+		remaining := amount
+		for _, i := range eligible {
+			item := &order.Items[i]
+			for u := 0; u < item.Quantity && remaining.IsPositive(); u++ {
+				share := item.Price.Min(remaining)
+				item.DiscountAmount = item.DiscountAmount.Add(share)
+				remaining = remaining.Sub(share)
+			}
+		}
+		return
+	}
+
+	// Proportional to line totals, with rounding left on the last line
+	allocated := models.Money{Currency: amount.Currency}
+	for n, i := range eligible {
+		item := &order.Items[i]
+		if n == len(eligible)-1 {
+			item.DiscountAmount = amount.Sub(allocated)
+			break
+		}
+		share := amount.Ratio(item.Price.Mul(item.Quantity).Amount, eligibleSubtotal.Amount)
+		item.DiscountAmount = share
+		allocated = allocated.Add(share)
+	}
+}
+
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func validateCoupon(coupon *models.Coupon) error {
+	if !couponCodePattern.MatchString(coupon.Code) {
+		return errors.New("code must be 3-32 letters, digits, dashes or underscores")
+	}
+
+	switch coupon.Type {
+	case models.CouponPercentage:
+		if coupon.Value <= 0 || coupon.Value > 100 {
+			return errors.New("percentage value must be between 0 and 100")
+		}
+		coupon.Amount = models.Money{}
+	case models.CouponFixed:
+		if !coupon.Amount.IsPositive() {
+			return errors.New("fixed amount must be greater than zero")
+		}
+		coupon.Value = 0
+	case models.CouponFreeShipping:
+		coupon.Value = 0
+		coupon.Amount = models.Money{}
+	case models.CouponBuyXGetY:
+		if coupon.BuyQuantity < 1 || coupon.GetQuantity < 1 {
+			return errors.New("buy_quantity and get_quantity must be at least 1")
+		}
+	default:
+		return errors.New("type must be percentage, fixed, free_shipping or buy_x_get_y")
+	}
+
+	amountOK := normalizeShopCurrency(&coupon.Amount)
+	minSpendOK := normalizeShopCurrency(&coupon.MinSpend)
+	if !amountOK || !minSpendOK {
+		return errors.New("coupon amounts must be in " + models.DefaultCurrency)
+	}
+	if coupon.MinSpend.IsNegative() || coupon.UsageLimit < 0 || coupon.PerUserLimit < 0 {
+		return errors.New("min_spend and limits cannot be negative")
+	}
+	if coupon.StartsAt != nil && coupon.EndsAt != nil && !coupon.EndsAt.After(*coupon.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package servicesimpl
+
+import (
+	"testing"
+
+	"beauty-ecommerce-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCouponDiscount(t *testing.T) {
+	lipstick := primitive.NewObjectID()
+	serum := primitive.NewObjectID()
+
+	order := &models.Order{
+		Items: []models.OrderItem{
+			{ProductID: lipstick.Hex(), Quantity: 2, Price: models.GBP(1000)},
+			{ProductID: serum.Hex(), Quantity: 1, Price: models.GBP(3000)},
+		},
+		Subtotal:    models.GBP(5000),
+		ShippingFee: models.GBP(399),
+	}
+	categories := map[string]string{lipstick.Hex(): "makeup", serum.Hex(): "skincare"}
+
+	tests := []struct {
+		name    string
+		coupon  models.Coupon
+		want    int64
+		wantErr bool
+	}{
+		{"percentage", models.Coupon{Type: models.CouponPercentage, Value: 10}, 500, false},
+		{"percentage restricted to category", models.Coupon{Type: models.CouponPercentage, Value: 10, Categories: []string{"makeup"}}, 200, false},
+		{"fixed capped at eligible subtotal", models.Coupon{Type: models.CouponFixed, Amount: models.GBP(10000), ProductIDs: []primitive.ObjectID{lipstick}}, 2000, false},
+		{"free shipping", models.Coupon{Type: models.CouponFreeShipping}, 399, false},
+		{"buy 2 get 1 frees the cheapest unit", models.Coupon{Type: models.CouponBuyXGetY, BuyQuantity: 2, GetQuantity: 1}, 1000, false},
+		{"min spend not reached", models.Coupon{Type: models.CouponFixed, Amount: models.GBP(500), MinSpend: models.GBP(6000)}, 0, true},
+		{"no eligible items", models.Coupon{Type: models.CouponFixed, Amount: models.GBP(500), Categories: []string{"fragrance"}}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := couponDiscount(&tt.coupon, order, categories)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, models.GBP(tt.want), got)
+		})
+	}
+}
+
+func TestValidateCouponCurrency(t *testing.T) {
+	coupon := models.Coupon{
+		Code:     "SPRING5",
+		Type:     models.CouponFixed,
+		Amount:   models.Money{Amount: 500, Currency: " gbp"},
+		MinSpend: models.Money{Amount: 2000},
+	}
+	assert.NoError(t, validateCoupon(&coupon))
+	assert.Equal(t, models.GBP(500), coupon.Amount)
+	assert.Equal(t, models.GBP(2000), coupon.MinSpend)
+
+	coupon.MinSpend = models.NewMoney(2000, "eur")
+	assert.Error(t, validateCoupon(&coupon))
+}
@@ -0,0 +1,85 @@
+package servicesimpl
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/repositories"
+	"beauty-ecommerce-backend/services"
+	"errors"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var _ services.CurrencyService = (*currencyServiceImpl)(nil)
+
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+type currencyServiceImpl struct {
+	currencyRepo *repositories.CurrencyRepository
+}
+
+func NewCurrencyService(currencyRepo *repositories.CurrencyRepository) *currencyServiceImpl {
+	return &currencyServiceImpl{currencyRepo: currencyRepo}
+}
+
+// -------------------- ADMIN --------------------
+func (s *currencyServiceImpl) GetRates() ([]models.ExchangeRate, error) {
+	return s.currencyRepo.FindAll()
+}
+
+func (s *currencyServiceImpl) SetRate(rate models.ExchangeRate) (*models.ExchangeRate, error) {
+	rate.Currency = normalizeCurrency(rate.Currency)
+	if !currencyCodePattern.MatchString(rate.Currency) {
+		return nil, errors.New("currency must be a 3-letter ISO code")
+	}
+	if rate.Currency == models.DefaultCurrency {
+		return nil, errors.New("prices are set in " + models.DefaultCurrency + ", it does not need a rate")
+	}
+	if rate.Rate <= 0 {
+		return nil, errors.New("rate must be greater than zero")
+	}
+	return s.currencyRepo.Upsert(&rate)
+}
+
+func (s *currencyServiceImpl) DeleteRate(currency string) error {
+	if err := s.currencyRepo.Delete(normalizeCurrency(currency)); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return services.ErrExchangeRateNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// -------------------- LOOKUP --------------------
+func (s *currencyServiceImpl) Currencies() ([]string, error) {
+	rates, err := s.currencyRepo.FindActive()
+	if err != nil {
+		return nil, err
+	}
+	currencies := []string{models.DefaultCurrency}
+	for _, rate := range rates {
+		currencies = append(currencies, rate.Currency)
+	}
+	return currencies, nil
+}
+
+func (s *currencyServiceImpl) Rate(currency string) (float64, error) {
+	currency = normalizeCurrency(currency)
+	if currency == "" || currency == models.DefaultCurrency {
+		return 1, nil
+	}
+	rate, err := s.currencyRepo.FindActiveByCurrency(currency)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, errors.New("unsupported currency " + currency)
+		}
+		return 0, err
+	}
+	return rate.Rate, nil
+}
+
+func normalizeCurrency(currency string) string {
+	return strings.ToUpper(strings.TrimSpace(currency))
+}
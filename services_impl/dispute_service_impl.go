@@ -0,0 +1,284 @@
+package servicesimpl
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/repositories"
+	"beauty-ecommerce-backend/services"
+	"beauty-ecommerce-backend/utils"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var _ services.DisputeService = (*disputeServiceImpl)(nil)
+
+type disputeServiceImpl struct {
+	disputeRepo *repositories.DisputeRepository
+	orderRepo   *repositories.OrderRepository
+	orders      *orderServiceImpl
+	payments    services.PaymentProvider
+	uow         *repositories.UnitOfWork
+}
+
+func NewDisputeService(disputeRepo *repositories.DisputeRepository, orderRepo *repositories.OrderRepository, orders *orderServiceImpl, payments services.PaymentProvider, uow *repositories.UnitOfWork) *disputeServiceImpl {
+	return &disputeServiceImpl{
+		disputeRepo: disputeRepo,
+		orderRepo:   orderRepo,
+		orders:      orders,
+		payments:    payments,
+		uow:         uow,
+	}
+}
+
+// disputeOrderStatus is where a dispute in the given status leaves the order,
+// and false when the order should stay as it is. Inquiries leave the order
+// alone; a chargeback holds it as disputed until the outcome, when a won
+// dispute puts it back where it was and a lost one refunds it.
+func disputeOrderStatus(order *models.Order, status models.DisputeStatus) (models.OrderStatus, bool) {
+	var to models.OrderStatus
+	switch {
+	case status.Won():
+		if order.Status != models.OrderDisputed {
+			return "", false
+		}
+		return preDisputeStatus(order), true
+	case status.Closed():
+		to = models.OrderRefunded
+	case status.Warning():
+		return "", false
+	default:
+		to = models.OrderDisputed
+	}
+
+	if order.Status == to || !order.Status.CanTransitionTo(to) {
+		return "", false
+	}
+	return to, true
+}
+
+// preDisputeStatus is the status the order had when the chargeback opened, or
+// paid when its history does not say
+func preDisputeStatus(order *models.Order) models.OrderStatus {
+	for i := len(order.StatusHistory) - 1; i >= 0; i-- {
+		if change := order.StatusHistory[i]; change.To == models.OrderDisputed {
+			if change.From.RestorableAfterDispute() {
+				return change.From
+			}
+			break
+		}
+	}
+	return models.OrderPaid
+}
+
+// -------------------- RECORD DISPUTE --------------------
+
+// RecordDispute stores the provider's latest view of a dispute. Its events can
+// arrive in any order, so whichever comes first creates the record, and a
+// closed dispute is not reopened by a late update.
+func (s *disputeServiceImpl) RecordDispute(event *services.PaymentEvent) (*models.Dispute, error) {
+	if event.Dispute == nil || event.Dispute.ID == "" {
+		return nil, fmt.Errorf("%w: dispute event has no dispute", services.ErrMalformedEvent)
+	}
+	ctx := context.Background()
+
+	// Disputes are raised against charges; orders know their payment intent
+	reference := event.PaymentReference
+	if reference == "" && event.ChargeID != "" {
+		resolved, err := s.payments.ChargePaymentReference(ctx, event.ChargeID)
+		if err != nil {
+			return nil, fmt.Errorf("could not find the payment for charge %s: %w", event.ChargeID, err)
+		}
+		reference = resolved
+	}
+
+	var orderID *primitive.ObjectID
+	if reference != "" {
+		if order, _, err := s.orders.findOrderByPayment(reference); err == nil {
+			orderID = &order.ID
+		}
+	}
+	if orderID == nil {
+		fmt.Println("⚠️ Dispute matches no order:", event.Dispute.ID, reference)
+	}
+
+	dispute, created, changed, err := s.save(ctx, event, reference, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if dispute.OrderID != nil && (created || changed) {
+		if err := s.applyToOrder(*dispute.OrderID, dispute); err != nil {
+			return nil, err
+		}
+	}
+
+	if created || (changed && dispute.Status.Closed()) {
+		go s.notifyAdmin(dispute, created)
+	}
+	return dispute, nil
+}
+
+// save creates or updates the dispute record, reporting whether it was new
+// and whether its status moved
+func (s *disputeServiceImpl) save(ctx context.Context, event *services.PaymentEvent, reference string, orderID *primitive.ObjectID) (*models.Dispute, bool, bool, error) {
+	incoming := event.Dispute
+	provider := s.payments.Name()
+	now := time.Now()
+
+	dispute, err := s.disputeRepo.FindByProviderID(ctx, provider, incoming.ID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		dispute = &models.Dispute{
+			ID:               primitive.NewObjectID(),
+			Provider:         provider,
+			ProviderID:       incoming.ID,
+			OrderID:          orderID,
+			PaymentReference: reference,
+			ChargeID:         event.ChargeID,
+			Amount:           incoming.Amount,
+			Reason:           incoming.Reason,
+			Status:           incoming.Status,
+			EvidenceDueBy:    incoming.EvidenceDueBy,
+			History:          []models.DisputeChange{{Status: incoming.Status, At: now}},
+			CreatedAt:        now,
+			UpdatedAt:        now,
+		}
+		if incoming.Status.Closed() {
+			dispute.ClosedAt = &now
+		}
+		if err := s.disputeRepo.Create(ctx, dispute); err != nil {
+			return nil, false, false, err
+		}
+		return dispute, true, false, nil
+	}
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	set := bson.M{"amount": incoming.Amount, "updated_at": now}
+	dispute.Amount, dispute.UpdatedAt = incoming.Amount, now
+	if incoming.Reason != "" {
+		set["reason"] = incoming.Reason
+		dispute.Reason = incoming.Reason
+	}
+	if incoming.EvidenceDueBy != nil {
+		set["evidence_due_by"] = incoming.EvidenceDueBy
+		dispute.EvidenceDueBy = incoming.EvidenceDueBy
+	}
+	if dispute.OrderID == nil && orderID != nil {
+		set["order_id"], set["payment_reference"] = orderID, reference
+		dispute.OrderID, dispute.PaymentReference = orderID, reference
+	}
+
+	var change *models.DisputeChange
+	changed := incoming.Status != dispute.Status && !dispute.Status.Closed()
+	if changed {
+		change = &models.DisputeChange{Status: incoming.Status, At: now}
+		set["status"] = incoming.Status
+		dispute.Status = incoming.Status
+		dispute.History = append(dispute.History, *change)
+		if incoming.Status.Closed() {
+			set["closed_at"] = now
+			dispute.ClosedAt = &now
+		}
+	}
+
+	if err := s.disputeRepo.Update(ctx, dispute.ID, set, change); err != nil {
+		return nil, false, false, err
+	}
+	return dispute, false, changed, nil
+}
+
+// applyToOrder moves the disputed order to match the dispute
+func (s *disputeServiceImpl) applyToOrder(orderID primitive.ObjectID, dispute *models.Dispute) error {
+	return s.uow.Do(context.Background(), func(ctx context.Context) error {
+		order, err := s.orderRepo.Get(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		to, ok := disputeOrderStatus(order, dispute.Status)
+		if !ok {
+			return nil
+		}
+		reason := fmt.Sprintf("dispute %s %s", dispute.ProviderID, dispute.Status)
+		if dispute.Status.Won() {
+			return s.orders.restoreAfterDispute(ctx, order, to, paymentActor(), reason)
+		}
+		return s.orders.changeStatus(ctx, order, to, paymentActor(), reason)
+	})
+}
+
+// -------------------- ADMIN --------------------
+
+// ListDisputes lists disputes with the nearest evidence deadline first
+func (s *disputeServiceImpl) ListDisputes(status models.DisputeStatus, openOnly bool) ([]models.Dispute, error) {
+	if status != "" && !status.Valid() {
+		return nil, fmt.Errorf("unknown dispute status %q", status)
+	}
+	disputes, err := s.disputeRepo.FindAll(status, openOnly)
+	if err != nil {
+		return nil, err
+	}
+	sortByEvidenceDeadline(disputes)
+	return disputes, nil
+}
+
+// sortByEvidenceDeadline puts the most urgent disputes first; those without a
+// deadline keep their order at the end
+func sortByEvidenceDeadline(disputes []models.Dispute) {
+	sort.SliceStable(disputes, func(i, j int) bool {
+		a, b := disputes[i].EvidenceDueBy, disputes[j].EvidenceDueBy
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return a.Before(*b)
+	})
+}
+
+func (s *disputeServiceImpl) GetDispute(id primitive.ObjectID) (*models.Dispute, error) {
+	dispute, err := s.disputeRepo.FindByID(id)
+	if err != nil {
+		return nil, errors.New("dispute not found")
+	}
+	return dispute, nil
+}
+
+// -------------------- NOTIFICATIONS --------------------
+func (s *disputeServiceImpl) notifyAdmin(dispute *models.Dispute, opened bool) {
+	adminEmail := os.Getenv("ADMIN_EMAIL")
+	if adminEmail == "" {
+		return
+	}
+
+	order := "no matching order"
+	if dispute.OrderID != nil {
+		order = dispute.OrderID.Hex()
+	}
+	deadline := "none"
+	if dispute.EvidenceDueBy != nil {
+		deadline = dispute.EvidenceDueBy.Format("02 Jan 2006 15:04 MST")
+	}
+
+	subject := fmt.Sprintf("⚠️ Payment Disputed - %s", order)
+	heading := "Payment Disputed"
+	if !opened {
+		subject = fmt.Sprintf("⚖️ Dispute %s - %s", dispute.Status, order)
+		heading = "Dispute Closed"
+	}
+	html := fmt.Sprintf(`
+		<h2>%s</h2>
+		<p><strong>Order ID:</strong> %s</p>
+		<p><strong>Dispute:</strong> %s</p>
+		<p><strong>Status:</strong> %s</p>
+		<p><strong>Reason:</strong> %s</p>
+		<p><strong>Amount:</strong> %s</p>
+		<p><strong>Evidence due by:</strong> %s</p>
+	`, heading, order, dispute.ProviderID, dispute.Status, dispute.Reason, dispute.Amount.Format(), deadline)
+	utils.QueueEmail(adminEmail, "Admin", subject, html)
+}
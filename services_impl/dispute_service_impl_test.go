@@ -0,0 +1,85 @@
+package servicesimpl
+
+import (
+	"testing"
+	"time"
+
+	"beauty-ecommerce-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisputeOrderStatus(t *testing.T) {
+	shipped := &models.Order{Status: models.OrderShipped}
+	disputed := &models.Order{
+		Status: models.OrderDisputed,
+		StatusHistory: []models.StatusChange{
+			{To: models.OrderPaid},
+			{From: models.OrderPaid, To: models.OrderShipped},
+			{From: models.OrderShipped, To: models.OrderDisputed},
+		},
+	}
+
+	cases := []struct {
+		name   string
+		order  *models.Order
+		status models.DisputeStatus
+		want   models.OrderStatus
+		moves  bool
+	}{
+		{"inquiry leaves the order alone", shipped, models.DisputeWarningNeedsResponse, "", false},
+		{"chargeback holds the order", shipped, models.DisputeNeedsResponse, models.OrderDisputed, true},
+		{"already held", disputed, models.DisputeUnderReview, "", false},
+		{"won goes back to where it was", disputed, models.DisputeWon, models.OrderShipped, true},
+		{"won inquiry changes nothing", shipped, models.DisputeWarningClosed, "", false},
+		{"lost is refunded", disputed, models.DisputeLost, models.OrderRefunded, true},
+		{"refunded charge is refunded", shipped, models.DisputeChargeRefunded, models.OrderRefunded, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			to, moves := disputeOrderStatus(tc.order, tc.status)
+			assert.Equal(t, tc.moves, moves)
+			assert.Equal(t, tc.want, to)
+		})
+	}
+
+	t.Run("won without history goes back to paid", func(t *testing.T) {
+		to, moves := disputeOrderStatus(&models.Order{Status: models.OrderDisputed}, models.DisputeWon)
+		assert.True(t, moves)
+		assert.Equal(t, models.OrderPaid, to)
+	})
+
+	t.Run("won before payment goes to paid", func(t *testing.T) {
+		unpaid := &models.Order{
+			Status:        models.OrderDisputed,
+			StatusHistory: []models.StatusChange{{From: models.OrderPending, To: models.OrderDisputed}},
+		}
+		to, _ := disputeOrderStatus(unpaid, models.DisputeWon)
+		assert.Equal(t, models.OrderPaid, to)
+	})
+
+	t.Run("only the dispute outcome moves a disputed order on", func(t *testing.T) {
+		assert.Error(t, models.CheckTransition(models.OrderDisputed, models.OrderShipped))
+		assert.Error(t, models.CheckTransition(models.OrderDisputed, models.OrderDelivered))
+		assert.True(t, models.OrderShipped.RestorableAfterDispute())
+		assert.False(t, models.OrderPending.RestorableAfterDispute())
+	})
+}
+
+func TestSortByEvidenceDeadline(t *testing.T) {
+	soon := time.Now().Add(24 * time.Hour)
+	later := soon.Add(48 * time.Hour)
+
+	disputes := []models.Dispute{
+		{ProviderID: "none"},
+		{ProviderID: "later", EvidenceDueBy: &later},
+		{ProviderID: "soon", EvidenceDueBy: &soon},
+	}
+	sortByEvidenceDeadline(disputes)
+
+	var order []string
+	for _, d := range disputes {
+		order = append(order, d.ProviderID)
+	}
+	assert.Equal(t, []string{"soon", "later", "none"}, order)
+}
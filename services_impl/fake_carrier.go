@@ -0,0 +1,87 @@
+package servicesimpl
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/services"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var _ services.Carrier = (*FakeCarrier)(nil)
+
+// FakeCarrierCode is the code the fake carrier registers under
+const FakeCarrierCode = "fake"
+
+// FakeCarrier is an in-memory carrier for tests and local development. Labels
+// get sequential tracking numbers and parcels stay in label_created until
+// Advance moves them on.
+type FakeCarrier struct {
+	mu      sync.Mutex
+	next    int
+	parcels map[string]*services.TrackingUpdate
+}
+
+func NewFakeCarrier() *FakeCarrier {
+	return &FakeCarrier{parcels: map[string]*services.TrackingUpdate{}}
+}
+
+func (c *FakeCarrier) Code() string {
+	return FakeCarrierCode
+}
+
+func (c *FakeCarrier) CreateLabel(ctx context.Context, order *models.Order, shipment *models.Shipment) (*services.CarrierLabel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.next++
+	trackingNumber := fmt.Sprintf("FAKE%08d", c.next)
+	c.parcels[trackingNumber] = &services.TrackingUpdate{
+		Status: models.ShipmentLabelCreated,
+		Events: []models.TrackingEvent{{
+			Status:      models.ShipmentLabelCreated,
+			Description: "Label created",
+			At:          time.Now(),
+		}},
+	}
+
+	return &services.CarrierLabel{
+		TrackingNumber: trackingNumber,
+		TrackingURL:    "https://tracking.example.com/" + trackingNumber,
+		LabelURL:       "https://labels.example.com/" + trackingNumber + ".pdf",
+	}, nil
+}
+
+func (c *FakeCarrier) Track(ctx context.Context, trackingNumber string) (*services.TrackingUpdate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	parcel, ok := c.parcels[trackingNumber]
+	if !ok {
+		return nil, errors.New("unknown tracking number")
+	}
+
+	update := &services.TrackingUpdate{Status: parcel.Status}
+	update.Events = append(update.Events, parcel.Events...)
+	return update, nil
+}
+
+// Advance records a new scan for a parcel, as the real carrier would
+func (c *FakeCarrier) Advance(trackingNumber string, status models.ShipmentStatus, description string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	parcel, ok := c.parcels[trackingNumber]
+	if !ok {
+		return errors.New("unknown tracking number")
+	}
+	parcel.Status = status
+	parcel.Events = append(parcel.Events, models.TrackingEvent{
+		Status:      status,
+		Description: description,
+		At:          time.Now(),
+	})
+	return nil
+}
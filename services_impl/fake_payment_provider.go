@@ -0,0 +1,550 @@
+package servicesimpl
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/services"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ services.PaymentProvider = (*FakePaymentProvider)(nil)
+
+// FakePaymentProviderName is the name the fake provider records events under
+const FakePaymentProviderName = "fake"
+
+// FakeSignatureHeader carries the fake provider's webhook signature
+const FakeSignatureHeader = "Fake-Signature"
+
+// Fake intent statuses, named after Stripe's
+const (
+	fakeIntentRequiresPayment = "requires_payment_method"
+	fakeIntentRequiresCapture = "requires_capture"
+	fakeIntentProcessing      = "processing"
+	fakeIntentSucceeded       = "succeeded"
+)
+
+// FakePaymentProvider keeps intents and refunds in memory so payment flows
+// run without network access. Payments are settled with Succeed, Fail and,
+// for hosted checkouts, CompleteCheckout and ExpireCheckout, which return the
+// webhook delivery the real provider would send; post it to the webhook
+// endpoint or hand it to VerifyWebhook. Saved cards are added with
+// CompleteSetup.
+type FakePaymentProvider struct {
+	mu        sync.Mutex
+	secret    []byte
+	seq       int
+	intents   map[string]*fakeIntent
+	checkouts map[string]*fakeCheckout
+	refunds   map[string]*services.ProviderRefund // by idempotency key
+	customers map[string][]services.PaymentMethod // saved cards by customer
+	setups    map[string]string                   // customer by setup intent
+	keys      map[string]string                   // customer or intent by idempotency key
+}
+
+type fakeIntent struct {
+	intent     services.PaymentIntent
+	customerID string
+	chargeID   string // set once the payment succeeds
+	captured   bool
+	refunded   int64
+	metadata   map[string]string
+}
+
+type fakeCheckout struct {
+	params services.CheckoutParams
+	closed bool
+}
+
+// fakeEnvelope is the body of a fake webhook delivery
+type fakeEnvelope struct {
+	ID   string          `json:"id"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+func NewFakePaymentProvider(secret string) *FakePaymentProvider {
+	return &FakePaymentProvider{
+		secret:    []byte(secret),
+		intents:   map[string]*fakeIntent{},
+		checkouts: map[string]*fakeCheckout{},
+		refunds:   map[string]*services.ProviderRefund{},
+		customers: map[string][]services.PaymentMethod{},
+		setups:    map[string]string{},
+		keys:      map[string]string{},
+	}
+}
+
+func (p *FakePaymentProvider) Name() string {
+	return FakePaymentProviderName
+}
+
+// nextID hands out sequential IDs; callers hold p.mu
+func (p *FakePaymentProvider) nextID(prefix string) string {
+	p.seq++
+	return fmt.Sprintf("%s_fake_%08d", prefix, p.seq)
+}
+
+// -------------------- INTENTS --------------------
+
+func (p *FakePaymentProvider) CreateIntent(ctx context.Context, req services.IntentParams) (*services.PaymentIntent, error) {
+	if !req.Amount.IsPositive() {
+		return nil, errors.New("amount must be positive")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Like the provider, a repeated key returns the intent it first created
+	if id, ok := p.keys[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		existing := p.intents[id].intent
+		return &existing, nil
+	}
+	intent := p.createIntent(req)
+	if req.IdempotencyKey != "" {
+		p.keys[req.IdempotencyKey] = intent.ID
+	}
+	return intent, nil
+}
+
+// createIntent records a new intent; callers hold p.mu
+func (p *FakePaymentProvider) createIntent(req services.IntentParams) *services.PaymentIntent {
+	id := p.nextID("pi")
+	intent := &fakeIntent{
+		intent: services.PaymentIntent{
+			ID:           id,
+			ClientSecret: id + "_secret",
+			Status:       fakeIntentRequiresPayment,
+			Amount:       req.Amount,
+		},
+		customerID: req.CustomerID,
+		captured:   !req.ManualCapture,
+		metadata:   req.Metadata,
+	}
+	p.intents[id] = intent
+
+	created := intent.intent
+	return &created
+}
+
+// ConfirmIntent leaves the intent processing; settle it with Succeed or Fail
+func (p *FakePaymentProvider) ConfirmIntent(ctx context.Context, intentID, paymentMethodID string) (*services.PaymentIntent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return nil, fmt.Errorf("no such payment intent: %s", intentID)
+	}
+	if !p.ownsMethod(intent.customerID, paymentMethodID) {
+		return nil, fmt.Errorf("payment method %s does not belong to the intent's customer", paymentMethodID)
+	}
+	if intent.intent.Status != fakeIntentRequiresPayment {
+		return nil, fmt.Errorf("payment intent %s is %s and cannot be confirmed", intentID, intent.intent.Status)
+	}
+	intent.intent.Status = fakeIntentProcessing
+
+	confirmed := intent.intent
+	return &confirmed, nil
+}
+
+func (p *FakePaymentProvider) CaptureIntent(ctx context.Context, intentID string) (*services.PaymentIntent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return nil, fmt.Errorf("no such payment intent: %s", intentID)
+	}
+	if intent.intent.Status != fakeIntentRequiresCapture {
+		return nil, fmt.Errorf("payment intent %s is %s and cannot be captured", intentID, intent.intent.Status)
+	}
+	intent.settle()
+	intent.captured = true
+
+	captured := intent.intent
+	return &captured, nil
+}
+
+func (p *FakePaymentProvider) GetIntent(ctx context.Context, intentID string) (*services.PaymentIntent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return nil, fmt.Errorf("no such payment intent: %s", intentID)
+	}
+	found := intent.intent
+	return &found, nil
+}
+
+// settle marks the intent paid and gives it a charge
+func (i *fakeIntent) settle() {
+	i.intent.Status = fakeIntentSucceeded
+	i.chargeID = "ch" + strings.TrimPrefix(i.intent.ID, "pi")
+}
+
+// ChargeID is the charge a paid intent produced, as named in dispute events
+func (p *FakePaymentProvider) ChargeID(intentID string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if intent, ok := p.intents[intentID]; ok {
+		return intent.chargeID
+	}
+	return ""
+}
+
+func (p *FakePaymentProvider) ChargePaymentReference(ctx context.Context, chargeID string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for id, intent := range p.intents {
+		if intent.chargeID != "" && intent.chargeID == chargeID {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("no such charge: %s", chargeID)
+}
+
+// -------------------- CHECKOUT --------------------
+
+func (p *FakePaymentProvider) CreateCheckoutSession(ctx context.Context, req services.CheckoutParams) (*services.CheckoutSession, error) {
+	if len(req.Lines) == 0 {
+		return nil, errors.New("checkout needs at least one line")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := p.nextID("cs")
+	p.checkouts[id] = &fakeCheckout{params: req}
+	return &services.CheckoutSession{ID: id, URL: "https://checkout.fake/pay/" + id}, nil
+}
+
+// CheckoutTotal is what the hosted page charges: the lines, shipping and
+// tax less the discount
+func (p *FakePaymentProvider) CheckoutTotal(sessionID string) (models.Money, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	checkout, ok := p.checkouts[sessionID]
+	if !ok {
+		return models.Money{}, false
+	}
+	return checkoutTotal(checkout.params), true
+}
+
+func checkoutTotal(params services.CheckoutParams) models.Money {
+	total := params.Shipping
+	if total.Currency == "" {
+		total.Currency = params.Lines[0].UnitPrice.Currency
+	}
+	for _, line := range params.Lines {
+		total = total.Add(line.UnitPrice.Mul(line.Quantity))
+	}
+	return total.Sub(params.Discount)
+}
+
+// CompleteCheckout has the customer pay on the hosted page and returns the
+// checkout_completed delivery
+func (p *FakePaymentProvider) CompleteCheckout(sessionID string) ([]byte, http.Header, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	checkout, ok := p.checkouts[sessionID]
+	if !ok {
+		return nil, nil, fmt.Errorf("no such checkout session: %s", sessionID)
+	}
+	if checkout.closed {
+		return nil, nil, fmt.Errorf("checkout session %s is closed", sessionID)
+	}
+
+	intent := p.createIntent(services.IntentParams{Amount: checkoutTotal(checkout.params), Metadata: checkout.params.Metadata})
+	p.intents[intent.ID].settle()
+	checkout.closed = true
+
+	return p.delivery(services.PaymentEvent{
+		Kind:              services.CheckoutCompleted,
+		PaymentReference:  intent.ID,
+		CheckoutSessionID: sessionID,
+		Paid:              true,
+	})
+}
+
+// ExpireCheckout closes the hosted page unpaid and returns the
+// checkout_expired delivery
+func (p *FakePaymentProvider) ExpireCheckout(sessionID string) ([]byte, http.Header, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	checkout, ok := p.checkouts[sessionID]
+	if !ok {
+		return nil, nil, fmt.Errorf("no such checkout session: %s", sessionID)
+	}
+	if checkout.closed {
+		return nil, nil, fmt.Errorf("checkout session %s is closed", sessionID)
+	}
+	checkout.closed = true
+
+	return p.delivery(services.PaymentEvent{Kind: services.CheckoutExpired, CheckoutSessionID: sessionID})
+}
+
+// -------------------- CUSTOMERS --------------------
+
+func (p *FakePaymentProvider) CreateCustomer(ctx context.Context, req services.CustomerParams) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if id, ok := p.keys[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		return id, nil
+	}
+	id := p.nextID("cus")
+	p.customers[id] = []services.PaymentMethod{}
+	if req.IdempotencyKey != "" {
+		p.keys[req.IdempotencyKey] = id
+	}
+	return id, nil
+}
+
+func (p *FakePaymentProvider) CreateSetupIntent(ctx context.Context, customerID string) (*services.SetupIntent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.customers[customerID]; !ok {
+		return nil, fmt.Errorf("no such customer: %s", customerID)
+	}
+	id := p.nextID("seti")
+	p.setups[id] = customerID
+	return &services.SetupIntent{ID: id, ClientSecret: id + "_secret", Status: fakeIntentRequiresPayment}, nil
+}
+
+// CompleteSetup has the customer enter a card for the setup intent and
+// saves it to them
+func (p *FakePaymentProvider) CompleteSetup(setupIntentID, brand, last4 string) (*services.PaymentMethod, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	customerID, ok := p.setups[setupIntentID]
+	if !ok {
+		return nil, fmt.Errorf("no such setup intent: %s", setupIntentID)
+	}
+	delete(p.setups, setupIntentID)
+
+	method := services.PaymentMethod{
+		ID:       p.nextID("pm"),
+		Brand:    brand,
+		Last4:    last4,
+		ExpMonth: 12,
+		ExpYear:  time.Now().Year() + 3,
+	}
+	p.customers[customerID] = append(p.customers[customerID], method)
+	return &method, nil
+}
+
+func (p *FakePaymentProvider) ListPaymentMethods(ctx context.Context, customerID string) ([]services.PaymentMethod, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	methods, ok := p.customers[customerID]
+	if !ok {
+		return nil, fmt.Errorf("no such customer: %s", customerID)
+	}
+	return append([]services.PaymentMethod{}, methods...), nil
+}
+
+func (p *FakePaymentProvider) DetachPaymentMethod(ctx context.Context, customerID, paymentMethodID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	methods := p.customers[customerID]
+	for i, method := range methods {
+		if method.ID == paymentMethodID {
+			p.customers[customerID] = append(methods[:i:i], methods[i+1:]...)
+			return nil
+		}
+	}
+	return services.ErrPaymentMethodNotFound
+}
+
+// ownsMethod reports whether the method is saved to the customer; callers
+// hold p.mu
+func (p *FakePaymentProvider) ownsMethod(customerID, paymentMethodID string) bool {
+	for _, method := range p.customers[customerID] {
+		if method.ID == paymentMethodID {
+			return true
+		}
+	}
+	return false
+}
+
+// -------------------- REFUNDS --------------------
+
+func (p *FakePaymentProvider) Refund(ctx context.Context, req services.RefundParams) (*services.ProviderRefund, error) {
+	if req.PaymentReference == "" {
+		return nil, errors.New("order has no payment to refund")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if req.IdempotencyKey != "" {
+		if refund, ok := p.refunds[req.IdempotencyKey]; ok {
+			return refund, nil
+		}
+	}
+
+	intent, ok := p.intents[req.PaymentReference]
+	if !ok {
+		return nil, fmt.Errorf("no such payment intent: %s", req.PaymentReference)
+	}
+	if intent.intent.Status != fakeIntentSucceeded || !intent.captured {
+		return nil, fmt.Errorf("payment intent %s has not been paid", req.PaymentReference)
+	}
+	if req.Amount.Currency != intent.intent.Amount.Currency {
+		return nil, fmt.Errorf("refund currency %s does not match payment currency %s", req.Amount.Currency, intent.intent.Amount.Currency)
+	}
+	if !req.Amount.IsPositive() || intent.refunded+req.Amount.Amount > intent.intent.Amount.Amount {
+		return nil, fmt.Errorf("refund of %s is more than is left on the payment", req.Amount.Format())
+	}
+
+	intent.refunded += req.Amount.Amount
+	refund := &services.ProviderRefund{ID: p.nextID("re"), Status: "succeeded"}
+	if req.IdempotencyKey != "" {
+		p.refunds[req.IdempotencyKey] = refund
+	}
+	return refund, nil
+}
+
+// Refunded is how much of the intent has been refunded, for assertions in tests
+func (p *FakePaymentProvider) Refunded(intentID string) models.Money {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return models.Money{}
+	}
+	return models.Money{Amount: intent.refunded, Currency: intent.intent.Amount.Currency}
+}
+
+// -------------------- SETTLEMENT --------------------
+
+// Succeed completes the customer's payment, or authorises it when the intent
+// uses manual capture, and returns the payment_succeeded delivery
+func (p *FakePaymentProvider) Succeed(intentID string) ([]byte, http.Header, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return nil, nil, fmt.Errorf("no such payment intent: %s", intentID)
+	}
+	if intent.captured {
+		intent.settle()
+	} else {
+		intent.intent.Status = fakeIntentRequiresCapture
+	}
+	return p.delivery(services.PaymentEvent{Kind: services.PaymentSucceeded, PaymentReference: intentID})
+}
+
+// Fail declines the customer's payment and returns the payment_failed
+// delivery. The intent can be paid again, as with a declined card.
+func (p *FakePaymentProvider) Fail(intentID string) ([]byte, http.Header, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return nil, nil, fmt.Errorf("no such payment intent: %s", intentID)
+	}
+	intent.intent.Status = fakeIntentRequiresPayment
+	return p.delivery(services.PaymentEvent{
+		Kind:             services.PaymentFailed,
+		PaymentReference: intentID,
+		ErrorCode:        "card_declined",
+		ErrorMessage:     "Your card was declined.",
+	})
+}
+
+// Deliver builds a signed delivery for any event, e.g. a dispute
+func (p *FakePaymentProvider) Deliver(event services.PaymentEvent) ([]byte, http.Header, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.delivery(event)
+}
+
+// delivery signs an event the way VerifyWebhook expects; callers hold p.mu
+func (p *FakePaymentProvider) delivery(event services.PaymentEvent) ([]byte, http.Header, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, err
+	}
+	payload, err := json.Marshal(fakeEnvelope{ID: p.nextID("evt"), Type: event.Kind, Data: data})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	headers := http.Header{}
+	headers.Set(FakeSignatureHeader, p.sign(payload))
+	return payload, headers, nil
+}
+
+func (p *FakePaymentProvider) sign(payload []byte) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// -------------------- WEBHOOKS --------------------
+
+// VerifyWebhook refuses every delivery without a secret, as anyone could sign
+// with an empty key
+func (p *FakePaymentProvider) VerifyWebhook(payload []byte, headers http.Header) (*services.WebhookDelivery, error) {
+	if len(p.secret) == 0 {
+		return nil, errors.New("webhook secret not configured")
+	}
+
+	signature, err := hex.DecodeString(headers.Get(FakeSignatureHeader))
+	if err != nil || len(signature) == 0 {
+		return nil, errors.New("missing or malformed signature")
+	}
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, errors.New("signature does not match payload")
+	}
+
+	var envelope fakeEnvelope
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid event: %w", err)
+	}
+	return &services.WebhookDelivery{ID: envelope.ID, Type: envelope.Type, Data: envelope.Data}, nil
+}
+
+// DecodeEvent reads the events built by delivery, whose type is the Kind
+func (p *FakePaymentProvider) DecodeEvent(eventType string, data []byte) (*services.PaymentEvent, error) {
+	switch eventType {
+	case services.PaymentSucceeded, services.PaymentFailed, services.PaymentRefunded,
+		services.PaymentPartiallyRefunded, services.PaymentDisputed,
+		services.DisputeUpdated, services.DisputeClosed,
+		services.CheckoutCompleted, services.CheckoutExpired:
+	default:
+		return &services.PaymentEvent{}, nil
+	}
+
+	var event services.PaymentEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse event: %w", services.ErrMalformedEvent, err)
+	}
+	event.Kind = eventType
+	return &event, nil
+}
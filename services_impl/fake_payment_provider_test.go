@@ -0,0 +1,163 @@
+package servicesimpl
+
+import (
+	"context"
+	"testing"
+
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakePaymentProvider(t *testing.T) {
+	ctx := context.Background()
+	provider := NewFakePaymentProvider("whsec_test")
+
+	intent, err := provider.CreateIntent(ctx, services.IntentParams{Amount: models.GBP(2500)})
+	require.NoError(t, err)
+	assert.NotEmpty(t, intent.ClientSecret)
+
+	t.Run("repeated intent keys return the same intent", func(t *testing.T) {
+		params := services.IntentParams{Amount: models.GBP(2500), IdempotencyKey: "payment-1"}
+		first, err := provider.CreateIntent(ctx, params)
+		require.NoError(t, err)
+		retried, err := provider.CreateIntent(ctx, params)
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, retried.ID)
+	})
+
+	t.Run("refund before payment", func(t *testing.T) {
+		_, err := provider.Refund(ctx, services.RefundParams{PaymentReference: intent.ID, Amount: models.GBP(100)})
+		assert.Error(t, err)
+	})
+
+	t.Run("payment webhook round trip", func(t *testing.T) {
+		payload, headers, err := provider.Succeed(intent.ID)
+		require.NoError(t, err)
+
+		delivery, err := provider.VerifyWebhook(payload, headers)
+		require.NoError(t, err)
+		assert.NotEmpty(t, delivery.ID)
+
+		event, err := provider.DecodeEvent(delivery.Type, delivery.Data)
+		require.NoError(t, err)
+		assert.Equal(t, services.PaymentSucceeded, event.Kind)
+		assert.Equal(t, intent.ID, event.PaymentReference)
+	})
+
+	t.Run("webhooks need a secret", func(t *testing.T) {
+		unsigned := NewFakePaymentProvider("")
+		held, err := unsigned.CreateIntent(ctx, services.IntentParams{Amount: models.GBP(100)})
+		require.NoError(t, err)
+		payload, headers, err := unsigned.Succeed(held.ID)
+		require.NoError(t, err)
+		_, err = unsigned.VerifyWebhook(payload, headers)
+		assert.Error(t, err)
+	})
+
+	t.Run("tampered webhook", func(t *testing.T) {
+		payload, headers, err := provider.Fail(intent.ID)
+		require.NoError(t, err)
+		payload[len(payload)-2] = ' '
+		_, err = provider.VerifyWebhook(payload, headers)
+		assert.Error(t, err)
+	})
+
+	t.Run("refunds stop at the amount paid", func(t *testing.T) {
+		provider.Succeed(intent.ID)
+
+		first, err := provider.Refund(ctx, services.RefundParams{PaymentReference: intent.ID, Amount: models.GBP(1500), IdempotencyKey: "refund-1"})
+		require.NoError(t, err)
+
+		retried, err := provider.Refund(ctx, services.RefundParams{PaymentReference: intent.ID, Amount: models.GBP(1500), IdempotencyKey: "refund-1"})
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, retried.ID)
+
+		_, err = provider.Refund(ctx, services.RefundParams{PaymentReference: intent.ID, Amount: models.GBP(1500), IdempotencyKey: "refund-2"})
+		assert.Error(t, err)
+		assert.Equal(t, models.GBP(1500), provider.Refunded(intent.ID))
+	})
+
+	t.Run("manual capture", func(t *testing.T) {
+		held, err := provider.CreateIntent(ctx, services.IntentParams{Amount: models.GBP(900), ManualCapture: true})
+		require.NoError(t, err)
+		_, _, err = provider.Succeed(held.ID)
+		require.NoError(t, err)
+
+		captured, err := provider.CaptureIntent(ctx, held.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "succeeded", captured.Status)
+
+		_, err = provider.CaptureIntent(ctx, held.ID)
+		assert.Error(t, err)
+	})
+
+	t.Run("declined intents can be retried", func(t *testing.T) {
+		declined, err := provider.CreateIntent(ctx, services.IntentParams{Amount: models.GBP(700)})
+		require.NoError(t, err)
+		payload, headers, err := provider.Fail(declined.ID)
+		require.NoError(t, err)
+
+		delivery, err := provider.VerifyWebhook(payload, headers)
+		require.NoError(t, err)
+		event, err := provider.DecodeEvent(delivery.Type, delivery.Data)
+		require.NoError(t, err)
+		assert.Equal(t, services.PaymentFailed, event.Kind)
+		assert.NotEmpty(t, event.ErrorCode)
+
+		current, err := provider.GetIntent(ctx, declined.ID)
+		require.NoError(t, err)
+		assert.True(t, current.Reusable())
+
+		provider.Succeed(declined.ID)
+		current, err = provider.GetIntent(ctx, declined.ID)
+		require.NoError(t, err)
+		assert.False(t, current.Reusable())
+	})
+
+	t.Run("saved payment methods", func(t *testing.T) {
+		customer, err := provider.CreateCustomer(ctx, services.CustomerParams{Email: "ada@example.com", IdempotencyKey: "customer-1"})
+		require.NoError(t, err)
+		again, err := provider.CreateCustomer(ctx, services.CustomerParams{Email: "ada@example.com", IdempotencyKey: "customer-1"})
+		require.NoError(t, err)
+		assert.Equal(t, customer, again)
+
+		setup, err := provider.CreateSetupIntent(ctx, customer)
+		require.NoError(t, err)
+		card, err := provider.CompleteSetup(setup.ID, "visa", "4242")
+		require.NoError(t, err)
+
+		methods, err := provider.ListPaymentMethods(ctx, customer)
+		require.NoError(t, err)
+		require.Len(t, methods, 1)
+		assert.Equal(t, "4242", methods[0].Last4)
+
+		other, err := provider.CreateCustomer(ctx, services.CustomerParams{Email: "bob@example.com"})
+		require.NoError(t, err)
+		assert.Error(t, provider.DetachPaymentMethod(ctx, other, card.ID))
+
+		owned, err := provider.CreateIntent(ctx, services.IntentParams{Amount: models.GBP(1200), CustomerID: customer})
+		require.NoError(t, err)
+		stranger, err := provider.CreateIntent(ctx, services.IntentParams{Amount: models.GBP(1200), CustomerID: other})
+		require.NoError(t, err)
+		_, err = provider.ConfirmIntent(ctx, stranger.ID, card.ID)
+		assert.Error(t, err)
+
+		confirmed, err := provider.ConfirmIntent(ctx, owned.ID, card.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "processing", confirmed.Status)
+
+		require.NoError(t, provider.DetachPaymentMethod(ctx, customer, card.ID))
+		methods, err = provider.ListPaymentMethods(ctx, customer)
+		require.NoError(t, err)
+		assert.Empty(t, methods)
+	})
+
+	t.Run("unknown event types are not acted on", func(t *testing.T) {
+		event, err := provider.DecodeEvent("customer.created", []byte(`{}`))
+		require.NoError(t, err)
+		assert.Empty(t, event.Kind)
+	})
+}
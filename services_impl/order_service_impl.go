@@ -8,12 +8,15 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"beauty-ecommerce-backend/services"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // Ensure at compile-time that orderServiceImpl implements services.OrderService
@@ -23,14 +26,30 @@ type orderServiceImpl struct {
 	orderRepo   *repositories.OrderRepository
 	productRepo *repositories.ProductRepository
 	userRepo    *repositories.UserRepository
+	cartRepo    *repositories.CartRepository
+	attempts    *repositories.PaymentAttemptRepository
+	coupons     services.CouponService
+	shipping    services.ShippingService
+	taxes       services.TaxService
+	currencies  services.CurrencyService
+	payments    services.PaymentProvider
+	uow         *repositories.UnitOfWork
 }
 
 // Constructor
-func NewOrderService(orderRepo *repositories.OrderRepository, productRepo *repositories.ProductRepository, userRepo *repositories.UserRepository) *orderServiceImpl {
+func NewOrderService(orderRepo *repositories.OrderRepository, productRepo *repositories.ProductRepository, userRepo *repositories.UserRepository, cartRepo *repositories.CartRepository, attempts *repositories.PaymentAttemptRepository, coupons services.CouponService, shipping services.ShippingService, taxes services.TaxService, currencies services.CurrencyService, payments services.PaymentProvider, uow *repositories.UnitOfWork) *orderServiceImpl {
 	return &orderServiceImpl{
 		orderRepo:   orderRepo,
 		productRepo: productRepo,
 		userRepo:    userRepo,
+		cartRepo:    cartRepo,
+		attempts:    attempts,
+		coupons:     coupons,
+		shipping:    shipping,
+		taxes:       taxes,
+		currencies:  currencies,
+		payments:    payments,
+		uow:         uow,
 	}
 }
 
@@ -39,45 +58,128 @@ func (s *orderServiceImpl) CreateOrder(order models.Order) (models.Order, error)
 		return order, errors.New("order must contain at least one item")
 	}
 
-	var subtotal float64
+	// The currency and its rate are locked in here; the catalog, shipping zones
+	// and coupons are priced in DefaultCurrency and converted line by line
+	currency := normalizeCurrency(order.Currency)
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	rate, err := s.currencies.Rate(currency)
+	if err != nil {
+		return order, err
+	}
+	order.Currency = currency
+	order.ExchangeRate = rate
+
+	subtotal := models.Money{Currency: currency}
+	baseSubtotal := models.Money{Currency: models.DefaultCurrency}
+	var weightGrams int
+	requested := map[string]int{}
+	categories := map[string]string{}
 
 	for i, item := range order.Items {
+		if item.Quantity <= 0 {
+			return order, services.ErrInvalidQuantity
+		}
+
 		productID, err := primitive.ObjectIDFromHex(item.ProductID)
 		if err != nil {
 			return order, errors.New("invalid product ID")
 		}
 
+		variantID := primitive.NilObjectID
+		if item.VariantID != "" {
+			variantID, err = primitive.ObjectIDFromHex(item.VariantID)
+			if err != nil {
+				return order, errors.New("invalid variant ID")
+			}
+		}
+
 		product, err := s.productRepo.FindByID(productID)
 		if err != nil {
-			return order, errors.New("product not found")
+			return order, services.ErrProductNotFound
 		}
 
-		if item.Quantity > product.Stock {
-			return order, fmt.Errorf("only %d left of %s", product.Stock, product.Name)
+		variant, err := product.ResolveVariant(variantID)
+		if err != nil {
+			return order, fmt.Errorf("%s: %w", product.Name, err)
 		}
 
+		// The same product/variant may appear on several lines
+		key := item.ProductID + ":" + item.VariantID
+		requested[key] += item.Quantity
+		if available := product.AvailableStock(variant); requested[key] > available {
+			return order, fmt.Errorf("%w: only %d left of %s", repositories.ErrInsufficientStock, available, product.Name)
+		}
+
+		categories[item.ProductID] = product.Category
+		order.Items[i].Category = product.Category
 		order.Items[i].ProductName = product.Name
-		order.Items[i].Price = product.Price
-		subtotal += product.Price * float64(item.Quantity)
+		order.Items[i].Price = product.UnitPrice(variant).Convert(currency, rate)
+		if variant != nil {
+			order.Items[i].SKU = variant.SKU
+			order.Items[i].VariantLabel = variant.Label()
+		}
+		subtotal = subtotal.Add(order.Items[i].Price.Mul(item.Quantity))
+		baseSubtotal = baseSubtotal.Add(product.UnitPrice(variant).Mul(item.Quantity))
+		weightGrams += product.WeightGrams * item.Quantity
 	}
 
 	order.Subtotal = subtotal
 
-	switch order.DeliveryType {
-	case "express":
-		order.ShippingFee = 4.99
-	default:
-		order.DeliveryType = "standard"
-		order.ShippingFee = 3.99
+	quotes, err := s.shipping.QuoteShipping(order.ShippingAddress, models.ShippingParcel{
+		Subtotal:    baseSubtotal,
+		WeightGrams: weightGrams,
+	})
+	if err != nil {
+		return order, err
+	}
+	quote, err := pickShippingQuote(quotes, order.DeliveryType)
+	if err != nil {
+		return order, err
+	}
+	order.DeliveryType = quote.Code
+	order.ShippingFee = quote.Price.Convert(currency, rate)
+
+	// Discounts are only ever worked out here, never taken from the request
+	if err := s.coupons.ApplyCoupon(&order, categories); err != nil {
+		return order, err
+	}
+
+	if err := s.taxes.CalculateTax(&order); err != nil {
+		return order, err
 	}
 
-	order.TotalPrice = order.Subtotal + order.ShippingFee
-	order.Status = "pending"
+	// Inclusive tax (UK VAT) is already in the prices; only exclusive tax is added
+	order.TotalPrice = order.Subtotal.Add(order.ShippingFee).Sub(order.DiscountTotal).Add(order.ExclusiveTax())
+	order.Status = models.OrderPending
 	order.ID = primitive.NewObjectID()
 	order.CreatedAt = time.Now()
 	order.UpdatedAt = time.Now()
+	order.StatusHistory = []models.StatusChange{{
+		To:      models.OrderPending,
+		Actor:   models.ActorCustomer,
+		ActorID: order.UserID.Hex(),
+		Reason:  "order placed",
+		At:      order.CreatedAt,
+	}}
+
+	order.Reservation = &models.StockReservation{
+		Status:    models.ReservationHeld,
+		ExpiresAt: time.Now().Add(reservationTTL()),
+	}
 
-	if err := s.orderRepo.CreateOrder(context.Background(), &order); err != nil {
+	// Stock holds and the order document are written together or not at all
+	err = s.uow.Do(context.Background(), func(ctx context.Context) error {
+		if err := s.reserveItems(ctx, order.Items); err != nil {
+			return err
+		}
+		if err := s.coupons.RedeemCoupon(ctx, &order); err != nil {
+			return err
+		}
+		return s.orderRepo.CreateOrder(ctx, &order)
+	})
+	if err != nil {
 		return order, err
 	}
 
@@ -88,116 +190,480 @@ func (s *orderServiceImpl) CreateOrder(order models.Order) (models.Order, error)
 	return order, nil
 }
 
-// -------------------- MARK ORDER AS PAID --------------------
-func (s *orderServiceImpl) MarkOrderAsPaid(paymentReference string) error {
-	order, err := s.orderRepo.FindByReference(paymentReference)
+// -------------------- CHECKOUT CART --------------------
+
+// CheckoutCart turns the user's server-side cart into an order. Lines are
+// re-priced and re-checked against the catalog; anything that changed since it
+// was added is reported back. Ordered lines leave the cart only once the order
+// has been persisted.
+func (s *orderServiceImpl) CheckoutCart(userID primitive.ObjectID, details models.Order) (*models.Order, []models.CartChange, error) {
+	ctx := context.Background()
+
+	cartItems, err := s.cartRepo.GetUserCart(ctx, userID)
 	if err != nil {
-		return errors.New("order not found for this payment reference")
+		return nil, nil, err
+	}
+	if len(cartItems) == 0 {
+		return nil, nil, errors.New("your cart is empty")
 	}
 
-	if order.Status == "paid" {
-		return nil
+	changes := []models.CartChange{}
+	var items []models.OrderItem
+	var orderedLines []primitive.ObjectID
+
+	for _, line := range cartItems {
+		change := models.CartChange{ProductID: line.ProductID.Hex()}
+		item := models.OrderItem{ProductID: line.ProductID.Hex(), Quantity: line.Quantity}
+		if !line.VariantID.IsZero() {
+			change.VariantID = line.VariantID.Hex()
+			item.VariantID = line.VariantID.Hex()
+		}
+
+		product, err := s.productRepo.FindByID(line.ProductID)
+		if err != nil {
+			change.Reason = models.CartChangeUnavailable
+			changes = append(changes, change)
+			continue
+		}
+		change.ProductName = product.Name
+
+		variant, err := product.ResolveVariant(line.VariantID)
+		if err != nil {
+			change.Reason = models.CartChangeUnavailable
+			changes = append(changes, change)
+			continue
+		}
+
+		available := product.AvailableStock(variant)
+		if available <= 0 {
+			change.Reason = models.CartChangeOutOfStock
+			change.Requested = line.Quantity
+			changes = append(changes, change)
+			continue
+		}
+		if line.Quantity > available {
+			reduced := change
+			reduced.Reason = models.CartChangeQuantityReduced
+			reduced.Requested = line.Quantity
+			reduced.Available = available
+			changes = append(changes, reduced)
+			item.Quantity = available
+		}
+
+		if price := product.UnitPrice(variant); line.UnitPrice != price {
+			oldPrice := line.UnitPrice
+			repriced := change
+			repriced.Reason = models.CartChangePriceChanged
+			repriced.OldPrice = &oldPrice
+			repriced.NewPrice = &price
+			changes = append(changes, repriced)
+		}
+
+		items = append(items, item)
+		orderedLines = append(orderedLines, line.ID)
 	}
-	if order.Status != "pending" {
-		return errors.New("order cannot be marked as paid")
+
+	if len(items) == 0 {
+		return nil, changes, errors.New("none of the items in your cart are available")
 	}
 
-	order.Status = "paid"
-	order.UpdatedAt = time.Now()
-	if err := s.orderRepo.UpdateOrder(order); err != nil {
+	if details.CustomerEmail == "" || details.CustomerName == "" {
+		if user, err := s.userRepo.FindByID(userID); err == nil {
+			if details.CustomerEmail == "" {
+				details.CustomerEmail = user.Email
+			}
+			if details.CustomerName == "" {
+				details.CustomerName = user.Name
+			}
+		}
+	}
+	details.UserID = userID
+	details.Items = items
+
+	// CreateOrder prices from the catalog, reserves stock and applies shipping
+	order, err := s.CreateOrder(details)
+	if err != nil {
+		return nil, changes, err
+	}
+
+	if err := s.cartRepo.DeleteItems(ctx, userID, orderedLines); err != nil {
+		fmt.Println("⚠️ Order placed but cart could not be cleared:", err)
+	}
+
+	return &order, changes, nil
+}
+
+// -------------------- MARK ORDER AS PAID --------------------
+func (s *orderServiceImpl) MarkOrderAsPaid(paymentReference string) error {
+	found, attempt, err := s.findOrderByPayment(paymentReference)
+	if err != nil {
 		return err
 	}
+	s.finishAttempt(attempt, models.PaymentAttemptSucceeded, "", "")
 
-	for _, item := range order.Items {
-		productID, _ := primitive.ObjectIDFromHex(item.ProductID)
-		filter := bson.M{"_id": productID, "stock": bson.M{"$gte": item.Quantity}}
-		update := bson.M{
-			"$inc": bson.M{"stock": -item.Quantity},
-			"$set": bson.M{"updated_at": time.Now()},
+	var order *models.Order
+	var from models.OrderStatus
+	alreadyPaid := false
+	err = s.uow.Do(context.Background(), func(ctx context.Context) error {
+		alreadyPaid = false
+		current, err := s.orderRepo.Get(ctx, found.ID)
+		if err != nil {
+			return err
 		}
-		res, err := s.productRepo.UpdateWithFilter(filter, update)
-		if err != nil || res.MatchedCount == 0 {
-			fmt.Printf("⚠️ Not enough stock to decrement for product %s\n", item.ProductName)
+		order = current
+		from = order.Status
+
+		if order.Status == models.OrderPaid {
+			alreadyPaid = true
+			if order.PaymentReference != paymentReference {
+				fmt.Println("⚠️ Order", order.ID.Hex(), "was paid twice, refund payment", paymentReference)
+			}
+			return nil
 		}
+		// Refunds go to the payment that actually succeeded
+		if order.PaymentReference != paymentReference {
+			if err := s.orderRepo.SetPaymentReference(ctx, order.ID, paymentReference); err != nil {
+				return err
+			}
+			order.PaymentReference = paymentReference
+		}
+		return s.changeStatus(ctx, order, models.OrderPaid, paymentActor(), "payment succeeded")
+	})
+	if err != nil || alreadyPaid {
+		return err
 	}
 
-	go s.notifyUserPaymentSuccess(order)
-	go s.notifyAdminPaymentSuccess(order)
-
+	go s.notifyStatusChange(order, from)
 	return nil
 }
 
 // -------------------- MARK ORDER AS FAILED --------------------
-func (s *orderServiceImpl) MarkOrderAsFailed(paymentReference string) error {
-	order, err := s.orderRepo.FindByReference(paymentReference)
+func (s *orderServiceImpl) MarkOrderAsFailed(paymentReference, errorCode, errorMessage string) error {
+	found, attempt, err := s.findOrderByPayment(paymentReference)
 	if err != nil {
 		return err
 	}
+	s.finishAttempt(attempt, models.PaymentAttemptFailed, errorCode, errorMessage)
 
-	if err := s.restoreStock(order); err != nil {
-		fmt.Println("⚠️ Failed to restore stock on order failure:", err)
-	}
+	var order *models.Order
+	failed := false
+	err = s.uow.Do(context.Background(), func(ctx context.Context) error {
+		failed = false
+		current, err := s.orderRepo.Get(ctx, found.ID)
+		if err != nil {
+			return err
+		}
+		order = current
 
-	go s.notifyUserPaymentFailed(order)
-	go s.notifyAdminPaymentFailed(order)
+		// A late failure for an old attempt must not touch an order that was
+		// paid since, or one the customer is paying with another attempt
+		if order.Status != models.OrderPending || order.PaymentReference != paymentReference {
+			return nil
+		}
+		if err := s.changeStatus(ctx, order, models.OrderFailed, paymentActor(), "payment failed"); err != nil {
+			return err
+		}
+		failed = true
+		return nil
+	})
+	if err != nil || !failed {
+		return err
+	}
 
-	order.Status = "failed"
-	order.UpdatedAt = time.Now()
-	return s.orderRepo.UpdateOrder(order)
+	go s.notifyStatusChange(order, models.OrderPending)
+	return nil
 }
 
-// -------------------- HANDLE REFUND / DISPUTE --------------------
+// -------------------- HANDLE REFUND --------------------
 func (s *orderServiceImpl) MarkOrderAsRefunded(paymentReference string) error {
-	return s.handleOrderFailure(paymentReference, "refunded")
+	return s.handleOrderFailure(paymentReference, models.OrderRefunded, "payment refunded")
 }
 
-func (s *orderServiceImpl) MarkOrderAsDisputed(paymentReference string) error {
-	return s.handleOrderFailure(paymentReference, "disputed")
+// MarkOrderAsPartiallyRefunded records a partial refund made outside the shop,
+// e.g. from the Stripe dashboard
+func (s *orderServiceImpl) MarkOrderAsPartiallyRefunded(paymentReference string) error {
+	return s.handleOrderFailure(paymentReference, models.OrderPartiallyRefunded, "payment partially refunded")
 }
 
-func (s *orderServiceImpl) handleOrderFailure(paymentReference, status string) error {
-	order, err := s.orderRepo.FindByReference(paymentReference)
+func (s *orderServiceImpl) handleOrderFailure(paymentReference string, status models.OrderStatus, reason string) error {
+	found, _, err := s.findOrderByPayment(paymentReference)
 	if err != nil {
 		return err
 	}
 
-	if order.Status == "pending" || order.Status == "paid" {
-		order.Status = status
-		order.UpdatedAt = time.Now()
-		if err := s.orderRepo.UpdateOrder(order); err != nil {
+	return s.uow.Do(context.Background(), func(ctx context.Context) error {
+		order, err := s.orderRepo.Get(ctx, found.ID)
+		if err != nil {
 			return err
 		}
-		if err := s.restoreStock(order); err != nil {
-			fmt.Println("⚠️ Failed to restore stock on order failure:", err)
+		// Webhooks can repeat or arrive late; ignore events that no longer apply
+		if !order.Status.CanTransitionTo(status) {
+			return nil
 		}
+		return s.changeStatus(ctx, order, status, paymentActor(), reason)
+	})
+}
+
+// -------------------- STATE MACHINE --------------------
+
+// statusActor says who is making a status change
+type statusActor struct {
+	kind string
+	id   string
+}
+
+func paymentActor() statusActor { return statusActor{kind: models.ActorPayment} }
+func systemActor() statusActor  { return statusActor{kind: models.ActorSystem} }
+
+// changeStatus moves the order to a new status inside the caller's unit of
+// work. The move is checked against the state machine, written conditionally
+// with its history entry, and its stock side effects applied in the same
+// transaction. Emails are left to notifyStatusChange once the work commits.
+func (s *orderServiceImpl) changeStatus(ctx context.Context, order *models.Order, to models.OrderStatus, actor statusActor, reason string) error {
+	if err := models.CheckTransition(order.Status, to); err != nil {
+		return err
+	}
+	return s.writeStatus(ctx, order, to, actor, reason)
+}
+
+// restoreAfterDispute puts a disputed order back to the status it had before
+// the chargeback. The state machine only lets a disputed order go to paid or
+// refunded, so this is the one way back for a won dispute.
+func (s *orderServiceImpl) restoreAfterDispute(ctx context.Context, order *models.Order, to models.OrderStatus, actor statusActor, reason string) error {
+	if order.Status != models.OrderDisputed || !to.RestorableAfterDispute() {
+		return &models.StatusTransitionError{From: order.Status, To: to}
+	}
+	return s.writeStatus(ctx, order, to, actor, reason)
+}
+
+// writeStatus records a status change the caller has already checked
+func (s *orderServiceImpl) writeStatus(ctx context.Context, order *models.Order, to models.OrderStatus, actor statusActor, reason string) error {
+	from := order.Status
+	change := models.StatusChange{
+		From:    from,
+		To:      to,
+		Actor:   actor.kind,
+		ActorID: actor.id,
+		Reason:  reason,
+		At:      time.Now(),
+	}
+	ok, err := s.orderRepo.TransitionStatus(ctx, order.ID, change)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("order changed while moving it to %s, please retry", to)
 	}
 
+	if err := s.applyStatusEffects(ctx, order, from, to); err != nil {
+		return err
+	}
+
+	order.Status = to
+	order.UpdatedAt = change.At
+	order.StatusHistory = append(order.StatusHistory, change)
 	return nil
 }
 
-// -------------------- RESTORE STOCK --------------------
-func (s *orderServiceImpl) restoreStock(order *models.Order) error {
-	for _, item := range order.Items {
+// applyStatusEffects keeps stock and coupon uses in step with the status
+func (s *orderServiceImpl) applyStatusEffects(ctx context.Context, order *models.Order, from, to models.OrderStatus) error {
+	switch to {
+	case models.OrderPaid:
+		// A won dispute leaves the sale as it was
+		if from == models.OrderDisputed {
+			return nil
+		}
+		if err := s.commitReservation(ctx, order); err != nil {
+			return fmt.Errorf("payment received but stock could not be secured: %w", err)
+		}
+	case models.OrderCancelled, models.OrderFailed, models.OrderExpired:
+		return s.releaseOrder(ctx, order)
+	case models.OrderRefunded, models.OrderDisputed:
+		switch from {
+		case models.OrderPending:
+			// Unpaid orders only hold stock
+			return s.releaseOrder(ctx, order)
+		case models.OrderPaid, models.OrderProcessing:
+			// Sold but never sent, so it goes back on the shelf. Refunds issued
+			// from the admin panel decide restocking themselves.
+			if to == models.OrderRefunded && len(order.Refunds) == 0 {
+				return s.returnItems(ctx, order.Items)
+			}
+		}
+	}
+	return nil
+}
+
+// notifyStatusChange sends the emails for a committed status change
+func (s *orderServiceImpl) notifyStatusChange(order *models.Order, from models.OrderStatus) {
+	switch order.Status {
+	case models.OrderPaid:
+		if from == models.OrderDisputed {
+			return
+		}
+		s.notifyUserPaymentSuccess(order)
+		s.notifyAdminPaymentSuccess(order)
+	case models.OrderFailed:
+		s.notifyUserPaymentFailed(order)
+		s.notifyAdminPaymentFailed(order)
+	case models.OrderShipped:
+		// Shipments send their own email with the tracking details
+		if len(order.Shipments) == 0 {
+			s.SendShippedEmail(order, nil)
+		}
+	}
+}
+
+// -------------------- STOCK RESERVATION --------------------
+// The helpers below take the unit-of-work context so their writes join the
+// surrounding transaction; any error rolls the whole flow back.
+
+// reservationTTL is how long an unpaid order holds its stock
+func reservationTTL() time.Duration {
+	if minutes, err := strconv.Atoi(os.Getenv("STOCK_HOLD_MINUTES")); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return 30 * time.Minute
+}
+
+// reserveItems takes the order quantities out of stock
+func (s *orderServiceImpl) reserveItems(ctx context.Context, items []models.OrderItem) error {
+	for _, item := range items {
 		productID, _ := primitive.ObjectIDFromHex(item.ProductID)
-		update := bson.M{
-			"$inc": bson.M{"stock": item.Quantity},
-			"$set": bson.M{"updated_at": time.Now()},
+		ok, err := s.productRepo.DecrementStock(ctx, productID, item.VariantObjectID(), item.Quantity)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: not enough left of %s", repositories.ErrInsufficientStock, item.DisplayName())
 		}
-		if err := s.productRepo.Update(productID, update); err != nil {
+	}
+	return nil
+}
+
+// returnItems puts the quantities back into stock
+func (s *orderServiceImpl) returnItems(ctx context.Context, items []models.OrderItem) error {
+	for _, item := range items {
+		productID, _ := primitive.ObjectIDFromHex(item.ProductID)
+		if err := s.productRepo.IncrementStock(ctx, productID, item.VariantObjectID(), item.Quantity); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// releaseOrder undoes what an unpaid order took: its stock hold and coupon uses
+func (s *orderServiceImpl) releaseOrder(ctx context.Context, order *models.Order) error {
+	if err := s.releaseReservation(ctx, order); err != nil {
+		return err
+	}
+	return s.coupons.ReleaseCoupon(ctx, order.ID)
+}
+
+// releaseReservation gives held stock back. It is a no-op once released or committed.
+func (s *orderServiceImpl) releaseReservation(ctx context.Context, order *models.Order) error {
+	// Orders placed before reservations existed never took stock
+	if order.Reservation == nil {
+		return nil
+	}
+
+	released, err := s.orderRepo.SetReservationStatus(ctx, order.ID, models.ReservationHeld, models.ReservationReleased)
+	if err != nil || !released {
+		return err
+	}
+	return s.returnItems(ctx, order.Items)
+}
+
+// commitReservation turns the hold into a sale once the order is paid
+func (s *orderServiceImpl) commitReservation(ctx context.Context, order *models.Order) error {
+	// Orders placed before reservations existed take their stock at payment time
+	if order.Reservation == nil {
+		return s.reserveItems(ctx, order.Items)
+	}
+
+	switch order.Reservation.Status {
+	case models.ReservationCommitted:
+		return nil
+	case models.ReservationReleased:
+		// The hold expired before payment arrived, so take the stock again
+		if err := s.reserveItems(ctx, order.Items); err != nil {
+			return err
+		}
+		return s.setReservationStatus(ctx, order.ID, models.ReservationReleased, models.ReservationCommitted)
+	default:
+		return s.setReservationStatus(ctx, order.ID, models.ReservationHeld, models.ReservationCommitted)
+	}
+}
+
+func (s *orderServiceImpl) setReservationStatus(ctx context.Context, orderID primitive.ObjectID, from, to string) error {
+	ok, err := s.orderRepo.SetReservationStatus(ctx, orderID, from, to)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("order stock reservation changed concurrently")
+	}
+	return nil
+}
+
+// -------------------- RESERVATION SWEEPER --------------------
+
+// ExpireStaleOrders expires pending orders whose stock hold has run out and
+// releases their stock. It returns how many orders were expired.
+func (s *orderServiceImpl) ExpireStaleOrders() (int, error) {
+	orders, err := s.orderRepo.FindExpiredReservations(time.Now(), 100)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for i := range orders {
+		order := &orders[i]
+
+		err := s.uow.Do(context.Background(), func(ctx context.Context) error {
+			return s.changeStatus(ctx, order, models.OrderExpired, systemActor(), "stock hold expired before payment")
+		})
+		if err != nil {
+			// Usually the order was paid or cancelled since the query ran
+			fmt.Println("⚠️ Could not expire order:", order.ID.Hex(), err)
+			continue
+		}
+		expired++
+	}
+
+	return expired, nil
+}
+
+// StartReservationSweeper periodically expires stale pending orders
+func (s *orderServiceImpl) StartReservationSweeper(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			count, err := s.ExpireStaleOrders()
+			if err != nil {
+				fmt.Println("⚠️ Reservation sweep failed:", err)
+				continue
+			}
+			if count > 0 {
+				fmt.Printf("⏳ Expired %d unpaid order(s) and released their stock\n", count)
+			}
+		}
+	}()
+}
+
 // -------------------- SHIPMENT EMAIL --------------------
-func (s *orderServiceImpl) SendShippedEmail(order *models.Order) {
+
+// SendShippedEmail tells the customer a parcel is on its way. shipment is nil
+// when an admin marked the order shipped without recording a shipment.
+func (s *orderServiceImpl) SendShippedEmail(order *models.Order, shipment *models.Shipment) {
 	user, err := s.userRepo.FindById(order.UserID.Hex())
 	if err != nil {
 		fmt.Println("⚠️ Could not find user for shipment email:", err)
 		return
 	}
-	utils.SendShipmentEmail(user.Email, user.Name, order.ID.Hex(), order.DeliveryType)
+	utils.SendShipmentEmail(user.Email, user.Name, order, shipment)
 }
 
 // -------------------- NOTIFICATIONS --------------------
@@ -209,7 +675,7 @@ func (s *orderServiceImpl) notifyAdminOrderCreated(order *models.Order) {
 	subject := fmt.Sprintf("🛒 New Order Created - %s", order.ID.Hex())
 	itemsHTML := ""
 	for _, item := range order.Items {
-		itemsHTML += fmt.Sprintf("<li>%s × %d — £%.2f</li>", item.ProductName, item.Quantity, item.Price*float64(item.Quantity))
+		itemsHTML += fmt.Sprintf("<li>%s × %d — %s</li>", item.DisplayName(), item.Quantity, item.Price.Mul(item.Quantity).Format())
 	}
 	html := fmt.Sprintf(`
 		<h2>New Order Created</h2>
@@ -217,11 +683,13 @@ func (s *orderServiceImpl) notifyAdminOrderCreated(order *models.Order) {
 		<p><strong>Order ID:</strong> %s</p>
 		<p><strong>Delivery:</strong> %s</p>
 		<h3>Items</h3><ul>%s</ul>
-		<p><strong>Subtotal:</strong> £%.2f</p>
-		<p><strong>Shipping:</strong> £%.2f</p>
-		<p><strong>Total:</strong> £%.2f</p>
+		<p><strong>Subtotal:</strong> %s</p>
+		<p><strong>Shipping:</strong> %s</p>
+		<p><strong>Discount:</strong> -%s %s</p>
+		<p><strong>Total:</strong> %s</p>
+		%s
 		<p>Status: <b>Pending payment</b></p>
-	`, order.CustomerName, order.CustomerEmail, order.ID.Hex(), order.DeliveryType, itemsHTML, order.Subtotal, order.ShippingFee, order.TotalPrice)
+	`, order.CustomerName, order.CustomerEmail, order.ID.Hex(), order.DeliveryType, itemsHTML, order.Subtotal.Format(), order.ShippingFee.Format(), order.DiscountTotal.Format(), order.CouponCode, order.TotalPrice.Format(), utils.TaxBreakdownHTML(order))
 	utils.QueueEmail(adminEmail, "Admin", subject, html)
 }
 
@@ -231,7 +699,7 @@ func (s *orderServiceImpl) notifyUserOrderCreated(order *models.Order) {
 		fmt.Println("⚠️ Could not find user for order email:", err)
 		return
 	}
-	utils.SendConfirmationEmail(user.Email, user.Name, order.ID.Hex(), order.DeliveryType, order.Subtotal, order.ShippingFee, order.TotalPrice)
+	utils.SendConfirmationEmail(user.Email, user.Name, order)
 }
 
 func (s *orderServiceImpl) notifyUserPaymentSuccess(order *models.Order) {
@@ -240,7 +708,7 @@ func (s *orderServiceImpl) notifyUserPaymentSuccess(order *models.Order) {
 		fmt.Println("⚠️ Could not find user for payment success email:", err)
 		return
 	}
-	utils.SendConfirmationEmail(user.Email, user.Name, order.ID.Hex(), order.DeliveryType, order.Subtotal, order.ShippingFee, order.TotalPrice)
+	utils.SendConfirmationEmail(user.Email, user.Name, order)
 }
 
 func (s *orderServiceImpl) notifyAdminPaymentSuccess(order *models.Order) {
@@ -252,8 +720,8 @@ func (s *orderServiceImpl) notifyAdminPaymentSuccess(order *models.Order) {
 	subject := fmt.Sprintf("Order Paid - %s", order.ID.Hex())
 	html := fmt.Sprintf(`<p>Order <b>%s</b> paid by <b>%s</b> (%s).</p>
 		<p>Delivery: %s</p>
-		<p>Subtotal: £%.2f | Shipping: £%.2f | Total: £%.2f</p>`,
-		order.ID.Hex(), user.Name, user.Email, order.DeliveryType, order.Subtotal, order.ShippingFee, order.TotalPrice)
+		<p>Subtotal: %s | Shipping: %s | Discount: -%s | Tax: %s | Total: %s</p>`,
+		order.ID.Hex(), user.Name, user.Email, order.DeliveryType, order.Subtotal.Format(), order.ShippingFee.Format(), order.DiscountTotal.Format(), order.TaxTotal.Format(), order.TotalPrice.Format())
 	utils.QueueEmail(adminEmail, "Admin", subject, html)
 }
 
@@ -281,25 +749,24 @@ func (s *orderServiceImpl) notifyAdminPaymentFailed(order *models.Order) {
 func (s *orderServiceImpl) CancelOrder(orderID, userID primitive.ObjectID) (*models.Order, error) {
 	order, err := s.orderRepo.FindByID(orderID)
 	if err != nil {
-		return nil, errors.New("order not found")
+		return nil, services.ErrOrderNotFound
 	}
 
 	if order.UserID != userID {
 		return nil, errors.New("you cannot cancel this order")
 	}
 
-	if order.Status != "pending" {
+	// Customers can only cancel before paying; paid orders go through a refund
+	if order.Status != models.OrderPending {
 		return nil, errors.New("order cannot be cancelled")
 	}
 
-	order.Status = "cancelled"
-	order.UpdatedAt = time.Now()
-	if err := s.orderRepo.UpdateOrder(order); err != nil {
-		return nil, err
-	}
-
-	if err := s.restoreStock(order); err != nil {
-		fmt.Println("⚠️ Failed to restore stock on cancellation:", err)
+	err = s.uow.Do(context.Background(), func(ctx context.Context) error {
+		return s.changeStatus(ctx, order, models.OrderCancelled,
+			statusActor{kind: models.ActorCustomer, id: userID.Hex()}, "cancelled by customer")
+	})
+	if err != nil {
+		return nil, errors.New("order cannot be cancelled")
 	}
 
 	return order, nil
@@ -319,41 +786,442 @@ func (s *orderServiceImpl) GetOrderByID(orderID primitive.ObjectID) (*models.Ord
 }
 
 // -------------------- UPDATE ORDER STATUS --------------------
-func (s *orderServiceImpl) UpdateOrderStatus(orderID primitive.ObjectID, status string) error {
-	order, err := s.orderRepo.FindByID(orderID)
+
+// UpdateOrderStatus is the admin's manual status change. Only fulfilment
+// statuses and cancellation can be set by hand; payment statuses follow from
+// the payment provider.
+func (s *orderServiceImpl) UpdateOrderStatus(orderID primitive.ObjectID, status models.OrderStatus, adminID primitive.ObjectID, reason string) (*models.Order, error) {
+	if !status.Valid() {
+		return nil, &models.StatusTransitionError{To: status}
+	}
+	if !status.AdminSettable() {
+		return nil, fmt.Errorf("%s is set by the payment flow and cannot be set by hand", status)
+	}
+
+	var order *models.Order
+	var from models.OrderStatus
+	err := s.uow.Do(context.Background(), func(ctx context.Context) error {
+		current, err := s.orderRepo.Get(ctx, orderID)
+		if err != nil {
+			return services.ErrOrderNotFound
+		}
+		order = current
+		from = order.Status
+		return s.changeStatus(ctx, order, status, statusActor{kind: models.ActorAdmin, id: adminID.Hex()}, reason)
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	order.Status = status
-	order.UpdatedAt = time.Now()
-	if err := s.orderRepo.UpdateOrder(order); err != nil {
-		return err
+
+	go s.notifyStatusChange(order, from)
+	return order, nil
+}
+
+// -------------------- PAYMENT ATTEMPTS --------------------
+
+// findOrderByPayment finds the order a payment belongs to through its
+// attempt, falling back to the order's own reference for orders paid before
+// attempts were recorded. The attempt is nil in that case.
+func (s *orderServiceImpl) findOrderByPayment(reference string) (*models.Order, *models.PaymentAttempt, error) {
+	attempt, err := s.attempts.FindByReference(context.Background(), reference)
+	if err == nil {
+		order, err := s.orderRepo.FindByID(attempt.OrderID)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil, fmt.Errorf("%w for payment %s", services.ErrOrderNotFound, reference)
+		}
+		return order, attempt, err
 	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil, err
+	}
+	order, err := s.orderRepo.FindByReference(reference)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil, fmt.Errorf("%w for payment %s", services.ErrOrderNotFound, reference)
+	}
+	return order, nil, err
+}
 
-	if status == "shipped" {
-		go s.SendShippedEmail(order)
+// finishAttempt records an attempt's outcome; failing to is only logged, as
+// the order itself has the final say
+func (s *orderServiceImpl) finishAttempt(attempt *models.PaymentAttempt, status, errorCode, errorMessage string) {
+	if attempt == nil {
+		return
+	}
+	if err := s.attempts.Finish(context.Background(), attempt.ID, status, errorCode, errorMessage); err != nil {
+		fmt.Println("⚠️ Could not record payment attempt outcome:", attempt.ID.Hex(), err)
 	}
+}
 
-	return nil
+// GetPaymentAttempts lists every payment opened for the order
+func (s *orderServiceImpl) GetPaymentAttempts(orderID primitive.ObjectID) ([]models.PaymentAttempt, error) {
+	if _, err := s.orderRepo.FindByID(orderID); err != nil {
+		return nil, services.ErrOrderNotFound
+	}
+	return s.attempts.FindByOrder(orderID)
 }
 
 // -------------------- INITIALIZE PAYMENT --------------------
-func (s *orderServiceImpl) InitializePayment(orderID, userID primitive.ObjectID, email string) (string, string, error) {
+// InitializePayment opens a payment with the provider for the order total and
+// records it as a payment attempt, so the provider's webhooks can find the
+// order. An intent the customer can still complete is handed back instead
+// of opening another. Paying with a saved payment method confirms the
+// intent straight away.
+func (s *orderServiceImpl) InitializePayment(orderID, userID primitive.ObjectID, options services.PaymentOptions) (*models.Order, *services.PaymentIntent, error) {
+	order, user, err := s.payableOrder(orderID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx := context.Background()
+
+	// Intents carry the shopper's customer so their cards can be saved
+	customerID, err := paymentCustomerID(ctx, s.userRepo, s.payments, user)
+	if err != nil {
+		if options.PaymentMethodID != "" || options.SavePaymentMethod {
+			return nil, nil, err
+		}
+		fmt.Println("⚠️ Could not create payment customer:", user.ID.Hex(), err)
+	}
+	if options.PaymentMethodID != "" {
+		if err := s.checkPaymentMethod(ctx, customerID, options.PaymentMethodID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	intent, err := s.livePaymentIntent(ctx, order)
+	if err != nil {
+		return nil, nil, err
+	}
+	if intent == nil {
+		if intent, err = s.createPaymentIntent(ctx, order, user, customerID, options.SavePaymentMethod); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// An intent already confirmed is left to the customer to finish
+	if options.PaymentMethodID == "" || (intent.Status != "requires_payment_method" && intent.Status != "requires_confirmation") {
+		return order, intent, nil
+	}
+	confirmed, err := s.payments.ConfirmIntent(ctx, intent.ID, options.PaymentMethodID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return order, confirmed, nil
+}
+
+// checkPaymentMethod makes sure the shopper is paying with one of their own
+// saved payment methods
+func (s *orderServiceImpl) checkPaymentMethod(ctx context.Context, customerID, paymentMethodID string) error {
+	methods, err := s.payments.ListPaymentMethods(ctx, customerID)
+	if err != nil {
+		return err
+	}
+	for _, method := range methods {
+		if method.ID == paymentMethodID {
+			return nil
+		}
+	}
+	return services.ErrPaymentMethodNotFound
+}
+
+// createPaymentIntent opens a new intent for the order total and records it
+// as the order's latest payment attempt
+func (s *orderServiceImpl) createPaymentIntent(ctx context.Context, order *models.Order, user *models.User, customerID string, savePaymentMethod bool) (*services.PaymentIntent, error) {
+	// The key only changes once an earlier attempt is recorded or the total
+	// changes. Requests that race, or retry after the attempt failed to save,
+	// get the same intent back from the provider and record it once.
+	previous, err := s.attempts.CountByOrder(ctx, order.ID, models.PaymentAttemptIntent)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("payment-%s-%d-%d", order.ID.Hex(), order.TotalPrice.Amount, previous+1)
+
+	intent, err := s.payments.CreateIntent(ctx, services.IntentParams{
+		Amount:            order.TotalPrice,
+		CustomerID:        customerID,
+		SavePaymentMethod: savePaymentMethod,
+		IdempotencyKey:    key,
+		Metadata:          paymentMetadata(order, user.Email, user.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	attempt := &models.PaymentAttempt{
+		ID:             primitive.NewObjectID(),
+		OrderID:        order.ID,
+		Provider:       s.payments.Name(),
+		Kind:           models.PaymentAttemptIntent,
+		Reference:      intent.ID,
+		IdempotencyKey: key,
+		Amount:         order.TotalPrice,
+		Status:         models.PaymentAttemptPending,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := s.attempts.Create(ctx, attempt); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return nil, services.ErrPaymentNotSaved
+	}
+	if err := s.SaveOrderReference(order.ID.Hex(), intent.ID); err != nil {
+		return nil, services.ErrPaymentNotSaved
+	}
+	order.PaymentReference = intent.ID
+	return intent, nil
+}
+
+// livePaymentIntent returns the order's open intent if the customer can still
+// complete it, and nil if a new one is needed
+func (s *orderServiceImpl) livePaymentIntent(ctx context.Context, order *models.Order) (*services.PaymentIntent, error) {
+	attempt, err := s.attempts.FindLatestPending(ctx, order.ID, models.PaymentAttemptIntent)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if attempt.Amount != order.TotalPrice {
+		s.finishAttempt(attempt, models.PaymentAttemptFailed, "amount_changed", "")
+		return nil, nil
+	}
+
+	intent, err := s.payments.GetIntent(ctx, attempt.Reference)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case intent.Status == "succeeded":
+		return nil, errors.New("payment already received, the order will update shortly")
+	case !intent.Reusable():
+		s.finishAttempt(attempt, models.PaymentAttemptFailed, intent.Status, "")
+		return nil, nil
+	}
+
+	if order.PaymentReference != intent.ID {
+		if err := s.SaveOrderReference(order.ID.Hex(), intent.ID); err != nil {
+			return nil, services.ErrPaymentNotSaved
+		}
+		order.PaymentReference = intent.ID
+	}
+	return intent, nil
+}
+
+// payableOrder loads one of the customer's orders that is waiting for payment
+func (s *orderServiceImpl) payableOrder(orderID, userID primitive.ObjectID) (*models.Order, *models.User, error) {
 	order, err := s.orderRepo.FindByID(orderID)
+	if err != nil || order.UserID != userID {
+		return nil, nil, services.ErrOrderNotFound
+	}
+	if order.Status != models.OrderPending {
+		return nil, nil, errors.New("order is not awaiting payment")
+	}
+	if order.Reservation != nil && time.Now().After(order.Reservation.ExpiresAt) {
+		return nil, nil, errors.New("order reservation has expired, please place the order again")
+	}
+	if !order.TotalPrice.IsPositive() {
+		return nil, nil, errors.New("order total invalid")
+	}
+
+	user, err := s.userRepo.FindById(userID.Hex())
 	if err != nil {
-		return "", "", errors.New("order not found")
+		return nil, nil, services.ErrUserNotFound
 	}
-	if order.UserID != userID {
-		return "", "", errors.New("unauthorized")
+	return order, &user, nil
+}
+
+// paymentMetadata is the order breakdown attached to the payment, so it can
+// be read in the provider's dashboard
+func paymentMetadata(order *models.Order, email, name string) map[string]string {
+	metadata := map[string]string{
+		"order_id":      order.ID.Hex(),
+		"user_email":    email,
+		"user_name":     name,
+		"delivery_type": order.DeliveryType,
+		"subtotal":      order.Subtotal.String(),
+		"shipping_fee":  order.ShippingFee.String(),
+		"tax_total":     order.TaxTotal.String(),
+		"total_price":   order.TotalPrice.String(),
+	}
+	if order.Currency != "" && order.Currency != models.DefaultCurrency {
+		metadata["exchange_rate"] = strconv.FormatFloat(order.ExchangeRate, 'f', -1, 64)
+	}
+	if order.DiscountTotal.IsPositive() {
+		metadata["discount_total"] = order.DiscountTotal.String()
+		metadata["coupon_code"] = order.CouponCode
+	}
+	return metadata
+}
+
+// -------------------- HOSTED CHECKOUT --------------------
+
+// frontendURL is where the storefront runs, for links back from the provider
+func frontendURL() string {
+	if url := os.Getenv("FRONTEND_URL"); url != "" {
+		return strings.TrimRight(url, "/")
 	}
+	return "http://localhost:3000"
+}
+
+// Hosted checkout pages stay open for at least 30 minutes and at most a day
+const (
+	minCheckoutLifetime = 30 * time.Minute
+	maxCheckoutLifetime = 24 * time.Hour
+)
 
-	reference := primitive.NewObjectID().Hex()
-	if err := s.SaveOrderReference(orderID.Hex(), reference); err != nil {
-		return "", "", err
+// checkoutParams lays the order out as hosted checkout lines: the items at
+// their unit prices, exclusive tax as lines of its own, then shipping and the
+// discount. It fails if they do not come to the order total.
+func checkoutParams(order *models.Order) (services.CheckoutParams, error) {
+	params := services.CheckoutParams{
+		Shipping:     order.ShippingFee,
+		ShippingName: order.DeliveryType,
+		Discount:     order.DiscountTotal,
+	}
+	if params.ShippingName == "" {
+		params.ShippingName = "Shipping"
 	}
 
-	paymentURL := fmt.Sprintf("https://payment-provider.com/pay/%s", reference)
-	return reference, paymentURL, nil
+	total := order.ShippingFee.Sub(order.DiscountTotal)
+	for _, item := range order.Items {
+		params.Lines = append(params.Lines, services.CheckoutLine{Name: item.DisplayName(), UnitPrice: item.Price, Quantity: item.Quantity})
+		total = total.Add(item.Price.Mul(item.Quantity))
+	}
+	for _, tax := range order.TaxLines {
+		if tax.Inclusive || !tax.Amount.IsPositive() {
+			continue
+		}
+		params.Lines = append(params.Lines, services.CheckoutLine{
+			Name:      tax.Name,
+			UnitPrice: tax.Amount,
+			Quantity:  1,
+		})
+		total = total.Add(tax.Amount)
+	}
+
+	if total != order.TotalPrice {
+		return params, fmt.Errorf("checkout lines come to %s but the order total is %s", total.Format(), order.TotalPrice.Format())
+	}
+	return params, nil
+}
+
+// InitializeCheckout opens a hosted checkout page for the order, or hands
+// back one that is still open. The payment is linked to the order when the
+// provider reports the checkout completed.
+func (s *orderServiceImpl) InitializeCheckout(orderID, userID primitive.ObjectID) (*models.Order, *services.CheckoutSession, error) {
+	order, user, err := s.payableOrder(orderID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx := context.Background()
+
+	open, err := s.attempts.FindLatestPending(ctx, orderID, models.PaymentAttemptCheckout)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil, err
+	}
+	if open != nil && open.Amount == order.TotalPrice && open.ExpiresAt != nil && time.Until(*open.ExpiresAt) > time.Minute {
+		return order, &services.CheckoutSession{ID: open.CheckoutSession, URL: open.CheckoutURL}, nil
+	}
+
+	params, err := checkoutParams(order)
+	if err != nil {
+		return nil, nil, err
+	}
+	params.CustomerEmail = user.Email
+	params.Metadata = paymentMetadata(order, user.Email, user.Name)
+	if customerID, err := paymentCustomerID(ctx, s.userRepo, s.payments, user); err == nil {
+		params.CustomerID = customerID
+	} else {
+		fmt.Println("⚠️ Could not create payment customer:", user.ID.Hex(), err)
+	}
+
+	base := frontendURL() + "/orders/" + orderID.Hex()
+	params.SuccessURL = base + "?checkout=success"
+	params.CancelURL = base + "?checkout=cancelled"
+
+	// Close the page when the stock hold runs out, within the provider's limits
+	lifetime := maxCheckoutLifetime
+	if order.Reservation != nil {
+		lifetime = min(max(time.Until(order.Reservation.ExpiresAt), minCheckoutLifetime), maxCheckoutLifetime)
+	}
+	expiresAt := time.Now().Add(lifetime)
+	params.ExpiresAt = &expiresAt
+
+	attempt := &models.PaymentAttempt{
+		ID:        primitive.NewObjectID(),
+		OrderID:   orderID,
+		Provider:  s.payments.Name(),
+		Kind:      models.PaymentAttemptCheckout,
+		Amount:    order.TotalPrice,
+		Status:    models.PaymentAttemptPending,
+		ExpiresAt: &expiresAt,
+	}
+	params.IdempotencyKey = "payment-attempt-" + attempt.ID.Hex()
+
+	session, err := s.payments.CreateCheckoutSession(ctx, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attempt.CheckoutSession = session.ID
+	attempt.CheckoutURL = session.URL
+	attempt.CreatedAt = time.Now()
+	attempt.UpdatedAt = attempt.CreatedAt
+	if err := s.attempts.Create(ctx, attempt); err != nil {
+		return nil, nil, services.ErrCheckoutNotSaved
+	}
+	return order, session, nil
+}
+
+// CompleteCheckout links the payment a hosted checkout produced to its order
+// and, if the money has been taken, marks the order paid. Payments that settle
+// later are marked paid by their own payment event.
+func (s *orderServiceImpl) CompleteCheckout(sessionID, paymentReference string, paid bool) error {
+	if paymentReference == "" {
+		return fmt.Errorf("%w: checkout session has no payment", services.ErrMalformedEvent)
+	}
+
+	ctx := context.Background()
+	attempt, err := s.attempts.FindByCheckoutSession(ctx, sessionID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("%w for checkout session %s", services.ErrOrderNotFound, sessionID)
+	}
+	if err != nil {
+		return err
+	}
+	if attempt.Reference == "" {
+		if err := s.attempts.Update(ctx, attempt.ID, bson.M{"reference": paymentReference}); err != nil {
+			return err
+		}
+	}
+
+	if !paid {
+		// Point the order at the payment so its outcome applies
+		order, err := s.orderRepo.FindByID(attempt.OrderID)
+		if err != nil {
+			return err
+		}
+		if order.Status == models.OrderPending && order.PaymentReference != paymentReference {
+			if err := s.SaveOrderReference(order.ID.Hex(), paymentReference); err != nil {
+				return err
+			}
+		}
+		fmt.Println("⏳ Checkout completed, waiting for payment:", paymentReference)
+		return nil
+	}
+	return s.MarkOrderAsPaid(paymentReference)
+}
+
+// ExpireCheckout records a hosted checkout that closed unpaid. The order stays
+// pending, so the customer can pay again until the stock hold runs out.
+func (s *orderServiceImpl) ExpireCheckout(sessionID string) error {
+	attempt, err := s.attempts.FindByCheckoutSession(context.Background(), sessionID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("%w for checkout session %s", services.ErrOrderNotFound, sessionID)
+	}
+	if err != nil {
+		return err
+	}
+	s.finishAttempt(attempt, models.PaymentAttemptExpired, "", "")
+	return nil
 }
 
 // -------------------- SAVE ORDER REFERENCE --------------------
@@ -366,7 +1234,11 @@ func (s *orderServiceImpl) GetProductByID(productID primitive.ObjectID) (*models
 	return s.productRepo.FindByID(productID)
 }
 
+// GetSalesAnalytics summarises revenue, discounts and tax over orders that were paid
 func (s *orderServiceImpl) GetSalesAnalytics() (map[string]interface{}, error) {
-	// Example: return empty analytics
-	return map[string]interface{}{}, nil
+	summary, err := s.orderRepo.SalesSummary([]models.OrderStatus{models.OrderPaid, models.OrderProcessing, models.OrderShipped, models.OrderDelivered, models.OrderPartiallyRefunded})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
 }
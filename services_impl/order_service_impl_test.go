@@ -0,0 +1,71 @@
+package servicesimpl
+
+import (
+	"context"
+	"testing"
+
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckoutParams(t *testing.T) {
+	order := &models.Order{
+		Items: []models.OrderItem{
+			{ProductName: "Lipstick", VariantLabel: "Ruby", Quantity: 2, Price: models.GBP(1200)},
+			{ProductName: "Serum", Quantity: 1, Price: models.GBP(3000)},
+		},
+		ShippingFee:   models.GBP(499),
+		DeliveryType:  "standard",
+		DiscountTotal: models.GBP(500),
+		TaxLines: []models.TaxLine{
+			{Name: "VAT 20%", Inclusive: true, Amount: models.GBP(900)},
+			{Name: "Sales tax 5%", Amount: models.GBP(270)},
+		},
+		TotalPrice: models.GBP(1200*2 + 3000 + 499 - 500 + 270),
+	}
+
+	params, err := checkoutParams(order)
+	require.NoError(t, err)
+	assert.Equal(t, []services.CheckoutLine{
+		{Name: "Lipstick (Ruby)", UnitPrice: models.GBP(1200), Quantity: 2},
+		{Name: "Serum", UnitPrice: models.GBP(3000), Quantity: 1},
+		{Name: "Sales tax 5%", UnitPrice: models.GBP(270), Quantity: 1},
+	}, params.Lines)
+
+	t.Run("fake checkout charges the order total", func(t *testing.T) {
+		provider := NewFakePaymentProvider("whsec_test")
+		session, err := provider.CreateCheckoutSession(context.Background(), params)
+		require.NoError(t, err)
+
+		charged, ok := provider.CheckoutTotal(session.ID)
+		require.True(t, ok)
+		assert.Equal(t, order.TotalPrice, charged)
+
+		payload, headers, err := provider.CompleteCheckout(session.ID)
+		require.NoError(t, err)
+		delivery, err := provider.VerifyWebhook(payload, headers)
+		require.NoError(t, err)
+		event, err := provider.DecodeEvent(delivery.Type, delivery.Data)
+		require.NoError(t, err)
+		assert.Equal(t, services.CheckoutCompleted, event.Kind)
+		assert.Equal(t, session.ID, event.CheckoutSessionID)
+		assert.True(t, event.Paid)
+
+		intent, err := provider.GetIntent(context.Background(), event.PaymentReference)
+		require.NoError(t, err)
+		assert.Equal(t, order.TotalPrice, intent.Amount)
+
+		_, _, err = provider.ExpireCheckout(session.ID)
+		assert.Error(t, err, "a completed checkout cannot expire")
+	})
+
+	t.Run("totals that do not add up", func(t *testing.T) {
+		broken := *order
+		broken.TotalPrice = models.GBP(1)
+		_, err := checkoutParams(&broken)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,84 @@
+package servicesimpl
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"beauty-ecommerce-backend/config"
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/repositories"
+	"beauty-ecommerce-backend/services"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestPaymentFlow pays an order end to end through the fake provider: the
+// order service opens the intent, the provider settles it and the webhook
+// service marks the order paid. Orders change status in transactions, so it
+// needs MONGODB_TEST_URI pointing at a replica set and is skipped without it.
+func TestPaymentFlow(t *testing.T) {
+	_ = godotenv.Load("../.env")
+	if os.Getenv("MONGODB_TEST_URI") == "" {
+		t.Skip("MONGODB_TEST_URI is not set")
+	}
+	config.ConnectTestDB()
+	db := config.DB
+	ctx := context.Background()
+
+	orderRepo := repositories.NewOrderRepository(db)
+	userRepo := repositories.NewUserRepository(db)
+	provider := NewFakePaymentProvider("whsec_test")
+	orders := NewOrderService(orderRepo, repositories.NewProductRepository(db), userRepo, repositories.NewCartRepository(db),
+		repositories.NewPaymentAttemptRepository(db), nil, nil, nil, nil, provider, repositories.NewUnitOfWork(db))
+	webhooks := NewWebhookService(repositories.NewWebhookEventRepository(db), orders, nil, provider)
+
+	user := models.User{ID: primitive.NewObjectID(), Name: "Ada", Email: primitive.NewObjectID().Hex() + "@example.com", Role: string(models.RoleCustomer)}
+	_, err := userRepo.CreateUser(user)
+	require.NoError(t, err)
+
+	order := models.Order{
+		ID:         primitive.NewObjectID(),
+		UserID:     user.ID,
+		Status:     models.OrderPending,
+		Subtotal:   models.GBP(2500),
+		TotalPrice: models.GBP(2500),
+		Reservation: &models.StockReservation{
+			Status:    models.ReservationHeld,
+			ExpiresAt: time.Now().Add(15 * time.Minute),
+		},
+	}
+	require.NoError(t, orderRepo.CreateOrder(ctx, &order))
+
+	_, intent, err := orders.InitializePayment(order.ID, user.ID, services.PaymentOptions{})
+	require.NoError(t, err)
+
+	// Asking again hands back the same open intent
+	_, again, err := orders.InitializePayment(order.ID, user.ID, services.PaymentOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, intent.ID, again.ID)
+
+	payload, headers, err := provider.Succeed(intent.ID)
+	require.NoError(t, err)
+	delivery, err := provider.VerifyWebhook(payload, headers)
+	require.NoError(t, err)
+	require.NoError(t, webhooks.HandleEvent(delivery.ID, delivery.Type, delivery.Data))
+
+	paid, err := orderRepo.FindByID(order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderPaid, paid.Status)
+	assert.Equal(t, intent.ID, paid.PaymentReference)
+	assert.Equal(t, models.ReservationCommitted, paid.Reservation.Status)
+
+	attempts, err := orders.GetPaymentAttempts(order.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 1)
+	assert.Equal(t, models.PaymentAttemptSucceeded, attempts[0].Status)
+
+	// A redelivery is recognised and not applied twice
+	assert.ErrorIs(t, webhooks.HandleEvent(delivery.ID, delivery.Type, delivery.Data), repositories.ErrEventDone)
+}
@@ -52,6 +52,39 @@ func (s *productServiceImpl) GetAllProducts() ([]models.Product, error) {
 	return products, nil
 }
 
+// SEARCH PRODUCTS
+func (s *productServiceImpl) SearchProducts(query models.ProductQuery) (*models.ProductPage, error) {
+	if query.Limit < 1 {
+		query.Limit = 20
+	}
+	if query.Limit > 100 {
+		query.Limit = 100
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+
+	products, total, nextCursor, err := s.productRepo.Search(query)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range products {
+		products[i].OutOfStock = products[i].Stock <= 0
+	}
+
+	page := &models.ProductPage{
+		Products:   products,
+		Total:      total,
+		Limit:      query.Limit,
+		NextCursor: nextCursor,
+	}
+	if query.Cursor == "" {
+		page.Page = query.Page
+	}
+	return page, nil
+}
+
 // GET PRODUCT BY ID
 func (s *productServiceImpl) GetProductByID(id string) (*models.Product, error) {
 	objID, err := primitive.ObjectIDFromHex(id)
@@ -61,7 +94,7 @@ func (s *productServiceImpl) GetProductByID(id string) (*models.Product, error)
 
 	product, err := s.productRepo.FindByID(objID)
 	if err != nil {
-		return nil, errors.New("product not found")
+		return nil, services.ErrProductNotFound
 	}
 
 	return product, nil
@@ -74,6 +107,11 @@ func (s *productServiceImpl) UpdateProduct(id string, product models.Product) er
 		return errors.New("invalid product ID")
 	}
 
+	existing, err := s.productRepo.FindByID(objID)
+	if err != nil {
+		return services.ErrProductNotFound
+	}
+
 	update := bson.M{}
 
 	// Only update fields that are non-empty / non-zero
@@ -83,11 +121,12 @@ func (s *productServiceImpl) UpdateProduct(id string, product models.Product) er
 	if product.Description != "" {
 		update["description"] = product.Description
 	}
-	if product.Price != 0 {
+	if !product.Price.IsZero() {
 		update["price"] = product.Price
 	}
 
-	if product.Stock >= 0 {
+	// Variant products derive their stock from the variants
+	if product.Stock >= 0 && len(existing.Variants) == 0 {
 		update["stock"] = product.Stock
 	}
 
@@ -97,6 +136,9 @@ func (s *productServiceImpl) UpdateProduct(id string, product models.Product) er
 	if product.ImageURL != "" {
 		update["image_url"] = product.ImageURL
 	}
+	if product.WeightGrams > 0 {
+		update["weight_grams"] = product.WeightGrams
+	}
 
 	// Always update timestamp
 	update["updated_at"] = time.Now()
@@ -114,7 +156,7 @@ func (s *productServiceImpl) DeleteProduct(id string) error {
 	// Fetch the product first
 	product, err := s.productRepo.FindByID(objID)
 	if err != nil {
-		return errors.New("product not found")
+		return services.ErrProductNotFound
 	}
 
 	// Delete image from Cloudinary if exists
@@ -128,3 +170,104 @@ func (s *productServiceImpl) DeleteProduct(id string) error {
 	// Delete product from DB
 	return s.productRepo.Delete(objID)
 }
+
+// -------------------- VARIANTS --------------------
+func validateVariant(variant models.ProductVariant) error {
+	if variant.SKU == "" {
+		return errors.New("variant sku is required")
+	}
+	if len(variant.Attributes) == 0 {
+		return errors.New("variant needs at least one attribute (e.g. shade, size, finish)")
+	}
+	if variant.Stock < 0 {
+		return errors.New("variant stock cannot be negative")
+	}
+	if variant.Price != nil && !variant.Price.IsPositive() {
+		return errors.New("variant price must be greater than zero")
+	}
+	return nil
+}
+
+// ADD VARIANT
+func (s *productServiceImpl) AddVariant(productID string, variant models.ProductVariant) (*models.Product, error) {
+	objID, err := primitive.ObjectIDFromHex(productID)
+	if err != nil {
+		return nil, errors.New("invalid product ID")
+	}
+	if err := validateVariant(variant); err != nil {
+		return nil, err
+	}
+
+	product, err := s.productRepo.FindByID(objID)
+	if err != nil {
+		return nil, services.ErrProductNotFound
+	}
+
+	// The first variant takes over the stock; product-level stock is no longer used
+	if len(product.Variants) == 0 && product.Stock != 0 {
+		if err := s.productRepo.Update(objID, bson.M{"stock": 0}); err != nil {
+			return nil, err
+		}
+	}
+
+	variant.ID = primitive.NewObjectID()
+	if err := s.productRepo.AddVariant(objID, variant); err != nil {
+		return nil, err
+	}
+
+	return s.productRepo.FindByID(objID)
+}
+
+// UPDATE VARIANT
+func (s *productServiceImpl) UpdateVariant(productID, variantID string, variant models.ProductVariant) (*models.Product, error) {
+	objID, err := primitive.ObjectIDFromHex(productID)
+	if err != nil {
+		return nil, errors.New("invalid product ID")
+	}
+	varID, err := primitive.ObjectIDFromHex(variantID)
+	if err != nil {
+		return nil, errors.New("invalid variant ID")
+	}
+	if err := validateVariant(variant); err != nil {
+		return nil, err
+	}
+
+	product, err := s.productRepo.FindByID(objID)
+	if err != nil {
+		return nil, services.ErrProductNotFound
+	}
+	existing, err := product.ResolveVariant(varID)
+	if err != nil {
+		return nil, err
+	}
+
+	variant.ID = varID
+	if err := s.productRepo.ReplaceVariant(objID, variant, existing.Stock); err != nil {
+		return nil, err
+	}
+
+	return s.productRepo.FindByID(objID)
+}
+
+// DELETE VARIANT
+func (s *productServiceImpl) DeleteVariant(productID, variantID string) error {
+	objID, err := primitive.ObjectIDFromHex(productID)
+	if err != nil {
+		return errors.New("invalid product ID")
+	}
+	varID, err := primitive.ObjectIDFromHex(variantID)
+	if err != nil {
+		return errors.New("invalid variant ID")
+	}
+
+	product, err := s.productRepo.FindByID(objID)
+	if err != nil {
+		return services.ErrProductNotFound
+	}
+	existing, err := product.ResolveVariant(varID)
+	if err != nil {
+		return err
+	}
+
+	return s.productRepo.RemoveVariant(objID, *existing)
+}
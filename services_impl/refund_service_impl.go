@@ -0,0 +1,296 @@
+package servicesimpl
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/repositories"
+	"beauty-ecommerce-backend/services"
+	"beauty-ecommerce-backend/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var _ services.RefundService = (*refundServiceImpl)(nil)
+
+// refundRequested marks a refund the provider has not answered yet
+const refundRequested = "requested"
+
+type refundServiceImpl struct {
+	orderRepo *repositories.OrderRepository
+	orders    *orderServiceImpl // order status changes go through its state machine
+	payments  services.PaymentProvider
+	uow       *repositories.UnitOfWork
+}
+
+func NewRefundService(orderRepo *repositories.OrderRepository, orders *orderServiceImpl, payments services.PaymentProvider, uow *repositories.UnitOfWork) *refundServiceImpl {
+	return &refundServiceImpl{
+		orderRepo: orderRepo,
+		orders:    orders,
+		payments:  payments,
+		uow:       uow,
+	}
+}
+
+// refundable reports whether an order in this status has money to give back
+func refundable(status models.OrderStatus) bool {
+	switch status {
+	case models.OrderPaid, models.OrderProcessing, models.OrderShipped, models.OrderDelivered, models.OrderPartiallyRefunded:
+		return true
+	}
+	return false
+}
+
+// refundLine is an order line with what has been refunded of it so far
+type refundLine struct {
+	item        models.OrderItem
+	paid        models.Money
+	refundedQty int
+	refunded    models.Money
+}
+
+// planRefund works out the lines and amount of a new refund, never giving
+// back more than was paid for a line, for shipping or for the order
+func planRefund(order *models.Order, req models.RefundRequest) (models.Refund, error) {
+	zero := models.Money{Currency: order.TotalPrice.Currency}
+	refund := models.Refund{Amount: zero, Shipping: zero}
+
+	balance := order.TotalPrice.Sub(order.RefundedTotal())
+	if !balance.IsPositive() {
+		return refund, errors.New("this order has already been fully refunded")
+	}
+
+	// The same product/variant may appear on several order lines
+	lines := map[string]*refundLine{}
+	var keys []string
+	for _, item := range order.Items {
+		line, ok := lines[item.Key()]
+		if !ok {
+			line = &refundLine{item: item, paid: zero, refunded: zero}
+			line.item.Quantity = 0
+			lines[item.Key()] = line
+			keys = append(keys, item.Key())
+		}
+		line.item.Quantity += item.Quantity
+		line.paid = line.paid.Add(order.LinePaid(item))
+	}
+	shippingLeft := order.ShippingPaid()
+	for _, previous := range order.Refunds {
+		for _, item := range previous.Items {
+			if line, ok := lines[item.Key()]; ok {
+				line.refundedQty += item.Quantity
+				line.refunded = line.refunded.Add(item.Amount)
+			}
+		}
+		shippingLeft = shippingLeft.Sub(previous.Shipping)
+	}
+
+	// refundQuantity refunds qty of a line; the last unit takes whatever is
+	// left so rounding never leaves pennies behind
+	refundQuantity := func(line *refundLine, qty int) models.RefundItem {
+		amount := line.paid.Ratio(int64(qty), int64(line.item.Quantity))
+		if line.refundedQty+qty == line.item.Quantity || !line.refunded.Add(amount).LessThan(line.paid) {
+			amount = line.paid.Sub(line.refunded)
+		}
+		line.refundedQty += qty
+		line.refunded = line.refunded.Add(amount)
+		return models.RefundItem{ProductID: line.item.ProductID, VariantID: line.item.VariantID, Quantity: qty, Amount: amount}
+	}
+
+	if req.IsFull() {
+		for _, key := range keys {
+			line := lines[key]
+			if left := line.item.Quantity - line.refundedQty; left > 0 {
+				refund.Items = append(refund.Items, refundQuantity(line, left))
+			}
+		}
+		if shippingLeft.IsPositive() {
+			refund.Shipping = shippingLeft
+		}
+		refund.Amount = balance
+		return refund, nil
+	}
+
+	for _, requested := range req.Items {
+		if requested.Quantity <= 0 {
+			return refund, errors.New("refund quantities must be positive")
+		}
+		line, ok := lines[requested.Key()]
+		if !ok {
+			return refund, fmt.Errorf("product %s is not on this order", requested.ProductID)
+		}
+		if left := line.item.Quantity - line.refundedQty; requested.Quantity > left {
+			return refund, fmt.Errorf("only %d of product %s left to refund", left, requested.ProductID)
+		}
+		item := refundQuantity(line, requested.Quantity)
+		refund.Items = append(refund.Items, item)
+		refund.Amount = refund.Amount.Add(item.Amount)
+	}
+
+	if req.Shipping {
+		if !shippingLeft.IsPositive() {
+			return refund, errors.New("shipping has already been refunded")
+		}
+		refund.Shipping = shippingLeft
+		refund.Amount = refund.Amount.Add(shippingLeft)
+	}
+
+	if balance.LessThan(refund.Amount) {
+		trimRefund(&refund, refund.Amount.Sub(balance))
+	}
+	if !refund.Amount.IsPositive() {
+		return refund, errors.New("nothing to refund")
+	}
+	return refund, nil
+}
+
+// trimRefund takes excess off a refund that asks for more than is left to
+// give back, from the last item first and then the shipping, so the recorded
+// amounts still add up to what is paid back
+func trimRefund(refund *models.Refund, excess models.Money) {
+	refund.Amount = refund.Amount.Sub(excess)
+	for i := len(refund.Items) - 1; i >= 0 && excess.IsPositive(); i-- {
+		cut := excess.Min(refund.Items[i].Amount)
+		refund.Items[i].Amount = refund.Items[i].Amount.Sub(cut)
+		excess = excess.Sub(cut)
+	}
+	if excess.IsPositive() {
+		refund.Shipping = refund.Shipping.Sub(excess.Min(refund.Shipping))
+	}
+}
+
+// -------------------- REFUND ORDER --------------------
+
+// RefundOrder gives back all or part of an order's payment. The refund is
+// recorded before the provider is called, so a charge.refunded webhook racing
+// with us sees it and leaves the stock alone; if the provider refuses, the
+// entry is removed again. Once the money has moved the order becomes
+// partially_refunded or refunded, the items are optionally restocked and the
+// customer is emailed.
+func (s *refundServiceImpl) RefundOrder(orderID primitive.ObjectID, req models.RefundRequest, adminID primitive.ObjectID) (*models.Order, *models.Refund, error) {
+	refund := models.Refund{
+		ID:        req.RefundID,
+		Status:    refundRequested,
+		Restocked: req.Restock,
+		Reason:    req.Reason,
+		CreatedBy: adminID.Hex(),
+		CreatedAt: time.Now(),
+	}
+
+	if refund.ID.IsZero() {
+		refund.ID = primitive.NewObjectID()
+	}
+	idempotencyKey := req.IdempotencyKey
+	if idempotencyKey == "" {
+		idempotencyKey = "refund-" + refund.ID.Hex()
+	}
+
+	var order *models.Order
+	err := s.uow.Do(context.Background(), func(ctx context.Context) error {
+		current, err := s.orderRepo.Get(ctx, orderID)
+		if err != nil {
+			return services.ErrOrderNotFound
+		}
+		order = current
+
+		if !refundable(order.Status) {
+			return fmt.Errorf("a %s order cannot be refunded", order.Status)
+		}
+		if order.PaymentReference == "" {
+			return errors.New("order has no payment to refund")
+		}
+
+		plan, err := planRefund(order, req)
+		if err != nil {
+			return err
+		}
+		refund.Amount = plan.Amount
+		refund.Items = plan.Items
+		refund.Shipping = plan.Shipping
+		return s.orderRepo.AddRefund(ctx, order.ID, refund)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := s.payments.Refund(context.Background(), services.RefundParams{
+		PaymentReference: order.PaymentReference,
+		Amount:           refund.Amount,
+		Reason:           req.Reason,
+		IdempotencyKey:   idempotencyKey,
+		Metadata:         map[string]string{"order_id": order.ID.Hex(), "refund_id": refund.ID.Hex()},
+	})
+	if err != nil {
+		if removeErr := s.orderRepo.RemoveRefund(context.Background(), order.ID, refund.ID); removeErr != nil {
+			fmt.Println("⚠️ Could not remove failed refund:", refund.ID.Hex(), removeErr)
+		}
+		return nil, nil, fmt.Errorf("refund failed: %w", err)
+	}
+	refund.ProviderID = result.ID
+	refund.Status = result.Status
+
+	var from models.OrderStatus
+	err = s.uow.Do(context.Background(), func(ctx context.Context) error {
+		current, err := s.orderRepo.Get(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		order = current
+		from = order.Status
+
+		if err := s.orderRepo.UpdateRefund(ctx, order.ID, refund); err != nil {
+			return err
+		}
+		for i := range order.Refunds {
+			if order.Refunds[i].ID == refund.ID {
+				order.Refunds[i] = refund
+			}
+		}
+
+		if refund.Restocked {
+			items := make([]models.OrderItem, 0, len(refund.Items))
+			for _, item := range refund.Items {
+				items = append(items, models.OrderItem{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity})
+			}
+			if err := s.orders.returnItems(ctx, items); err != nil {
+				return err
+			}
+		}
+
+		to := models.OrderPartiallyRefunded
+		if !order.RefundedTotal().LessThan(order.TotalPrice) {
+			to = models.OrderRefunded
+		}
+		// The provider's webhook may have moved the order already
+		if order.Status == to || !order.Status.CanTransitionTo(to) {
+			return nil
+		}
+		reason := req.Reason
+		if reason == "" {
+			reason = "refunded " + refund.Amount.Format()
+		}
+		return s.orders.changeStatus(ctx, order, to, statusActor{kind: models.ActorAdmin, id: adminID.Hex()}, reason)
+	})
+	if err != nil {
+		// The money has gone back already, so this needs a person to look at it
+		fmt.Println("❌ Refund issued but not recorded on order:", orderID.Hex(), refund.ProviderID, err)
+		return nil, nil, fmt.Errorf("%w: %s: %w", services.ErrRefundNotRecorded, refund.ProviderID, err)
+	}
+
+	go func() {
+		s.orders.notifyStatusChange(order, from)
+		s.sendRefundEmail(order, &refund)
+	}()
+	return order, &refund, nil
+}
+
+func (s *refundServiceImpl) sendRefundEmail(order *models.Order, refund *models.Refund) {
+	user, err := s.orders.userRepo.FindById(order.UserID.Hex())
+	if err != nil {
+		fmt.Println("⚠️ Could not find user for refund email:", err)
+		return
+	}
+	utils.SendRefundEmail(user.Email, user.Name, order, refund)
+}
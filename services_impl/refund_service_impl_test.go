@@ -0,0 +1,79 @@
+package servicesimpl
+
+import (
+	"testing"
+
+	"beauty-ecommerce-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanRefund(t *testing.T) {
+	// Three lipsticks at £3.33 with 10p off, a serum at £20 and £3.99 shipping
+	order := &models.Order{
+		Items: []models.OrderItem{
+			{ProductID: "lipstick", Quantity: 3, Price: models.GBP(333), DiscountAmount: models.GBP(10)},
+			{ProductID: "serum", Quantity: 1, Price: models.GBP(2000)},
+		},
+		TotalPrice: models.GBP(999 - 10 + 2000 + 399),
+	}
+
+	t.Run("full", func(t *testing.T) {
+		refund, err := planRefund(order, models.RefundRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, order.TotalPrice, refund.Amount)
+		assert.Equal(t, models.GBP(399), refund.Shipping)
+		assert.Len(t, refund.Items, 2)
+	})
+
+	t.Run("one line and shipping", func(t *testing.T) {
+		refund, err := planRefund(order, models.RefundRequest{
+			Items:    []models.RefundItem{{ProductID: "serum", Quantity: 1}},
+			Shipping: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, models.GBP(2399), refund.Amount)
+	})
+
+	t.Run("unit by unit adds up to the line", func(t *testing.T) {
+		partial := *order
+		var total int64
+		for i := 0; i < 3; i++ {
+			refund, err := planRefund(&partial, models.RefundRequest{Items: []models.RefundItem{{ProductID: "lipstick", Quantity: 1}}})
+			require.NoError(t, err)
+			total += refund.Amount.Amount
+			partial.Refunds = append(partial.Refunds, refund)
+		}
+		assert.Equal(t, int64(989), total)
+
+		_, err := planRefund(&partial, models.RefundRequest{Items: []models.RefundItem{{ProductID: "lipstick", Quantity: 1}}})
+		assert.Error(t, err)
+	})
+
+	t.Run("capped at the balance", func(t *testing.T) {
+		// A £10 goodwill refund leaves less than the serum and shipping cost
+		goodwill := *order
+		goodwill.Refunds = []models.Refund{{Amount: models.GBP(1000)}}
+		refund, err := planRefund(&goodwill, models.RefundRequest{
+			Items:    []models.RefundItem{{ProductID: "serum", Quantity: 1}},
+			Shipping: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, models.GBP(2388), refund.Amount)
+		assert.Equal(t, models.GBP(1989), refund.Items[0].Amount)
+		assert.Equal(t, models.GBP(399), refund.Shipping)
+	})
+
+	t.Run("unknown product", func(t *testing.T) {
+		_, err := planRefund(order, models.RefundRequest{Items: []models.RefundItem{{ProductID: "mascara", Quantity: 1}}})
+		assert.Error(t, err)
+	})
+
+	t.Run("nothing left", func(t *testing.T) {
+		refunded := *order
+		refunded.Refunds = []models.Refund{{Amount: order.TotalPrice}}
+		_, err := planRefund(&refunded, models.RefundRequest{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,363 @@
+package servicesimpl
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/repositories"
+	"beauty-ecommerce-backend/services"
+	"beauty-ecommerce-backend/utils"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var _ services.ReturnService = (*returnServiceImpl)(nil)
+
+type returnServiceImpl struct {
+	returnRepo *repositories.ReturnRepository
+	orderRepo  *repositories.OrderRepository
+	userRepo   *repositories.UserRepository
+	refunds    services.RefundService
+	uow        *repositories.UnitOfWork
+}
+
+func NewReturnService(returnRepo *repositories.ReturnRepository, orderRepo *repositories.OrderRepository, userRepo *repositories.UserRepository, refunds services.RefundService, uow *repositories.UnitOfWork) *returnServiceImpl {
+	return &returnServiceImpl{
+		returnRepo: returnRepo,
+		orderRepo:  orderRepo,
+		userRepo:   userRepo,
+		refunds:    refunds,
+		uow:        uow,
+	}
+}
+
+// returnWindow is how long after delivery a customer can ask for a return
+func returnWindow() time.Duration {
+	if days, err := strconv.Atoi(os.Getenv("RETURN_WINDOW_DAYS")); err == nil && days > 0 {
+		return time.Duration(days) * 24 * time.Hour
+	}
+	return 30 * 24 * time.Hour
+}
+
+// returnableOrder reports whether the order's goods have been sent out
+func returnableOrder(status models.OrderStatus) bool {
+	switch status {
+	case models.OrderShipped, models.OrderDelivered, models.OrderPartiallyRefunded:
+		return true
+	}
+	return false
+}
+
+// deliveredAt is when the order was last marked delivered, if ever
+func deliveredAt(order *models.Order) *time.Time {
+	for i := len(order.StatusHistory) - 1; i >= 0; i-- {
+		if order.StatusHistory[i].To == models.OrderDelivered {
+			return &order.StatusHistory[i].At
+		}
+	}
+	return nil
+}
+
+// returnableItems is, per order line, how many units the customer can still
+// send back: what was shipped, less what open returns already cover and what
+// was refunded outside a return
+func returnableItems(order *models.Order, returns []models.Return) map[string]int {
+	available := map[string]int{}
+	if len(order.Shipments) == 0 {
+		// Orders marked shipped by hand have no shipment records
+		for _, item := range order.Items {
+			available[item.Key()] += item.Quantity
+		}
+	} else {
+		for _, shipment := range order.Shipments {
+			for _, item := range shipment.Items {
+				available[item.Key()] += item.Quantity
+			}
+		}
+	}
+
+	settled := map[primitive.ObjectID]bool{}
+	for _, ret := range returns {
+		if ret.RefundID != nil {
+			settled[*ret.RefundID] = true
+		}
+		if !ret.Status.Open() {
+			continue
+		}
+		for _, item := range ret.Items {
+			available[item.Key()] -= item.Quantity
+		}
+	}
+	for _, refund := range order.Refunds {
+		if settled[refund.ID] {
+			continue
+		}
+		for _, item := range refund.Items {
+			available[item.Key()] -= item.Quantity
+		}
+	}
+	return available
+}
+
+// -------------------- REQUEST RETURN --------------------
+
+// RequestReturn opens a return for items the customer has received
+func (s *returnServiceImpl) RequestReturn(orderID, userID primitive.ObjectID, req models.ReturnRequest) (*models.Return, error) {
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return nil, errors.New("please tell us why you are returning these items")
+	}
+	if len(req.Items) == 0 {
+		return nil, errors.New("choose at least one item to return")
+	}
+
+	now := time.Now()
+	ret := &models.Return{
+		ID:      primitive.NewObjectID(),
+		OrderID: orderID,
+		UserID:  userID,
+		Reason:  req.Reason,
+		Comment: strings.TrimSpace(req.Comment),
+		Status:  models.ReturnRequested,
+		StatusHistory: []models.ReturnStatusChange{{
+			To:      models.ReturnRequested,
+			Actor:   models.ActorCustomer,
+			ActorID: userID.Hex(),
+			At:      now,
+		}},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	var order *models.Order
+	err := s.uow.Do(context.Background(), func(ctx context.Context) error {
+		current, err := s.orderRepo.Get(ctx, orderID)
+		if err != nil || current.UserID != userID {
+			return services.ErrOrderNotFound
+		}
+		order = current
+
+		if !returnableOrder(order.Status) {
+			return fmt.Errorf("a %s order cannot be returned", order.Status)
+		}
+		if delivered := deliveredAt(order); delivered != nil && now.Sub(*delivered) > returnWindow() {
+			return fmt.Errorf("returns must be requested within %d days of delivery", int(returnWindow().Hours()/24))
+		}
+
+		existing, err := s.returnRepo.FindByOrder(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		available := returnableItems(order, existing)
+
+		ret.Items = ret.Items[:0]
+		for _, item := range req.Items {
+			if item.Quantity <= 0 {
+				return errors.New("return quantities must be positive")
+			}
+			left, ok := available[item.Key()]
+			if !ok {
+				return fmt.Errorf("product %s is not on this order", item.ProductID)
+			}
+			if item.Quantity > left {
+				return fmt.Errorf("only %d of product %s can be returned", max(left, 0), item.ProductID)
+			}
+			available[item.Key()] = left - item.Quantity
+			ret.Items = append(ret.Items, item)
+		}
+
+		return s.returnRepo.Create(ctx, ret)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go s.notifyReturn(ret, order, "")
+	go s.notifyAdminReturnRequested(ret, order)
+	return ret, nil
+}
+
+// GetOrderReturns lists the returns on one of the customer's orders
+func (s *returnServiceImpl) GetOrderReturns(orderID, userID primitive.ObjectID) ([]models.Return, error) {
+	order, err := s.orderRepo.FindByID(orderID)
+	if err != nil || order.UserID != userID {
+		return nil, services.ErrOrderNotFound
+	}
+	return s.returnRepo.FindByOrder(context.Background(), orderID)
+}
+
+// -------------------- ADMIN --------------------
+
+func (s *returnServiceImpl) ListReturns(status models.ReturnStatus) ([]models.Return, error) {
+	if status != "" && !status.Valid() {
+		return nil, fmt.Errorf("unknown return status %q", status)
+	}
+	return s.returnRepo.FindAll(status)
+}
+
+func (s *returnServiceImpl) GetReturn(id primitive.ObjectID) (*models.Return, error) {
+	ret, err := s.returnRepo.Get(context.Background(), id)
+	if err != nil {
+		return nil, services.ErrReturnNotFound
+	}
+	return ret, nil
+}
+
+// ApproveReturn lets the customer send the goods back
+func (s *returnServiceImpl) ApproveReturn(id, adminID primitive.ObjectID, note string) (*models.Return, error) {
+	return s.changeStatus(id, models.ReturnApproved, adminID, note, nil)
+}
+
+// RejectReturn turns the return down; note tells the customer why
+func (s *returnServiceImpl) RejectReturn(id, adminID primitive.ObjectID, note string) (*models.Return, error) {
+	if strings.TrimSpace(note) == "" {
+		return nil, errors.New("please give the customer a reason for rejecting the return")
+	}
+	return s.changeStatus(id, models.ReturnRejected, adminID, note, nil)
+}
+
+// MarkReturnReceived records that the goods are back at the warehouse
+func (s *returnServiceImpl) MarkReturnReceived(id, adminID primitive.ObjectID, note string) (*models.Return, error) {
+	return s.changeStatus(id, models.ReturnReceived, adminID, note, nil)
+}
+
+// RefundReturn refunds the returned items through the order's refund flow,
+// optionally putting them back into stock, and closes the return
+func (s *returnServiceImpl) RefundReturn(id, adminID primitive.ObjectID, restock bool) (*models.Return, *models.Order, error) {
+	// Claim the return with the refund's ID first, so a second or retried call
+	// finds it refunding and cannot pay out again
+	refundID := primitive.NewObjectID()
+	ret, err := s.changeStatus(id, models.ReturnRefunding, adminID, "", bson.M{"refund_id": refundID})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	items := make([]models.RefundItem, 0, len(ret.Items))
+	for _, item := range ret.Items {
+		items = append(items, models.RefundItem{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity})
+	}
+	order, refund, err := s.refunds.RefundOrder(ret.OrderID, models.RefundRequest{
+		Items:          items,
+		Restock:        restock,
+		Reason:         "return " + ret.ID.Hex() + ": " + ret.Reason,
+		RefundID:       refundID,
+		IdempotencyKey: "return-" + ret.ID.Hex(),
+	}, adminID)
+	if errors.Is(err, services.ErrRefundNotRecorded) {
+		// The money has moved, so the return stays claimed for a person to finish
+		fmt.Println("❌ Return refunded but left refunding:", id.Hex(), err)
+		return nil, nil, err
+	}
+	if err != nil {
+		s.releaseRefundClaim(ret, adminID, err)
+		return nil, nil, err
+	}
+
+	// The refund email goes out from the refund itself
+	ret, err = s.changeStatus(id, models.ReturnRefunded, adminID, "refunded "+refund.Amount.Format(), nil)
+	if err != nil {
+		fmt.Println("❌ Return refunded but not closed:", id.Hex(), err)
+		return nil, nil, err
+	}
+	return ret, order, nil
+}
+
+// releaseRefundClaim puts a return whose refund was never paid back to
+// received, so it can be refunded again
+func (s *returnServiceImpl) releaseRefundClaim(ret *models.Return, adminID primitive.ObjectID, cause error) {
+	fmt.Println("⚠️ Refund for return", ret.ID.Hex(), "failed:", cause)
+	change := models.ReturnStatusChange{
+		From:    models.ReturnRefunding,
+		To:      models.ReturnReceived,
+		Actor:   models.ActorAdmin,
+		ActorID: adminID.Hex(),
+		Note:    "the refund could not be completed",
+		At:      time.Now(),
+	}
+	ok, err := s.returnRepo.TransitionStatus(context.Background(), ret.ID, change, bson.M{"refund_id": nil})
+	if err != nil || !ok {
+		fmt.Println("⚠️ Could not release refunding return:", ret.ID.Hex(), err)
+	}
+}
+
+// changeStatus moves a return along its state machine, recording who did it
+func (s *returnServiceImpl) changeStatus(id primitive.ObjectID, to models.ReturnStatus, adminID primitive.ObjectID, note string, set bson.M) (*models.Return, error) {
+	ret, err := s.GetReturn(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.CheckReturnTransition(ret.Status, to); err != nil {
+		return nil, err
+	}
+
+	change := models.ReturnStatusChange{
+		From:    ret.Status,
+		To:      to,
+		Actor:   models.ActorAdmin,
+		ActorID: adminID.Hex(),
+		Note:    strings.TrimSpace(note),
+		At:      time.Now(),
+	}
+	ok, err := s.returnRepo.TransitionStatus(context.Background(), id, change, set)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("return changed while moving it to %s, please retry", to)
+	}
+
+	ret.Status = to
+	ret.UpdatedAt = change.At
+	ret.StatusHistory = append(ret.StatusHistory, change)
+	if refundID, ok := set["refund_id"].(primitive.ObjectID); ok {
+		ret.RefundID = &refundID
+	}
+
+	if to != models.ReturnRefunding && to != models.ReturnRefunded {
+		go s.notifyReturn(ret, nil, change.Note)
+	}
+	return ret, nil
+}
+
+// -------------------- NOTIFICATIONS --------------------
+func (s *returnServiceImpl) notifyReturn(ret *models.Return, order *models.Order, note string) {
+	user, err := s.userRepo.FindById(ret.UserID.Hex())
+	if err != nil {
+		fmt.Println("⚠️ Could not find user for return email:", err)
+		return
+	}
+	if order == nil {
+		if order, err = s.orderRepo.FindByID(ret.OrderID); err != nil {
+			fmt.Println("⚠️ Could not find order for return email:", err)
+			return
+		}
+	}
+	utils.SendReturnEmail(user.Email, user.Name, order, ret, note)
+}
+
+func (s *returnServiceImpl) notifyAdminReturnRequested(ret *models.Return, order *models.Order) {
+	adminEmail := os.Getenv("ADMIN_EMAIL")
+	if adminEmail == "" {
+		return
+	}
+	subject := fmt.Sprintf("↩️ Return Requested - %s", order.ID.Hex())
+	// The reason and comment are the customer's own words
+	reason, comment := html.EscapeString(ret.Reason), html.EscapeString(ret.Comment)
+	body := fmt.Sprintf(`
+		<h2>Return Requested</h2>
+		<p><strong>Customer:</strong> %s (%s)</p>
+		<p><strong>Order ID:</strong> %s</p>
+		<p><strong>Return ID:</strong> %s</p>
+		<p><strong>Reason:</strong> %s</p>
+		<p>%s</p>
+		<h3>Items</h3><ul>%s</ul>
+	`, order.CustomerName, order.CustomerEmail, order.ID.Hex(), ret.ID.Hex(), reason, comment, utils.ReturnItemsHTML(order, ret))
+	utils.QueueEmail(adminEmail, "Admin", subject, body)
+}
@@ -0,0 +1,49 @@
+package servicesimpl
+
+import (
+	"testing"
+
+	"beauty-ecommerce-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReturnableItems(t *testing.T) {
+	settledRefund := primitive.NewObjectID()
+	order := &models.Order{
+		Items: []models.OrderItem{
+			{ProductID: "lipstick", Quantity: 3},
+			{ProductID: "serum", Quantity: 2},
+		},
+		Shipments: []models.Shipment{
+			{Items: []models.ShipmentItem{{ProductID: "lipstick", Quantity: 3}, {ProductID: "serum", Quantity: 1}}},
+		},
+		Refunds: []models.Refund{
+			{ID: settledRefund, Items: []models.RefundItem{{ProductID: "lipstick", Quantity: 1}}},
+			{ID: primitive.NewObjectID(), Items: []models.RefundItem{{ProductID: "serum", Quantity: 1}}},
+		},
+	}
+	returns := []models.Return{
+		{Status: models.ReturnRefunded, RefundID: &settledRefund, Items: []models.ReturnItem{{ProductID: "lipstick", Quantity: 1}}},
+		{Status: models.ReturnRejected, Items: []models.ReturnItem{{ProductID: "lipstick", Quantity: 2}}},
+	}
+
+	available := returnableItems(order, returns)
+
+	// One lipstick came back through a return; the rejected return frees its items
+	assert.Equal(t, 2, available["lipstick/"])
+	// Only one serum shipped and a refund outside any return covered it
+	assert.Equal(t, 0, available["serum/"])
+}
+
+func TestReturnRefundClaim(t *testing.T) {
+	// A received return must be claimed before it can be refunded
+	assert.Error(t, models.CheckReturnTransition(models.ReturnReceived, models.ReturnRefunded))
+	assert.NoError(t, models.CheckReturnTransition(models.ReturnReceived, models.ReturnRefunding))
+	assert.NoError(t, models.CheckReturnTransition(models.ReturnRefunding, models.ReturnRefunded))
+
+	// Only one caller can claim it, and nobody can put it back by hand
+	assert.Error(t, models.CheckReturnTransition(models.ReturnRefunding, models.ReturnRefunding))
+	assert.Error(t, models.CheckReturnTransition(models.ReturnRefunding, models.ReturnReceived))
+}
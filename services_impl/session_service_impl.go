@@ -0,0 +1,134 @@
+package servicesimpl
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/repositories"
+	"beauty-ecommerce-backend/services"
+	"beauty-ecommerce-backend/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var _ services.SessionService = (*sessionServiceImpl)(nil)
+
+const (
+	// refreshTokenTTL is how long a session lasts without being refreshed
+	refreshTokenTTL = 30 * 24 * time.Hour
+
+	// refreshReuseGrace lets a client that sent the same refresh token twice
+	// at once, e.g. from two tabs, off without losing the session
+	refreshReuseGrace = 10 * time.Second
+)
+
+type sessionServiceImpl struct {
+	sessionRepo *repositories.SessionRepository
+	userRepo    *repositories.UserRepository
+}
+
+func NewSessionService(sessionRepo *repositories.SessionRepository, userRepo *repositories.UserRepository) *sessionServiceImpl {
+	return &sessionServiceImpl{
+		sessionRepo: sessionRepo,
+		userRepo:    userRepo,
+	}
+}
+
+// -------------------- START --------------------
+func (s *sessionServiceImpl) StartSession(user *models.User, client services.SessionClient) (*services.TokenPair, error) {
+	refreshToken := utils.GenerateRandomToken(32)
+	now := time.Now()
+	session := &models.Session{
+		ID:         primitive.NewObjectID(),
+		UserID:     user.ID,
+		TokenHash:  utils.HashToken(refreshToken),
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(refreshTokenTTL),
+	}
+	if err := s.sessionRepo.Create(context.Background(), session); err != nil {
+		return nil, errors.New("failed to start session")
+	}
+	return tokenPair(user, refreshToken)
+}
+
+func tokenPair(user *models.User, refreshToken string) (*services.TokenPair, error) {
+	accessToken, err := utils.GenerateToken(user.ID, user.Email, user.Role, user.TokenVersion)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+	return &services.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(utils.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// -------------------- REFRESH --------------------
+func (s *sessionServiceImpl) Refresh(refreshToken string, client services.SessionClient) (*services.TokenPair, error) {
+	if refreshToken == "" {
+		return nil, services.ErrInvalidRefreshToken
+	}
+	ctx := context.Background()
+	hash := utils.HashToken(refreshToken)
+
+	next := utils.GenerateRandomToken(32)
+	session, err := s.sessionRepo.Rotate(ctx, hash, utils.HashToken(next), time.Now().Add(refreshTokenTTL))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		s.detectReuse(ctx, hash)
+		return nil, services.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The role or token version may have changed since the last refresh
+	user, err := s.userRepo.FindByID(session.UserID)
+	if err != nil {
+		_ = s.sessionRepo.Revoke(ctx, session.ID)
+		return nil, services.ErrInvalidRefreshToken
+	}
+	return tokenPair(user, next)
+}
+
+// detectReuse ends the session a replaced refresh token belonged to. Only a
+// copy of the token can still be sending it, unless the client raced itself.
+func (s *sessionServiceImpl) detectReuse(ctx context.Context, hash string) {
+	session, err := s.sessionRepo.FindByPreviousHash(ctx, hash)
+	if err != nil || session.RevokedAt != nil {
+		return
+	}
+	if time.Since(session.LastUsedAt) < refreshReuseGrace {
+		return
+	}
+	fmt.Println("⚠️ Refresh token reused, ending session:", session.ID.Hex(), "user:", session.UserID.Hex())
+	if err := s.sessionRepo.Revoke(ctx, session.ID); err != nil {
+		fmt.Println("❌ Could not end session:", session.ID.Hex(), err)
+	}
+}
+
+// -------------------- LOGOUT --------------------
+
+// Logout does not fail for an unknown token, so logging out twice is fine.
+// The session's access token stays valid until it expires.
+func (s *sessionServiceImpl) Logout(refreshToken string) error {
+	if refreshToken == "" {
+		return errors.New("refresh token is required")
+	}
+	_, err := s.sessionRepo.RevokeByTokenHash(context.Background(), utils.HashToken(refreshToken))
+	return err
+}
+
+func (s *sessionServiceImpl) RevokeAll(userID primitive.ObjectID) error {
+	ctx := context.Background()
+	// Bump the version first: access tokens are what can still be used now
+	if err := s.userRepo.BumpTokenVersion(ctx, userID); err != nil {
+		return err
+	}
+	return s.sessionRepo.RevokeAllForUser(ctx, userID)
+}
@@ -0,0 +1,332 @@
+package servicesimpl
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/repositories"
+	"beauty-ecommerce-backend/services"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var _ services.ShipmentService = (*shipmentServiceImpl)(nil)
+
+type shipmentServiceImpl struct {
+	orderRepo *repositories.OrderRepository
+	orders    *orderServiceImpl // order status changes go through its state machine
+	carriers  map[string]services.Carrier
+	uow       *repositories.UnitOfWork
+}
+
+func NewShipmentService(orderRepo *repositories.OrderRepository, orders *orderServiceImpl, uow *repositories.UnitOfWork, carriers ...services.Carrier) *shipmentServiceImpl {
+	registry := make(map[string]services.Carrier, len(carriers))
+	for _, carrier := range carriers {
+		registry[strings.ToLower(carrier.Code())] = carrier
+	}
+	return &shipmentServiceImpl{
+		orderRepo: orderRepo,
+		orders:    orders,
+		carriers:  registry,
+		uow:       uow,
+	}
+}
+
+// Carriers lists the carriers with an integration
+func (s *shipmentServiceImpl) Carriers() []string {
+	codes := make([]string, 0, len(s.carriers))
+	for code := range s.carriers {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// shippable reports whether an order in this status can have items sent out
+func shippable(status models.OrderStatus) bool {
+	switch status {
+	case models.OrderPaid, models.OrderProcessing, models.OrderShipped, models.OrderPartiallyRefunded:
+		return true
+	}
+	return false
+}
+
+// planShipment works out the items a new shipment carries. With no items
+// requested it takes everything not yet shipped.
+func planShipment(order *models.Order, requested []models.ShipmentItem) ([]models.ShipmentItem, error) {
+	remaining := order.UnshippedItems()
+
+	if len(requested) == 0 {
+		var items []models.ShipmentItem
+		for _, line := range order.Items {
+			if qty := remaining[line.Key()]; qty > 0 {
+				items = append(items, models.ShipmentItem{ProductID: line.ProductID, VariantID: line.VariantID, Quantity: qty})
+				remaining[line.Key()] = 0
+			}
+		}
+		if len(items) == 0 {
+			return nil, errors.New("every item on this order has already shipped")
+		}
+		return items, nil
+	}
+
+	items := make([]models.ShipmentItem, 0, len(requested))
+	for _, item := range requested {
+		if item.Quantity <= 0 {
+			return nil, errors.New("shipment quantities must be positive")
+		}
+		left, ok := remaining[item.Key()]
+		if !ok {
+			return nil, fmt.Errorf("product %s is not on this order", item.ProductID)
+		}
+		if item.Quantity > left {
+			return nil, fmt.Errorf("only %d of product %s left to ship", left, item.ProductID)
+		}
+		remaining[item.Key()] = left - item.Quantity
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// -------------------- CREATE SHIPMENT --------------------
+
+// CreateShipment sends some or all of an order's items. A registered carrier
+// books the label when no tracking number is given. The first partial
+// shipment moves a paid order to processing and the one that completes the
+// order moves it to shipped. The customer gets an email per shipment.
+func (s *shipmentServiceImpl) CreateShipment(orderID primitive.ObjectID, req models.ShipmentRequest, adminID primitive.ObjectID) (*models.Order, *models.Shipment, error) {
+	req.Carrier = strings.ToLower(strings.TrimSpace(req.Carrier))
+	req.TrackingNumber = strings.TrimSpace(req.TrackingNumber)
+	if req.Carrier == "" {
+		return nil, nil, errors.New("carrier is required")
+	}
+	carrier := s.carriers[req.Carrier]
+	if carrier == nil && req.TrackingNumber == "" {
+		return nil, nil, fmt.Errorf("tracking number is required for %s shipments", req.Carrier)
+	}
+
+	order, err := s.orderRepo.FindByID(orderID)
+	if err != nil {
+		return nil, nil, services.ErrOrderNotFound
+	}
+	if !shippable(order.Status) {
+		return nil, nil, fmt.Errorf("a %s order cannot be shipped", order.Status)
+	}
+	items, err := planShipment(order, req.Items)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	shipment := models.Shipment{
+		ID:             primitive.NewObjectID(),
+		Items:          items,
+		Carrier:        req.Carrier,
+		Service:        req.Service,
+		TrackingNumber: req.TrackingNumber,
+		TrackingURL:    strings.TrimSpace(req.TrackingURL),
+		Status:         models.ShipmentLabelCreated,
+		CreatedBy:      adminID.Hex(),
+		ShippedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	// Booking a label is a call to the carrier, so it happens before the
+	// transaction, which may be retried
+	if carrier != nil && shipment.TrackingNumber == "" {
+		label, err := carrier.CreateLabel(context.Background(), order, &shipment)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not create %s label: %w", req.Carrier, err)
+		}
+		shipment.TrackingNumber = label.TrackingNumber
+		shipment.LabelURL = label.LabelURL
+		if shipment.TrackingURL == "" {
+			shipment.TrackingURL = label.TrackingURL
+		}
+	}
+	shipment.Events = []models.TrackingEvent{{
+		Status:      models.ShipmentLabelCreated,
+		Description: "Shipment created",
+		At:          now,
+	}}
+
+	var from models.OrderStatus
+	err = s.uow.Do(context.Background(), func(ctx context.Context) error {
+		current, err := s.orderRepo.Get(ctx, orderID)
+		if err != nil {
+			return services.ErrOrderNotFound
+		}
+		order = current
+		from = order.Status
+
+		// Another admin may have shipped the same items since we looked
+		if !shippable(order.Status) {
+			return fmt.Errorf("a %s order cannot be shipped", order.Status)
+		}
+		if _, err := planShipment(order, shipment.Items); err != nil {
+			return err
+		}
+
+		if err := s.orderRepo.AddShipment(ctx, order.ID, shipment); err != nil {
+			return err
+		}
+		order.Shipments = append(order.Shipments, shipment)
+
+		actor := statusActor{kind: models.ActorAdmin, id: adminID.Hex()}
+		switch {
+		case order.FullyShipped() && order.Status != models.OrderShipped:
+			return s.orders.changeStatus(ctx, order, models.OrderShipped, actor, "all items shipped")
+		case order.Status == models.OrderPaid:
+			return s.orders.changeStatus(ctx, order, models.OrderProcessing, actor, "partially shipped")
+		}
+		return nil
+	})
+	if err != nil {
+		if shipment.LabelURL != "" {
+			fmt.Println("⚠️ Shipment not saved, void this label with the carrier:", shipment.TrackingNumber)
+		}
+		return nil, nil, err
+	}
+
+	go func() {
+		s.orders.notifyStatusChange(order, from)
+		s.orders.SendShippedEmail(order, &shipment)
+	}()
+	return order, &shipment, nil
+}
+
+// -------------------- TRACKING --------------------
+
+// UpdateShipmentStatus lets an admin record progress for a carrier we cannot poll
+func (s *shipmentServiceImpl) UpdateShipmentStatus(orderID, shipmentID primitive.ObjectID, status models.ShipmentStatus, description string, adminID primitive.ObjectID) (*models.Order, error) {
+	if !status.Valid() {
+		return nil, fmt.Errorf("unknown shipment status %q", status)
+	}
+	if description == "" {
+		description = "Updated by the shop"
+	}
+
+	update := &services.TrackingUpdate{Status: status}
+	actor := statusActor{kind: models.ActorAdmin, id: adminID.Hex()}
+	return s.applyTracking(orderID, shipmentID, update, description, actor)
+}
+
+// applyTracking records a tracking update on a shipment and, once everything
+// has arrived, moves the order to delivered
+func (s *shipmentServiceImpl) applyTracking(orderID, shipmentID primitive.ObjectID, update *services.TrackingUpdate, description string, actor statusActor) (*models.Order, error) {
+	var order *models.Order
+	var from models.OrderStatus
+	err := s.uow.Do(context.Background(), func(ctx context.Context) error {
+		current, err := s.orderRepo.Get(ctx, orderID)
+		if err != nil {
+			return services.ErrOrderNotFound
+		}
+		order = current
+		from = order.Status
+
+		var shipment *models.Shipment
+		for i := range order.Shipments {
+			if order.Shipments[i].ID == shipmentID {
+				shipment = &order.Shipments[i]
+				break
+			}
+		}
+		if shipment == nil {
+			return services.ErrShipmentNotFound
+		}
+
+		now := time.Now()
+		if len(update.Events) > 0 {
+			// Carriers report the whole scan history each time
+			shipment.Events = update.Events
+		} else {
+			shipment.Events = append(shipment.Events, models.TrackingEvent{Status: update.Status, Description: description, At: now})
+		}
+		shipment.Status = update.Status
+		shipment.LastCheckedAt = &now
+		shipment.UpdatedAt = now
+		if update.Status == models.ShipmentDelivered && shipment.DeliveredAt == nil {
+			shipment.DeliveredAt = &now
+		}
+		if err := s.orderRepo.UpdateShipment(ctx, order.ID, *shipment); err != nil {
+			return err
+		}
+
+		if order.Status != models.OrderDelivered && order.Status.CanTransitionTo(models.OrderDelivered) && order.FullyDelivered() {
+			return s.orders.changeStatus(ctx, order, models.OrderDelivered, actor, "all shipments delivered")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go s.orders.notifyStatusChange(order, from)
+	return order, nil
+}
+
+// RefreshTracking asks the carriers about every parcel still on its way
+func (s *shipmentServiceImpl) RefreshTracking() (int, error) {
+	if len(s.carriers) == 0 {
+		return 0, nil
+	}
+
+	orders, err := s.orderRepo.FindUndeliveredShipments(s.Carriers(), 200)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, order := range orders {
+		for _, shipment := range order.Shipments {
+			carrier := s.carriers[shipment.Carrier]
+			if carrier == nil || shipment.Status == models.ShipmentDelivered {
+				continue
+			}
+
+			update, err := carrier.Track(context.Background(), shipment.TrackingNumber)
+			if err != nil {
+				fmt.Println("⚠️ Could not track shipment:", shipment.TrackingNumber, err)
+				continue
+			}
+			if update.Status == shipment.Status && len(update.Events) == len(shipment.Events) {
+				continue
+			}
+
+			if _, err := s.applyTracking(order.ID, shipment.ID, update, "", systemActor()); err != nil {
+				fmt.Println("⚠️ Could not save tracking for shipment:", shipment.TrackingNumber, err)
+				continue
+			}
+			changed++
+		}
+	}
+
+	return changed, nil
+}
+
+// StartTrackingPoller periodically refreshes tracking from the carriers
+func (s *shipmentServiceImpl) StartTrackingPoller(interval time.Duration) {
+	if len(s.carriers) == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			count, err := s.RefreshTracking()
+			if err != nil {
+				fmt.Println("⚠️ Tracking refresh failed:", err)
+				continue
+			}
+			if count > 0 {
+				fmt.Printf("🚚 Updated tracking for %d shipment(s)\n", count)
+			}
+		}
+	}()
+}
@@ -0,0 +1,87 @@
+package servicesimpl
+
+import (
+	"context"
+	"testing"
+
+	"beauty-ecommerce-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanShipment(t *testing.T) {
+	order := &models.Order{
+		Items: []models.OrderItem{
+			{ProductID: "lipstick", VariantID: "ruby", Quantity: 2},
+			{ProductID: "serum", Quantity: 1},
+		},
+		Shipments: []models.Shipment{
+			{Items: []models.ShipmentItem{{ProductID: "lipstick", VariantID: "ruby", Quantity: 1}}},
+		},
+	}
+
+	t.Run("defaults to everything left", func(t *testing.T) {
+		items, err := planShipment(order, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []models.ShipmentItem{
+			{ProductID: "lipstick", VariantID: "ruby", Quantity: 1},
+			{ProductID: "serum", Quantity: 1},
+		}, items)
+	})
+
+	t.Run("partial", func(t *testing.T) {
+		items, err := planShipment(order, []models.ShipmentItem{{ProductID: "serum", Quantity: 1}})
+		require.NoError(t, err)
+		assert.Len(t, items, 1)
+	})
+
+	t.Run("more than is left", func(t *testing.T) {
+		_, err := planShipment(order, []models.ShipmentItem{{ProductID: "lipstick", VariantID: "ruby", Quantity: 2}})
+		assert.Error(t, err)
+	})
+
+	t.Run("wrong variant", func(t *testing.T) {
+		_, err := planShipment(order, []models.ShipmentItem{{ProductID: "lipstick", VariantID: "nude", Quantity: 1}})
+		assert.Error(t, err)
+	})
+
+	t.Run("repeated lines count together", func(t *testing.T) {
+		_, err := planShipment(order, []models.ShipmentItem{{ProductID: "serum", Quantity: 1}, {ProductID: "serum", Quantity: 1}})
+		assert.Error(t, err)
+	})
+
+	t.Run("nothing left", func(t *testing.T) {
+		shipped := *order
+		shipped.Shipments = append(shipped.Shipments, models.Shipment{Items: []models.ShipmentItem{
+			{ProductID: "lipstick", VariantID: "ruby", Quantity: 1},
+			{ProductID: "serum", Quantity: 1},
+		}})
+		assert.True(t, shipped.FullyShipped())
+		_, err := planShipment(&shipped, nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestFakeCarrierTracking(t *testing.T) {
+	carrier := NewFakeCarrier()
+	ctx := context.Background()
+
+	label, err := carrier.CreateLabel(ctx, &models.Order{}, &models.Shipment{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, label.TrackingNumber)
+	assert.Contains(t, label.TrackingURL, label.TrackingNumber)
+
+	update, err := carrier.Track(ctx, label.TrackingNumber)
+	require.NoError(t, err)
+	assert.Equal(t, models.ShipmentLabelCreated, update.Status)
+
+	require.NoError(t, carrier.Advance(label.TrackingNumber, models.ShipmentDelivered, "Left with neighbour"))
+	update, err = carrier.Track(ctx, label.TrackingNumber)
+	require.NoError(t, err)
+	assert.Equal(t, models.ShipmentDelivered, update.Status)
+	assert.Len(t, update.Events, 2)
+
+	_, err = carrier.Track(ctx, "UNKNOWN")
+	assert.Error(t, err)
+}
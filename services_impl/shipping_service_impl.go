@@ -0,0 +1,225 @@
+package servicesimpl
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/repositories"
+	"beauty-ecommerce-backend/services"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var _ services.ShippingService = (*shippingServiceImpl)(nil)
+
+// legacyShippingQuotes are the flat rates used when no zone covers an address
+var legacyShippingQuotes = []models.ShippingQuote{
+	{Code: "standard", Name: "Standard delivery", Price: models.GBP(399), Zone: "default"},
+	{Code: "express", Name: "Express delivery", Price: models.GBP(499), Zone: "default"},
+}
+
+type shippingServiceImpl struct {
+	shippingRepo *repositories.ShippingRepository
+}
+
+func NewShippingService(shippingRepo *repositories.ShippingRepository) *shippingServiceImpl {
+	return &shippingServiceImpl{shippingRepo: shippingRepo}
+}
+
+// -------------------- ADMIN CRUD --------------------
+func (s *shippingServiceImpl) CreateZone(zone models.ShippingZone) (*models.ShippingZone, error) {
+	if err := validateShippingZone(&zone); err != nil {
+		return nil, err
+	}
+
+	zone.ID = primitive.NewObjectID()
+	zone.CreatedAt = time.Now()
+	zone.UpdatedAt = time.Now()
+
+	if err := s.shippingRepo.Create(&zone); err != nil {
+		return nil, err
+	}
+	return &zone, nil
+}
+
+func (s *shippingServiceImpl) GetZones() ([]models.ShippingZone, error) {
+	return s.shippingRepo.FindAll()
+}
+
+func (s *shippingServiceImpl) GetZone(id primitive.ObjectID) (*models.ShippingZone, error) {
+	zone, err := s.shippingRepo.FindByID(id)
+	if err != nil {
+		return nil, services.ErrShippingZoneNotFound
+	}
+	return zone, nil
+}
+
+func (s *shippingServiceImpl) UpdateZone(id primitive.ObjectID, zone models.ShippingZone) (*models.ShippingZone, error) {
+	existing, err := s.shippingRepo.FindByID(id)
+	if err != nil {
+		return nil, services.ErrShippingZoneNotFound
+	}
+	if err := validateShippingZone(&zone); err != nil {
+		return nil, err
+	}
+
+	zone.ID = id
+	zone.CreatedAt = existing.CreatedAt
+	zone.UpdatedAt = time.Now()
+
+	if err := s.shippingRepo.Replace(&zone); err != nil {
+		return nil, err
+	}
+	return &zone, nil
+}
+
+func (s *shippingServiceImpl) DeleteZone(id primitive.ObjectID) error {
+	if err := s.shippingRepo.Delete(id); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return services.ErrShippingZoneNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// -------------------- QUOTES --------------------
+
+// QuoteShipping prices every method of the most specific zone covering the
+// address. Addresses outside every zone get the legacy flat rates.
+func (s *shippingServiceImpl) QuoteShipping(address models.Address, parcel models.ShippingParcel) ([]models.ShippingQuote, error) {
+	zones, err := s.shippingRepo.FindActive()
+	if err != nil {
+		return nil, err
+	}
+
+	var zone *models.ShippingZone
+	bestPrefix := -1
+	for i := range zones {
+		if ok, prefix := zones[i].Match(address); ok && prefix > bestPrefix {
+			zone = &zones[i]
+			bestPrefix = prefix
+		}
+	}
+
+	if zone == nil {
+		quotes := make([]models.ShippingQuote, len(legacyShippingQuotes))
+		copy(quotes, legacyShippingQuotes)
+		return quotes, nil
+	}
+
+	quotes := []models.ShippingQuote{}
+	for _, method := range zone.Methods {
+		price, ok := method.Price(parcel)
+		if !ok {
+			continue
+		}
+		quotes = append(quotes, models.ShippingQuote{
+			Code:          method.Code,
+			Name:          method.Name,
+			Price:         price,
+			EstimatedDays: method.EstimatedDays,
+			Zone:          zone.Name,
+		})
+	}
+	if len(quotes) == 0 {
+		return nil, errors.New("we cannot ship this order to your address")
+	}
+
+	sort.SliceStable(quotes, func(i, j int) bool { return quotes[i].Price.LessThan(quotes[j].Price) })
+	return quotes, nil
+}
+
+// pickShippingQuote returns the quote for the requested delivery type, or the
+// cheapest one when none was requested
+func pickShippingQuote(quotes []models.ShippingQuote, deliveryType string) (*models.ShippingQuote, error) {
+	if deliveryType == "" {
+		return &quotes[0], nil
+	}
+	for i := range quotes {
+		if quotes[i].Code == deliveryType {
+			return &quotes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("delivery type %q is not available for this address", deliveryType)
+}
+
+// normalizeShopCurrency upper-cases an admin-entered currency, defaulting an
+// empty one, and reports whether it is DefaultCurrency. Quotes compare these
+// prices with order subtotals, which are always in DefaultCurrency.
+func normalizeShopCurrency(amount *models.Money) bool {
+	amount.Currency = strings.ToUpper(strings.TrimSpace(amount.Currency))
+	if amount.Currency == "" {
+		amount.Currency = models.DefaultCurrency
+	}
+	return amount.Currency == models.DefaultCurrency
+}
+
+func validateShippingZone(zone *models.ShippingZone) error {
+	zone.Name = strings.TrimSpace(zone.Name)
+	if zone.Name == "" {
+		return errors.New("zone name is required")
+	}
+	if len(zone.Countries) == 0 {
+		return errors.New("zone needs at least one country")
+	}
+	if len(zone.Methods) == 0 {
+		return errors.New("zone needs at least one shipping method")
+	}
+
+	codes := map[string]bool{}
+	for i := range zone.Methods {
+		method := &zone.Methods[i]
+		method.Code = strings.ToLower(strings.TrimSpace(method.Code))
+		if method.Code == "" || method.Name == "" {
+			return errors.New("every method needs a code and a name")
+		}
+		if codes[method.Code] {
+			return fmt.Errorf("method code %q is used twice", method.Code)
+		}
+		codes[method.Code] = true
+
+		switch method.Basis {
+		case "":
+			method.Basis = models.ShippingBasisFlat
+		case models.ShippingBasisFlat, models.ShippingBasisWeight, models.ShippingBasisSubtotal:
+		default:
+			return errors.New("method basis must be flat, weight or subtotal")
+		}
+
+		if len(method.Rates) == 0 {
+			return fmt.Errorf("method %q needs at least one rate", method.Code)
+		}
+		if method.FreeOver != nil {
+			if method.FreeOver.IsNegative() {
+				return errors.New("free_over cannot be negative")
+			}
+			if !normalizeShopCurrency(method.FreeOver) {
+				return errors.New("shipping prices must be in " + models.DefaultCurrency)
+			}
+		}
+		for j := range method.Rates {
+			rate := &method.Rates[j]
+			if rate.Price.IsNegative() || rate.UpTo < 0 {
+				return errors.New("rates cannot be negative")
+			}
+			if !normalizeShopCurrency(&rate.Price) {
+				return errors.New("shipping prices must be in " + models.DefaultCurrency)
+			}
+		}
+
+		// Bounded tiers in ascending order, the open-ended tier last
+		sort.SliceStable(method.Rates, func(a, b int) bool {
+			ra, rb := method.Rates[a].UpTo, method.Rates[b].UpTo
+			if ra == 0 || rb == 0 {
+				return rb == 0 && ra != 0
+			}
+			return ra < rb
+		})
+	}
+	return nil
+}
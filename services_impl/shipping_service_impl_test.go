@@ -0,0 +1,43 @@
+package servicesimpl
+
+import (
+	"testing"
+
+	"beauty-ecommerce-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateShippingZoneCurrency(t *testing.T) {
+	zone := func(price, freeOver models.Money) *models.ShippingZone {
+		return &models.ShippingZone{
+			Name:      "UK",
+			Countries: []string{"GB"},
+			Methods: []models.ShippingMethod{{
+				Code:     "standard",
+				Name:     "Standard",
+				Rates:    []models.ShippingRate{{Price: price}},
+				FreeOver: &freeOver,
+			}},
+		}
+	}
+
+	t.Run("currencies are upper-cased and defaulted", func(t *testing.T) {
+		z := zone(models.Money{Amount: 399, Currency: "gbp"}, models.Money{Amount: 5000})
+		assert.NoError(t, validateShippingZone(z))
+
+		method := z.Methods[0]
+		assert.Equal(t, models.GBP(399), method.Rates[0].Price)
+		assert.Equal(t, models.GBP(5000), *method.FreeOver)
+
+		// Quoting must not panic on mixed currencies
+		price, ok := method.Price(models.ShippingParcel{Subtotal: models.GBP(1000)})
+		assert.True(t, ok)
+		assert.Equal(t, models.GBP(399), price)
+	})
+
+	t.Run("other currencies are rejected", func(t *testing.T) {
+		assert.Error(t, validateShippingZone(zone(models.NewMoney(399, "EUR"), models.GBP(5000))))
+		assert.Error(t, validateShippingZone(zone(models.GBP(399), models.NewMoney(5000, "usd"))))
+	})
+}
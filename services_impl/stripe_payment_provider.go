@@ -0,0 +1,441 @@
+package servicesimpl
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/services"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/stripe/stripe-go/v74"
+	"github.com/stripe/stripe-go/v74/client"
+	"github.com/stripe/stripe-go/v74/webhook"
+)
+
+var _ services.PaymentProvider = (*stripePaymentProvider)(nil)
+
+// stripePaymentProvider takes payments through Stripe
+type stripePaymentProvider struct {
+	api           *client.API
+	webhookSecret string
+}
+
+func NewStripePaymentProvider(secretKey, webhookSecret string) *stripePaymentProvider {
+	return &stripePaymentProvider{
+		api:           client.New(secretKey, nil),
+		webhookSecret: webhookSecret,
+	}
+}
+
+func (p *stripePaymentProvider) Name() string {
+	return "stripe"
+}
+
+// -------------------- INTENTS --------------------
+
+func (p *stripePaymentProvider) CreateIntent(ctx context.Context, req services.IntentParams) (*services.PaymentIntent, error) {
+	params := &stripe.PaymentIntentParams{
+		Amount:   stripe.Int64(req.Amount.Amount),
+		Currency: stripe.String(strings.ToLower(req.Amount.Currency)),
+	}
+	params.Context = ctx
+	if req.ManualCapture {
+		params.CaptureMethod = stripe.String(string(stripe.PaymentIntentCaptureMethodManual))
+	}
+	if req.CustomerID != "" {
+		params.Customer = stripe.String(req.CustomerID)
+		if req.SavePaymentMethod {
+			params.SetupFutureUsage = stripe.String(string(stripe.PaymentIntentSetupFutureUsageOnSession))
+		}
+	}
+	if req.IdempotencyKey != "" {
+		params.SetIdempotencyKey(req.IdempotencyKey)
+	}
+	for key, value := range req.Metadata {
+		params.AddMetadata(key, value)
+	}
+
+	pi, err := p.api.PaymentIntents.New(params)
+	if err != nil {
+		return nil, err
+	}
+	return stripeIntent(pi), nil
+}
+
+func (p *stripePaymentProvider) GetIntent(ctx context.Context, intentID string) (*services.PaymentIntent, error) {
+	params := &stripe.PaymentIntentParams{}
+	params.Context = ctx
+
+	pi, err := p.api.PaymentIntents.Get(intentID, params)
+	if err != nil {
+		return nil, err
+	}
+	return stripeIntent(pi), nil
+}
+
+func (p *stripePaymentProvider) ConfirmIntent(ctx context.Context, intentID, paymentMethodID string) (*services.PaymentIntent, error) {
+	params := &stripe.PaymentIntentConfirmParams{PaymentMethod: stripe.String(paymentMethodID)}
+	params.Context = ctx
+
+	pi, err := p.api.PaymentIntents.Confirm(intentID, params)
+	if err != nil {
+		return nil, err
+	}
+	return stripeIntent(pi), nil
+}
+
+func (p *stripePaymentProvider) CaptureIntent(ctx context.Context, intentID string) (*services.PaymentIntent, error) {
+	params := &stripe.PaymentIntentCaptureParams{}
+	params.Context = ctx
+
+	pi, err := p.api.PaymentIntents.Capture(intentID, params)
+	if err != nil {
+		return nil, err
+	}
+	return stripeIntent(pi), nil
+}
+
+func stripeIntent(pi *stripe.PaymentIntent) *services.PaymentIntent {
+	return &services.PaymentIntent{
+		ID:           pi.ID,
+		ClientSecret: pi.ClientSecret,
+		Status:       string(pi.Status),
+		Amount:       models.Money{Amount: pi.Amount, Currency: strings.ToUpper(string(pi.Currency))},
+	}
+}
+
+// -------------------- CHECKOUT --------------------
+
+// CreateCheckoutSession opens a Stripe Checkout page for the order. Checkout
+// only takes discounts as coupons, so the order discount becomes a single-use
+// coupon for its amount.
+func (p *stripePaymentProvider) CreateCheckoutSession(ctx context.Context, req services.CheckoutParams) (*services.CheckoutSession, error) {
+	if len(req.Lines) == 0 {
+		return nil, errors.New("checkout needs at least one line")
+	}
+	currency := strings.ToLower(req.Lines[0].UnitPrice.Currency)
+
+	params := &stripe.CheckoutSessionParams{
+		Mode:              stripe.String(string(stripe.CheckoutSessionModePayment)),
+		SuccessURL:        stripe.String(req.SuccessURL),
+		CancelURL:         stripe.String(req.CancelURL),
+		PaymentIntentData: &stripe.CheckoutSessionPaymentIntentDataParams{Metadata: req.Metadata},
+	}
+	params.Context = ctx
+	// Stripe takes either the customer or an email for a new one
+	if req.CustomerID != "" {
+		params.Customer = stripe.String(req.CustomerID)
+	} else if req.CustomerEmail != "" {
+		params.CustomerEmail = stripe.String(req.CustomerEmail)
+	}
+	if orderID, ok := req.Metadata["order_id"]; ok {
+		params.ClientReferenceID = stripe.String(orderID)
+	}
+	if req.ExpiresAt != nil {
+		params.ExpiresAt = stripe.Int64(req.ExpiresAt.Unix())
+	}
+	if req.IdempotencyKey != "" {
+		params.SetIdempotencyKey(req.IdempotencyKey)
+	}
+	for key, value := range req.Metadata {
+		params.AddMetadata(key, value)
+	}
+
+	for _, line := range req.Lines {
+		params.LineItems = append(params.LineItems, &stripe.CheckoutSessionLineItemParams{
+			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+				Currency:    stripe.String(currency),
+				UnitAmount:  stripe.Int64(line.UnitPrice.Amount),
+				ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{Name: stripe.String(line.Name)},
+			},
+			Quantity: stripe.Int64(int64(line.Quantity)),
+		})
+	}
+
+	if req.Shipping.IsPositive() {
+		params.ShippingOptions = []*stripe.CheckoutSessionShippingOptionParams{{
+			ShippingRateData: &stripe.CheckoutSessionShippingOptionShippingRateDataParams{
+				Type:        stripe.String("fixed_amount"),
+				DisplayName: stripe.String(req.ShippingName),
+				FixedAmount: &stripe.CheckoutSessionShippingOptionShippingRateDataFixedAmountParams{
+					Amount:   stripe.Int64(req.Shipping.Amount),
+					Currency: stripe.String(currency),
+				},
+			},
+		}}
+	}
+
+	if req.Discount.IsPositive() {
+		couponParams := &stripe.CouponParams{
+			AmountOff:      stripe.Int64(req.Discount.Amount),
+			Currency:       stripe.String(currency),
+			Duration:       stripe.String(string(stripe.CouponDurationOnce)),
+			MaxRedemptions: stripe.Int64(1),
+			Name:           stripe.String("Order discount"),
+		}
+		couponParams.Context = ctx
+		if req.IdempotencyKey != "" {
+			couponParams.SetIdempotencyKey(req.IdempotencyKey + "-discount")
+		}
+		c, err := p.api.Coupons.New(couponParams)
+		if err != nil {
+			return nil, err
+		}
+		params.Discounts = []*stripe.CheckoutSessionDiscountParams{{Coupon: stripe.String(c.ID)}}
+	}
+
+	session, err := p.api.CheckoutSessions.New(params)
+	if err != nil {
+		return nil, err
+	}
+	return &services.CheckoutSession{ID: session.ID, URL: session.URL}, nil
+}
+
+// -------------------- REFUNDS --------------------
+
+func (p *stripePaymentProvider) Refund(ctx context.Context, req services.RefundParams) (*services.ProviderRefund, error) {
+	if req.PaymentReference == "" {
+		return nil, errors.New("order has no payment to refund")
+	}
+
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(req.PaymentReference),
+		Amount:        stripe.Int64(req.Amount.Amount),
+		Reason:        stripe.String(string(stripe.RefundReasonRequestedByCustomer)),
+	}
+	params.Context = ctx
+	if req.IdempotencyKey != "" {
+		params.SetIdempotencyKey(req.IdempotencyKey)
+	}
+	for key, value := range req.Metadata {
+		params.AddMetadata(key, value)
+	}
+	if req.Reason != "" {
+		params.AddMetadata("reason", req.Reason)
+	}
+
+	r, err := p.api.Refunds.New(params)
+	if err != nil {
+		return nil, err
+	}
+	return &services.ProviderRefund{ID: r.ID, Status: string(r.Status)}, nil
+}
+
+// -------------------- CUSTOMERS --------------------
+
+func (p *stripePaymentProvider) CreateCustomer(ctx context.Context, req services.CustomerParams) (string, error) {
+	params := &stripe.CustomerParams{
+		Email: stripe.String(req.Email),
+		Name:  stripe.String(req.Name),
+	}
+	params.Context = ctx
+	if req.IdempotencyKey != "" {
+		params.SetIdempotencyKey(req.IdempotencyKey)
+	}
+	for key, value := range req.Metadata {
+		params.AddMetadata(key, value)
+	}
+
+	customer, err := p.api.Customers.New(params)
+	if err != nil {
+		return "", err
+	}
+	return customer.ID, nil
+}
+
+func (p *stripePaymentProvider) CreateSetupIntent(ctx context.Context, customerID string) (*services.SetupIntent, error) {
+	params := &stripe.SetupIntentParams{
+		Customer:           stripe.String(customerID),
+		PaymentMethodTypes: stripe.StringSlice([]string{string(stripe.PaymentMethodTypeCard)}),
+		Usage:              stripe.String(string(stripe.SetupIntentUsageOnSession)),
+	}
+	params.Context = ctx
+
+	si, err := p.api.SetupIntents.New(params)
+	if err != nil {
+		return nil, err
+	}
+	return &services.SetupIntent{ID: si.ID, ClientSecret: si.ClientSecret, Status: string(si.Status)}, nil
+}
+
+func (p *stripePaymentProvider) ListPaymentMethods(ctx context.Context, customerID string) ([]services.PaymentMethod, error) {
+	params := &stripe.PaymentMethodListParams{
+		Customer: stripe.String(customerID),
+		Type:     stripe.String(string(stripe.PaymentMethodTypeCard)),
+	}
+	params.Context = ctx
+
+	methods := []services.PaymentMethod{}
+	iter := p.api.PaymentMethods.List(params)
+	for iter.Next() {
+		pm := iter.PaymentMethod()
+		method := services.PaymentMethod{ID: pm.ID}
+		if pm.Card != nil {
+			method.Brand = string(pm.Card.Brand)
+			method.Last4 = pm.Card.Last4
+			method.ExpMonth = int(pm.Card.ExpMonth)
+			method.ExpYear = int(pm.Card.ExpYear)
+		}
+		methods = append(methods, method)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return methods, nil
+}
+
+// DetachPaymentMethod checks the method is the customer's first, as Stripe
+// detaches any method by ID alone
+func (p *stripePaymentProvider) DetachPaymentMethod(ctx context.Context, customerID, paymentMethodID string) error {
+	getParams := &stripe.PaymentMethodParams{}
+	getParams.Context = ctx
+
+	pm, err := p.api.PaymentMethods.Get(paymentMethodID, getParams)
+	if err != nil {
+		var stripeErr *stripe.Error
+		if errors.As(err, &stripeErr) && stripeErr.HTTPStatusCode == http.StatusNotFound {
+			return services.ErrPaymentMethodNotFound
+		}
+		return err
+	}
+	if pm.Customer == nil || pm.Customer.ID != customerID {
+		return services.ErrPaymentMethodNotFound
+	}
+
+	params := &stripe.PaymentMethodDetachParams{}
+	params.Context = ctx
+	_, err = p.api.PaymentMethods.Detach(paymentMethodID, params)
+	return err
+}
+
+// -------------------- CHARGES --------------------
+
+func (p *stripePaymentProvider) ChargePaymentReference(ctx context.Context, chargeID string) (string, error) {
+	params := &stripe.ChargeParams{}
+	params.Context = ctx
+
+	charge, err := p.api.Charges.Get(chargeID, params)
+	if err != nil {
+		return "", err
+	}
+	if charge.PaymentIntent == nil {
+		return "", fmt.Errorf("charge %s has no payment intent", chargeID)
+	}
+	return charge.PaymentIntent.ID, nil
+}
+
+// -------------------- WEBHOOKS --------------------
+
+// VerifyWebhook checks the Stripe-Signature header against the webhook
+// secret. For local testing, sign fixtures with tools/replay_webhook or use
+// the Stripe CLI.
+func (p *stripePaymentProvider) VerifyWebhook(payload []byte, headers http.Header) (*services.WebhookDelivery, error) {
+	if p.webhookSecret == "" {
+		return nil, errors.New("webhook secret not configured")
+	}
+
+	event, err := webhook.ConstructEventWithOptions(
+		payload, headers.Get("Stripe-Signature"), p.webhookSecret,
+		webhook.ConstructEventOptions{IgnoreAPIVersionMismatch: true},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &services.WebhookDelivery{ID: event.ID, Type: string(event.Type), Data: event.Data.Raw}, nil
+}
+
+func (p *stripePaymentProvider) DecodeEvent(eventType string, data []byte) (*services.PaymentEvent, error) {
+	switch eventType {
+	case "payment_intent.succeeded", "payment_intent.payment_failed":
+		var pi stripe.PaymentIntent
+		if err := json.Unmarshal(data, &pi); err != nil {
+			return nil, fmt.Errorf("%w: failed to parse PaymentIntent: %w", services.ErrMalformedEvent, err)
+		}
+		event := &services.PaymentEvent{Kind: services.PaymentSucceeded, PaymentReference: pi.ID}
+		if eventType == "payment_intent.payment_failed" {
+			event.Kind = services.PaymentFailed
+			if perr := pi.LastPaymentError; perr != nil {
+				// The decline code says more than the generic card_declined
+				event.ErrorCode, event.ErrorMessage = string(perr.Code), perr.Msg
+				if perr.DeclineCode != "" {
+					event.ErrorCode = string(perr.DeclineCode)
+				}
+			}
+		}
+		return event, nil
+
+	case "charge.refunded":
+		var charge stripe.Charge
+		if err := json.Unmarshal(data, &charge); err != nil {
+			return nil, fmt.Errorf("%w: failed to parse Charge: %w", services.ErrMalformedEvent, err)
+		}
+		// Refunded is only set once the whole charge has been refunded
+		kind := services.PaymentPartiallyRefunded
+		if charge.Refunded {
+			kind = services.PaymentRefunded
+		}
+		event := &services.PaymentEvent{Kind: kind, ChargeID: charge.ID}
+		if charge.PaymentIntent != nil {
+			event.PaymentReference = charge.PaymentIntent.ID
+		}
+		return event, nil
+
+	case "checkout.session.completed", "checkout.session.expired":
+		var session stripe.CheckoutSession
+		if err := json.Unmarshal(data, &session); err != nil {
+			return nil, fmt.Errorf("%w: failed to parse Checkout Session: %w", services.ErrMalformedEvent, err)
+		}
+		event := &services.PaymentEvent{
+			Kind:              services.CheckoutCompleted,
+			CheckoutSessionID: session.ID,
+			Paid:              session.PaymentStatus == stripe.CheckoutSessionPaymentStatusPaid,
+		}
+		if eventType == "checkout.session.expired" {
+			event.Kind = services.CheckoutExpired
+		}
+		if session.PaymentIntent != nil {
+			event.PaymentReference = session.PaymentIntent.ID
+		}
+		return event, nil
+
+	case "charge.dispute.created", "charge.dispute.updated", "charge.dispute.closed":
+		var dispute stripe.Dispute
+		if err := json.Unmarshal(data, &dispute); err != nil {
+			return nil, fmt.Errorf("%w: failed to parse Dispute: %w", services.ErrMalformedEvent, err)
+		}
+		event := &services.PaymentEvent{
+			Kind: services.PaymentDisputed,
+			Dispute: &services.ProviderDispute{
+				ID:     dispute.ID,
+				Status: models.DisputeStatus(dispute.Status),
+				Reason: string(dispute.Reason),
+				Amount: models.Money{Amount: dispute.Amount, Currency: strings.ToUpper(string(dispute.Currency))},
+			},
+		}
+		switch eventType {
+		case "charge.dispute.updated":
+			event.Kind = services.DisputeUpdated
+		case "charge.dispute.closed":
+			event.Kind = services.DisputeClosed
+		}
+		// The charge is usually only an ID; the payment intent is set on
+		// newer API versions
+		if dispute.Charge != nil {
+			event.ChargeID = dispute.Charge.ID
+		}
+		if dispute.PaymentIntent != nil {
+			event.PaymentReference = dispute.PaymentIntent.ID
+		}
+		if dispute.EvidenceDetails != nil && dispute.EvidenceDetails.DueBy > 0 {
+			dueBy := time.Unix(dispute.EvidenceDetails.DueBy, 0)
+			event.Dispute.EvidenceDueBy = &dueBy
+		}
+		return event, nil
+	}
+
+	return &services.PaymentEvent{}, nil
+}
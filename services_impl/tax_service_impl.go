@@ -0,0 +1,225 @@
+package servicesimpl
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/repositories"
+	"beauty-ecommerce-backend/services"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var _ services.TaxService = (*taxServiceImpl)(nil)
+
+// countryAliases maps the ways shoppers write a country to the code rates are stored under
+var countryAliases = map[string]string{
+	"UK":             "GB",
+	"UNITED KINGDOM": "GB",
+	"GREAT BRITAIN":  "GB",
+	"ENGLAND":        "GB",
+	"SCOTLAND":       "GB",
+	"WALES":          "GB",
+}
+
+// defaultTaxRates apply when no rate has been configured for a country. The
+// shop's catalog prices are UK VAT-inclusive.
+var defaultTaxRates = map[string][]models.TaxRate{
+	"GB": {{Name: "VAT 20%", Country: "GB", Rate: 20, Inclusive: true, Active: true}},
+}
+
+type taxServiceImpl struct {
+	taxRepo *repositories.TaxRepository
+}
+
+func NewTaxService(taxRepo *repositories.TaxRepository) *taxServiceImpl {
+	return &taxServiceImpl{taxRepo: taxRepo}
+}
+
+// -------------------- ADMIN CRUD --------------------
+func (s *taxServiceImpl) CreateRate(rate models.TaxRate) (*models.TaxRate, error) {
+	if err := validateTaxRate(&rate); err != nil {
+		return nil, err
+	}
+
+	rate.ID = primitive.NewObjectID()
+	rate.CreatedAt = time.Now()
+	rate.UpdatedAt = time.Now()
+
+	if err := s.taxRepo.Create(&rate); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("a rate for this country and category already exists")
+		}
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (s *taxServiceImpl) GetRates() ([]models.TaxRate, error) {
+	return s.taxRepo.FindAll()
+}
+
+func (s *taxServiceImpl) UpdateRate(id primitive.ObjectID, rate models.TaxRate) (*models.TaxRate, error) {
+	existing, err := s.taxRepo.FindByID(id)
+	if err != nil {
+		return nil, services.ErrTaxRateNotFound
+	}
+	if err := validateTaxRate(&rate); err != nil {
+		return nil, err
+	}
+
+	rate.ID = id
+	rate.CreatedAt = existing.CreatedAt
+	rate.UpdatedAt = time.Now()
+
+	err = s.taxRepo.Update(id, bson.M{
+		"name":       rate.Name,
+		"country":    rate.Country,
+		"category":   rate.Category,
+		"rate":       rate.Rate,
+		"inclusive":  rate.Inclusive,
+		"active":     rate.Active,
+		"updated_at": rate.UpdatedAt,
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("a rate for this country and category already exists")
+		}
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (s *taxServiceImpl) DeleteRate(id primitive.ObjectID) error {
+	if err := s.taxRepo.Delete(id); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return services.ErrTaxRateNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// -------------------- CALCULATION --------------------
+
+// CalculateTax applies the rates for the shipping country. Each line is taxed
+// on its price after its share of the discount; shipping is taxed at the
+// country's default rate after any free-shipping discount.
+func (s *taxServiceImpl) CalculateTax(order *models.Order) error {
+	zero := models.Money{Currency: order.Subtotal.Currency}
+	order.TaxLines = nil
+	order.TaxTotal = zero
+	order.ShippingTax = zero
+
+	country := normalizeCountry(order.ShippingAddress.Country)
+	rates, err := s.taxRepo.FindActiveByCountry(country)
+	if err != nil {
+		return err
+	}
+	if len(rates) == 0 {
+		rates = defaultTaxRates[country]
+	}
+
+	byCategory := map[string]*models.TaxRate{}
+	for i := range rates {
+		byCategory[strings.ToLower(rates[i].Category)] = &rates[i]
+	}
+	rateFor := func(category string) *models.TaxRate {
+		if rate, ok := byCategory[strings.ToLower(category)]; ok {
+			return rate
+		}
+		return byCategory[""]
+	}
+
+	lines := map[string]*models.TaxLine{}
+	var keys []string
+	charge := func(rate *models.TaxRate, taxable models.Money) models.Money {
+		amount := taxOn(taxable, rate.Rate, rate.Inclusive)
+		key := fmt.Sprintf("%s|%g|%t", rate.Name, rate.Rate, rate.Inclusive)
+		line, ok := lines[key]
+		if !ok {
+			line = &models.TaxLine{Name: rate.Name, Rate: rate.Rate, Inclusive: rate.Inclusive, Taxable: zero, Amount: zero}
+			lines[key] = line
+			keys = append(keys, key)
+		}
+		line.Taxable = line.Taxable.Add(taxable)
+		line.Amount = line.Amount.Add(amount)
+		return amount
+	}
+
+	for i := range order.Items {
+		item := &order.Items[i]
+		item.TaxName, item.TaxRate, item.TaxAmount = "", 0, zero
+
+		rate := rateFor(item.Category)
+		if rate == nil {
+			continue
+		}
+		taxable := item.Price.Mul(item.Quantity).Sub(item.DiscountAmount)
+		item.TaxName = rate.Name
+		item.TaxRate = rate.Rate
+		item.TaxAmount = charge(rate, taxable)
+	}
+
+	if rate := byCategory[""]; rate != nil {
+		if taxable := order.ShippingFee.Sub(shippingDiscount(order)); taxable.IsPositive() {
+			order.ShippingTax = charge(rate, taxable)
+		}
+	}
+
+	for _, key := range keys {
+		order.TaxLines = append(order.TaxLines, *lines[key])
+		order.TaxTotal = order.TaxTotal.Add(lines[key].Amount)
+	}
+	return nil
+}
+
+// taxOn returns the tax in an amount. Inclusive amounts already contain the tax.
+func taxOn(amount models.Money, rate float64, inclusive bool) models.Money {
+	if !amount.IsPositive() || rate <= 0 {
+		return models.Money{Currency: amount.Currency}
+	}
+	if inclusive {
+		net := int64(math.Round(float64(amount.Amount) / (1 + rate/100)))
+		return models.Money{Amount: amount.Amount - net, Currency: amount.Currency}
+	}
+	return amount.Percent(rate)
+}
+
+// shippingDiscount is the part of the order discount that waives shipping
+func shippingDiscount(order *models.Order) models.Money {
+	total := models.Money{Currency: order.ShippingFee.Currency}
+	for _, line := range order.Discounts {
+		if line.Type == models.CouponFreeShipping {
+			total = total.Add(line.Amount)
+		}
+	}
+	return total
+}
+
+func normalizeCountry(country string) string {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if code, ok := countryAliases[country]; ok {
+		return code
+	}
+	return country
+}
+
+func validateTaxRate(rate *models.TaxRate) error {
+	rate.Name = strings.TrimSpace(rate.Name)
+	rate.Country = normalizeCountry(rate.Country)
+	rate.Category = strings.TrimSpace(rate.Category)
+
+	if rate.Name == "" || rate.Country == "" {
+		return errors.New("name and country are required")
+	}
+	if rate.Rate < 0 || rate.Rate > 100 {
+		return errors.New("rate must be between 0 and 100")
+	}
+	return nil
+}
@@ -4,10 +4,8 @@ import (
 	"beauty-ecommerce-backend/models"
 	"beauty-ecommerce-backend/repositories"
 	"beauty-ecommerce-backend/services"
-	"beauty-ecommerce-backend/utils"
 	"context"
 	"errors"
-	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -18,11 +16,15 @@ import (
 
 type userServiceImpl struct {
 	userRepo *repositories.UserRepository
+	payments services.PaymentProvider
+	sessions services.SessionService
 }
 
-func NewUserService(userRepo *repositories.UserRepository) services.UserService {
+func NewUserService(userRepo *repositories.UserRepository, payments services.PaymentProvider, sessions services.SessionService) services.UserService {
 	return &userServiceImpl{
 		userRepo: userRepo,
+		payments: payments,
+		sessions: sessions,
 	}
 }
 
@@ -43,15 +45,11 @@ func (s *userServiceImpl) Register(user models.User) error {
 		return errors.New("email already registered")
 	}
 
-	if user.Role == "" {
-		user.Role = "USER"
-	} else {
-		user.Role = strings.ToUpper(user.Role)
-	}
-
-	if user.Role != "ADMIN" && user.Role != "USER" {
-		return errors.New("invalid role (allowed: ADMIN, USER)")
+	// Staff accounts are only made by an administrator or tools/create_admin
+	if user.Role != "" && models.ParseRole(user.Role) != models.RoleCustomer {
+		return errors.New("invalid role (only customer accounts can register)")
 	}
+	user.Role = string(models.RoleCustomer)
 
 	hashed, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -78,7 +76,7 @@ func (s *userServiceImpl) Register(user models.User) error {
 }
 
 // -------------------- LOGIN --------------------
-func (s *userServiceImpl) Login(email, password string) (string, error) {
+func (s *userServiceImpl) Login(email, password string, client services.SessionClient) (*services.TokenPair, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -86,23 +84,41 @@ func (s *userServiceImpl) Login(email, password string) (string, error) {
 	err := s.userRepo.Collection.FindOne(ctx, bson.M{"email": email}).Decode(&found)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return "", errors.New("invalid email or password")
+			return nil, services.ErrInvalidCredentials
 		}
-		return "", errors.New("failed to find user")
+		return nil, errors.New("failed to find user")
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(found.Password), []byte(password))
 	if err != nil {
-		return "", errors.New("invalid email or password")
+		return nil, services.ErrInvalidCredentials
 	}
 
-	// Generate JWT with correct MongoDB user ID
-	token, err := utils.GenerateToken(found.ID, found.Email, found.Role)
+	return s.sessions.StartSession(&found, client)
+}
+
+// StaffLogin signs in an admin account. Customers get the same answer as a
+// wrong password, so the endpoint does not reveal who is staff.
+func (s *userServiceImpl) StaffLogin(email, password string, client services.SessionClient) (*services.TokenPair, *models.User, error) {
+	found, err := s.userRepo.FindByEmail(email)
 	if err != nil {
-		return "", errors.New("failed to generate token")
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil, services.ErrInvalidCredentials
+		}
+		return nil, nil, errors.New("failed to find user")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(found.Password), []byte(password)); err != nil {
+		return nil, nil, services.ErrInvalidCredentials
+	}
+	if !models.ParseRole(found.Role).Staff() {
+		return nil, nil, services.ErrInvalidCredentials
 	}
 
-	return token, nil
+	tokens, err := s.sessions.StartSession(&found, client)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, &found, nil
 }
 
 // -------------------- ADMIN METHODS --------------------
@@ -129,24 +145,36 @@ func (s *userServiceImpl) UpdateUser(userID string, update models.User) error {
 
 	id, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return errors.New("invalid user ID")
+		return services.ErrInvalidUserID
 	}
 
-	updateBson := bson.M{
-		"$set": bson.M{
-			"name":       update.Name,
-			"email":      update.Email,
-			"role":       strings.ToUpper(update.Role),
-			"updated_at": time.Now(),
-		},
+	set := bson.M{
+		"name":       update.Name,
+		"email":      update.Email,
+		"updated_at": time.Now(),
 	}
+	if update.Role != "" {
+		role := models.ParseRole(update.Role)
+		if !role.Valid() {
+			return services.ErrInvalidRole
+		}
+		set["role"] = string(role)
+	}
+	updateBson := bson.M{"$set": set}
 
-	res, err := s.userRepo.Collection.UpdateOne(ctx, bson.M{"_id": id}, updateBson)
+	// The document as it was, to tell whether the role changed
+	var before models.User
+	err = s.userRepo.Collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, updateBson).Decode(&before)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return services.ErrUserNotFound
+	}
 	if err != nil {
 		return err
 	}
-	if res.MatchedCount == 0 {
-		return errors.New("user not found")
+
+	// Tokens carry the role, so a new role needs a new sign-in
+	if role, ok := set["role"]; ok && role != before.Role {
+		return s.sessions.RevokeAll(id)
 	}
 	return nil
 }
@@ -157,7 +185,7 @@ func (s *userServiceImpl) DeleteUser(userID string) error {
 
 	id, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return errors.New("invalid user ID")
+		return services.ErrInvalidUserID
 	}
 
 	res, err := s.userRepo.Collection.DeleteOne(ctx, bson.M{"_id": id})
@@ -165,7 +193,7 @@ func (s *userServiceImpl) DeleteUser(userID string) error {
 		return err
 	}
 	if res.DeletedCount == 0 {
-		return errors.New("user not found")
+		return services.ErrUserNotFound
 	}
 	return nil
 }
@@ -177,7 +205,7 @@ func (s *userServiceImpl) GetUserByID(id primitive.ObjectID) (models.User, error
 	var user models.User
 	err := s.userRepo.Collection.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
 	if err != nil {
-		return models.User{}, errors.New("user not found")
+		return models.User{}, services.ErrUserNotFound
 	}
 
 	return user, nil
@@ -187,6 +215,25 @@ func (s *userServiceImpl) GetProfile(userID primitive.ObjectID) (*models.User, e
 	return s.userRepo.FindByID(userID)
 }
 
+// UpdateCurrency sets the user's preferred currency; the caller checks it is supported
+func (s *userServiceImpl) UpdateCurrency(userID primitive.ObjectID, currency string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := s.userRepo.Collection.UpdateOne(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"currency": currency, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return services.ErrUserNotFound
+	}
+	return nil
+}
+
 func (s *userServiceImpl) SavePasswordResetToken(
 	userID primitive.ObjectID,
 	hashedToken string,
@@ -208,7 +255,6 @@ func (s *userServiceImpl) SavePasswordResetToken(
 		bson.M{"_id": userID},
 		update,
 	)
-
 	return err
 }
 
@@ -250,8 +296,12 @@ func (s *userServiceImpl) UpdatePassword(
 		bson.M{"_id": userID},
 		update,
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
+	// Whoever knew the old password may still be signed in
+	return s.sessions.RevokeAll(userID)
 }
 
 func (s *userServiceImpl) ClearResetToken(
@@ -272,7 +322,6 @@ func (s *userServiceImpl) ClearResetToken(
 		bson.M{"_id": userID},
 		update,
 	)
-
 	return err
 }
 
@@ -287,8 +336,75 @@ func (s *userServiceImpl) GetUserByEmail(email string) (*models.User, error) {
 	).Decode(&user)
 
 	if err != nil {
-		return nil, errors.New("user not found")
+		return nil, services.ErrUserNotFound
 	}
 
 	return &user, nil
 }
+
+// -------------------- SAVED PAYMENT METHODS --------------------
+
+// paymentCustomerID returns the user's customer at the payment provider,
+// creating it the first time they need one
+func paymentCustomerID(ctx context.Context, userRepo *repositories.UserRepository, payments services.PaymentProvider, user *models.User) (string, error) {
+	if user.PaymentCustomerID != "" {
+		return user.PaymentCustomerID, nil
+	}
+
+	customerID, err := payments.CreateCustomer(ctx, services.CustomerParams{
+		Email:          user.Email,
+		Name:           user.Name,
+		IdempotencyKey: "customer-" + user.ID.Hex(),
+		Metadata:       map[string]string{"user_id": user.ID.Hex()},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// A concurrent request may have linked a customer first; use theirs
+	linked, err := userRepo.SetPaymentCustomerID(ctx, user.ID, customerID)
+	if err != nil {
+		return "", err
+	}
+	user.PaymentCustomerID = linked
+	return linked, nil
+}
+
+// ListPaymentMethods lists the cards the user has saved
+func (s *userServiceImpl) ListPaymentMethods(userID primitive.ObjectID) ([]services.PaymentMethod, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, services.ErrUserNotFound
+	}
+	if user.PaymentCustomerID == "" {
+		return []services.PaymentMethod{}, nil
+	}
+	return s.payments.ListPaymentMethods(context.Background(), user.PaymentCustomerID)
+}
+
+// SetupPaymentMethod starts saving a card; the frontend collects it with the
+// setup intent's client secret
+func (s *userServiceImpl) SetupPaymentMethod(userID primitive.ObjectID) (*services.SetupIntent, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, services.ErrUserNotFound
+	}
+	ctx := context.Background()
+
+	customerID, err := paymentCustomerID(ctx, s.userRepo, s.payments, user)
+	if err != nil {
+		return nil, err
+	}
+	return s.payments.CreateSetupIntent(ctx, customerID)
+}
+
+func (s *userServiceImpl) RemovePaymentMethod(userID primitive.ObjectID, paymentMethodID string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return services.ErrUserNotFound
+	}
+	if user.PaymentCustomerID == "" {
+		return services.ErrPaymentMethodNotFound
+	}
+	return s.payments.DetachPaymentMethod(context.Background(), user.PaymentCustomerID, paymentMethodID)
+}
@@ -0,0 +1,163 @@
+package servicesimpl
+
+import (
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/repositories"
+	"beauty-ecommerce-backend/services"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var _ services.WebhookService = (*webhookServiceImpl)(nil)
+
+// webhookClaimTTL is how long a delivery may take before another can retry it
+const webhookClaimTTL = 5 * time.Minute
+
+// errUnhandledEvent marks event types we receive but do not act on
+var errUnhandledEvent = errors.New("unhandled event type")
+
+type webhookServiceImpl struct {
+	eventRepo *repositories.WebhookEventRepository
+	orders    services.OrderService
+	disputes  services.DisputeService
+	payments  services.PaymentProvider
+}
+
+func NewWebhookService(eventRepo *repositories.WebhookEventRepository, orders services.OrderService, disputes services.DisputeService, payments services.PaymentProvider) *webhookServiceImpl {
+	return &webhookServiceImpl{eventRepo: eventRepo, orders: orders, disputes: disputes, payments: payments}
+}
+
+// HandleEvent stores the event and processes it unless a delivery of the same
+// event has already done so. A processing error is recorded on the event and
+// returned, so the provider retries the delivery. Errors no retry can fix are
+// wrapped in services.ErrEventNotApplied, so the provider can be told to stop.
+func (s *webhookServiceImpl) HandleEvent(id, eventType string, data []byte) error {
+	event, err := s.eventRepo.Claim(context.Background(), &models.WebhookEvent{
+		ID:       id,
+		Provider: s.payments.Name(),
+		Type:     eventType,
+		Data:     string(data),
+	}, webhookClaimTTL)
+	if err != nil {
+		return err
+	}
+	return s.process(event)
+}
+
+func (s *webhookServiceImpl) ListEvents(status string) ([]models.WebhookEvent, error) {
+	switch status {
+	case "", models.WebhookProcessing, models.WebhookProcessed, models.WebhookFailed, models.WebhookIgnored:
+	default:
+		return nil, fmt.Errorf("unknown webhook event status %q", status)
+	}
+	return s.eventRepo.FindAll(status, 200)
+}
+
+// ReplayEvent processes a failed event again from its stored payload
+func (s *webhookServiceImpl) ReplayEvent(id string) (*models.WebhookEvent, error) {
+	event, err := s.eventRepo.FindByID(id)
+	if err != nil {
+		return nil, services.ErrWebhookEventNotFound
+	}
+	if event.Status != models.WebhookFailed {
+		return nil, fmt.Errorf("only failed events can be replayed, this one is %s", event.Status)
+	}
+
+	event, err = s.eventRepo.Reclaim(context.Background(), id, webhookClaimTTL)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.process(event); err != nil {
+		return nil, err
+	}
+	return s.eventRepo.FindByID(id)
+}
+
+// process runs the event's side effects and records the outcome
+func (s *webhookServiceImpl) process(event *models.WebhookEvent) error {
+	err := s.dispatch(event.Type, []byte(event.Data))
+
+	status, lastError := models.WebhookProcessed, ""
+	switch {
+	case errors.Is(err, errUnhandledEvent):
+		fmt.Println("⚠️ Unhandled payment event type:", event.Type)
+		status, err = models.WebhookIgnored, nil
+	case permanentEventError(err):
+		// Kept as failed so an admin sees it and can replay it once fixed
+		fmt.Println("❌ Payment event cannot be applied:", event.ID, event.Type, err)
+		status, lastError = models.WebhookFailed, err.Error()
+		err = fmt.Errorf("%w: %w", services.ErrEventNotApplied, err)
+	case err != nil:
+		fmt.Println("❌ Payment event failed:", event.ID, event.Type, err)
+		status, lastError = models.WebhookFailed, err.Error()
+	}
+
+	if finishErr := s.eventRepo.Finish(context.Background(), event.ID, status, lastError); finishErr != nil {
+		// The claim runs out and a retry processes the event again, which the
+		// order state machine makes harmless
+		fmt.Println("⚠️ Could not record webhook outcome:", event.ID, finishErr)
+	}
+	return err
+}
+
+// permanentEventError reports errors that redelivering the event cannot fix:
+// the payload is unreadable, it refers to no order we know, or it asks for a
+// status change the order no longer allows
+func permanentEventError(err error) bool {
+	var transitionErr *models.StatusTransitionError
+	return errors.Is(err, services.ErrMalformedEvent) ||
+		errors.Is(err, services.ErrOrderNotFound) ||
+		errors.As(err, &transitionErr)
+}
+
+// dispatch applies a payment event to the orders
+func (s *webhookServiceImpl) dispatch(eventType string, data []byte) error {
+	event, err := s.payments.DecodeEvent(eventType, data)
+	if err != nil {
+		return err
+	}
+
+	switch event.Kind {
+	case services.PaymentSucceeded:
+		fmt.Println("✅ Payment succeeded:", event.PaymentReference)
+		return s.orders.MarkOrderAsPaid(event.PaymentReference)
+
+	case services.PaymentFailed:
+		fmt.Println("❌ Payment FAILED:", event.PaymentReference)
+		return s.orders.MarkOrderAsFailed(event.PaymentReference, event.ErrorCode, event.ErrorMessage)
+
+	case services.CheckoutCompleted:
+		fmt.Println("✅ Checkout completed:", event.CheckoutSessionID)
+		return s.orders.CompleteCheckout(event.CheckoutSessionID, event.PaymentReference, event.Paid)
+
+	case services.CheckoutExpired:
+		fmt.Println("⌛ Checkout expired:", event.CheckoutSessionID)
+		return s.orders.ExpireCheckout(event.CheckoutSessionID)
+
+	case services.PaymentRefunded:
+		if event.PaymentReference == "" {
+			return nil
+		}
+		fmt.Println("💸 Payment refunded:", event.PaymentReference)
+		return s.orders.MarkOrderAsRefunded(event.PaymentReference)
+
+	case services.PaymentPartiallyRefunded:
+		if event.PaymentReference == "" {
+			return nil
+		}
+		fmt.Println("💸 Payment partially refunded:", event.PaymentReference)
+		return s.orders.MarkOrderAsPartiallyRefunded(event.PaymentReference)
+
+	case services.PaymentDisputed, services.DisputeUpdated, services.DisputeClosed:
+		dispute, err := s.disputes.RecordDispute(event)
+		if err != nil {
+			return err
+		}
+		fmt.Println("⚠️ Payment dispute", dispute.ProviderID, "is", dispute.Status)
+		return nil
+	}
+
+	return errUnhandledEvent
+}
@@ -0,0 +1,53 @@
+package servicesimpl
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/repositories"
+	"beauty-ecommerce-backend/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookEventClaim(t *testing.T) {
+	now := time.Now()
+	expired := now.Add(-time.Second)
+	locked := now.Add(time.Minute)
+
+	tests := []struct {
+		name      string
+		event     models.WebhookEvent
+		claimable bool
+		conflict  error // why Reclaim refuses it
+	}{
+		{"failed events are retried", models.WebhookEvent{Status: models.WebhookFailed}, true, repositories.ErrEventInProgress},
+		{"a live claim blocks other deliveries", models.WebhookEvent{Status: models.WebhookProcessing, LockedUntil: &locked}, false, repositories.ErrEventInProgress},
+		{"an expired claim can be taken over", models.WebhookEvent{Status: models.WebhookProcessing, LockedUntil: &expired}, true, repositories.ErrEventInProgress},
+		{"a claim without a lock can be taken over", models.WebhookEvent{Status: models.WebhookProcessing}, true, repositories.ErrEventInProgress},
+		{"processed events are done", models.WebhookEvent{Status: models.WebhookProcessed}, false, repositories.ErrEventDone},
+		{"ignored events are done", models.WebhookEvent{Status: models.WebhookIgnored}, false, repositories.ErrEventDone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.claimable, tt.event.Claimable(now))
+			assert.ErrorIs(t, repositories.ClaimConflict(&tt.event), tt.conflict)
+		})
+	}
+}
+
+func TestPermanentEventError(t *testing.T) {
+	transition := &models.StatusTransitionError{From: models.OrderCancelled, To: models.OrderPaid}
+
+	assert.True(t, permanentEventError(fmt.Errorf("%w for payment pi_1", services.ErrOrderNotFound)))
+	assert.True(t, permanentEventError(fmt.Errorf("%w: failed to parse Charge", services.ErrMalformedEvent)))
+	assert.True(t, permanentEventError(fmt.Errorf("could not mark paid: %w", transition)))
+
+	// Anything else may be a blip and is left for the provider to retry
+	assert.False(t, permanentEventError(errors.New("server selection timeout")))
+	assert.False(t, permanentEventError(nil))
+}
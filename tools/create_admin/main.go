@@ -0,0 +1,118 @@
+// Command create_admin creates a staff account, or grants a role to an
+// existing account, in the users collection that /admin/login checks.
+//
+// The email and password default to ADMIN_EMAIL and ADMIN_PASSWORD, so the
+// old environment superuser can be moved into Mongo with no flags. An
+// existing account keeps its password unless -reset-password is given.
+//
+//	go run ./tools/create_admin -email ops@example.com -role ORDER_MANAGER
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"beauty-ecommerce-backend/config"
+	"beauty-ecommerce-backend/models"
+	"beauty-ecommerce-backend/repositories"
+
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️ Could not load .env file, relying on environment variables")
+	}
+
+	email := flag.String("email", os.Getenv("ADMIN_EMAIL"), "account email")
+	name := flag.String("name", "Admin", "name for a new account")
+	roleName := flag.String("role", string(models.RoleSuperAdmin), "one of "+roleList())
+	resetPassword := flag.Bool("reset-password", false, "set ADMIN_PASSWORD on an existing account")
+	flag.Parse()
+
+	role := models.ParseRole(*roleName)
+	if !role.Staff() {
+		log.Fatalf("❌ %q is not a staff role; use one of %s", *roleName, roleList())
+	}
+	if *email == "" {
+		log.Fatal("❌ -email or ADMIN_EMAIL is required")
+	}
+	password := os.Getenv("ADMIN_PASSWORD")
+
+	config.ConnectDB()
+	users := repositories.NewUserRepository(config.DB)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	existing, err := users.FindByEmail(*email)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		if len(password) < 8 {
+			log.Fatal("❌ ADMIN_PASSWORD must be set, and at least 8 characters, for a new account")
+		}
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			log.Fatal("❌ Failed to hash password:", err)
+		}
+		now := time.Now()
+		if _, err := users.Collection.InsertOne(ctx, models.User{
+			Name:      *name,
+			Email:     *email,
+			Role:      string(role),
+			Password:  string(hashed),
+			CreatedAt: now,
+			UpdatedAt: now,
+		}); err != nil {
+			log.Fatal("❌ Failed to create account:", err)
+		}
+		fmt.Printf("✅ Created %s with role %s\n", *email, role)
+
+	case err != nil:
+		log.Fatal("❌ Failed to look up account:", err)
+
+	default:
+		set := bson.M{"role": string(role), "updated_at": time.Now()}
+		if *resetPassword {
+			if len(password) < 8 {
+				log.Fatal("❌ ADMIN_PASSWORD must be at least 8 characters")
+			}
+			hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+			if err != nil {
+				log.Fatal("❌ Failed to hash password:", err)
+			}
+			set["password"] = string(hashed)
+		}
+		if _, err := users.Collection.UpdateOne(ctx, bson.M{"_id": existing.ID}, bson.M{"$set": set}); err != nil {
+			log.Fatal("❌ Failed to update account:", err)
+		}
+		// Sign the account out everywhere so the new role or password applies
+		if err := users.BumpTokenVersion(ctx, existing.ID); err != nil {
+			log.Fatal("❌ Failed to revoke access tokens:", err)
+		}
+		if err := repositories.NewSessionRepository(config.DB).RevokeAllForUser(ctx, existing.ID); err != nil {
+			log.Fatal("❌ Failed to end sessions:", err)
+		}
+		fmt.Printf("✅ %s now has role %s (was %s)\n", *email, role, existing.Role)
+	}
+
+	for _, permission := range role.Permissions() {
+		fmt.Println("   -", permission)
+	}
+}
+
+func roleList() string {
+	var names []string
+	for _, role := range models.StaffRoles() {
+		names = append(names, string(role))
+	}
+	return strings.Join(names, ", ")
+}
@@ -22,10 +22,11 @@ func main() {
 	}
 
 	email := "user@example.com"
-	role := "CUSTOMER"
+	role := "USER"
+	tokenVersion := 0 // must match the user's token_version
 
 	// Generate JWT token
-	token, err := utils.GenerateToken(userID, email, role)
+	token, err := utils.GenerateToken(userID, email, role, tokenVersion)
 	if err != nil {
 		log.Fatal("Failed to generate token:", err)
 	}
@@ -36,5 +37,5 @@ func main() {
 	fmt.Println("Authorization header example:")
 	fmt.Printf("Authorization: Bearer %s\n", token)
 	fmt.Println()
-	fmt.Println("Token expires in 72 hours from now:", time.Now().Add(72*time.Hour))
+	fmt.Println("Token expires at:", time.Now().Add(utils.AccessTokenTTL))
 }
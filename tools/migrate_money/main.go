@@ -0,0 +1,151 @@
+// Command migrate_money converts stored prices from float major units (12.99)
+// to the integer minor-unit Money documents ({amount: 1299, currency: "GBP"}).
+//
+// Every conversion only touches values that are still plain numbers, so the
+// migration can be run again safely. models.Money still decodes the old
+// numbers, so the API keeps working until it has run, but catalog price
+// filters and sorting only see migrated products.
+//
+//	go run ./tools/migrate_money
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"beauty-ecommerce-backend/config"
+	"beauty-ecommerce-backend/models"
+
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// toMoney rewrites a numeric expression as a Money document and leaves
+// anything else (already migrated, null, missing) untouched
+func toMoney(expr string) bson.M {
+	return bson.M{"$cond": bson.A{
+		bson.M{"$isNumber": expr},
+		bson.M{
+			"amount":   bson.M{"$toLong": bson.M{"$round": bson.A{bson.M{"$multiply": bson.A{expr, 100}}, 0}}},
+			"currency": models.DefaultCurrency,
+		},
+		expr,
+	}}
+}
+
+// mapArray applies fields to every element of an array field, if it is one
+func mapArray(field, as string, fields bson.M) bson.M {
+	return bson.M{"$cond": bson.A{
+		bson.M{"$isArray": field},
+		bson.M{"$map": bson.M{
+			"input": field,
+			"as":    as,
+			"in":    bson.M{"$mergeObjects": bson.A{"$$" + as, fields}},
+		}},
+		field,
+	}}
+}
+
+type step struct {
+	collection string
+	filter     bson.M
+	set        bson.M
+}
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️ Could not load .env file, relying on environment variables")
+	}
+	config.ConnectDB()
+	db := config.DB
+
+	steps := []step{
+		{"products", bson.M{}, bson.M{
+			"price": toMoney("$price"),
+			"variants": mapArray("$variants", "v", bson.M{
+				"price": toMoney("$$v.price"),
+			}),
+		}},
+		{"orders", bson.M{}, bson.M{
+			"subtotal":       toMoney("$subtotal"),
+			"shipping_fee":   toMoney("$shipping_fee"),
+			"discount_total": toMoney("$discount_total"),
+			"shipping_tax":   toMoney("$shipping_tax"),
+			"tax_total":      toMoney("$tax_total"),
+			"total_price":    toMoney("$total_price"),
+			"items": mapArray("$items", "i", bson.M{
+				"price":           toMoney("$$i.price"),
+				"discount_amount": toMoney("$$i.discount_amount"),
+				"tax_amount":      toMoney("$$i.tax_amount"),
+			}),
+			"tax_lines": mapArray("$tax_lines", "t", bson.M{
+				"taxable": toMoney("$$t.taxable"),
+				"amount":  toMoney("$$t.amount"),
+			}),
+			"discounts": mapArray("$discounts", "d", bson.M{
+				"amount": toMoney("$$d.amount"),
+			}),
+		}},
+		{"cart", bson.M{}, bson.M{
+			"unit_price": toMoney("$unit_price"),
+		}},
+		// Fixed coupons kept their amount in value; it now lives in amount
+		{"coupons", bson.M{"type": models.CouponFixed, "amount": bson.M{"$exists": false}}, bson.M{
+			"amount": toMoney("$value"),
+			"value":  0,
+		}},
+		{"coupons", bson.M{"amount": bson.M{"$exists": false}}, bson.M{
+			"amount": bson.M{"amount": 0, "currency": models.DefaultCurrency},
+		}},
+		{"coupons", bson.M{}, bson.M{
+			"min_spend": toMoney("$min_spend"),
+		}},
+		{"coupon_redemptions", bson.M{}, bson.M{
+			"amount": toMoney("$amount"),
+		}},
+		{"shipping_zones", bson.M{}, bson.M{
+			"methods": mapArray("$methods", "m", bson.M{
+				"free_over": toMoney("$$m.free_over"),
+				"rates": mapArray("$$m.rates", "r", bson.M{
+					// Subtotal tiers were bounded in pounds; convert them with the
+					// price so a second run leaves them alone
+					"up_to": bson.M{"$cond": bson.A{
+						bson.M{"$and": bson.A{
+							bson.M{"$eq": bson.A{"$$m.basis", models.ShippingBasisSubtotal}},
+							bson.M{"$isNumber": "$$r.price"},
+						}},
+						bson.M{"$toLong": bson.M{"$round": bson.A{bson.M{"$multiply": bson.A{"$$r.up_to", 100}}, 0}}},
+						bson.M{"$toLong": "$$r.up_to"},
+					}},
+					"price": toMoney("$$r.price"),
+				}),
+			}),
+		}},
+	}
+
+	for _, s := range steps {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		res, err := db.Collection(s.collection).UpdateMany(ctx, s.filter, mongo.Pipeline{
+			{{Key: "$set", Value: s.set}},
+		})
+		cancel()
+		if err != nil {
+			log.Fatalf("❌ Failed to migrate %s: %v", s.collection, err)
+		}
+		fmt.Printf("✅ %s: %d matched, %d modified\n", s.collection, res.MatchedCount, res.ModifiedCount)
+	}
+
+	// The catalog indexes now sort and filter on price.amount
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for _, name := range []string{"category_1_price_1", "price_1__id_1"} {
+		if _, err := db.Collection("products").Indexes().DropOne(ctx, name); err != nil {
+			fmt.Println("ℹ️ index", name, "not dropped:", err)
+		}
+	}
+
+	fmt.Println("✅ Money migration complete")
+}
@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"beauty-ecommerce-backend/models"
 	"fmt"
+	"html"
 	"log"
 	"os"
 	"time"
@@ -129,25 +131,51 @@ func QueueEmail(to, toName, subject, html string) {
 // -----------------------------
 // Email Templates
 // -----------------------------
-func SendConfirmationEmail(to, name, orderID, deliveryType string, subtotal, shippingFee, total float64) {
+func SendConfirmationEmail(to, name string, order *models.Order) {
 	subject := "Your order payment update"
+
+	discountHTML := ""
+	if order.DiscountTotal.IsPositive() {
+		discountHTML = fmt.Sprintf("<li>Discount (%s): -%s</li>", order.CouponCode, order.DiscountTotal)
+	}
+
 	html := fmt.Sprintf(`
 	<h2>Hello %s,</h2>
 	<p>We’ve received your payment and your order is now being processed.</p>
 	<p><strong>Order reference:</strong> %s</p>
 	<ul>
 		<li>Delivery type: %s</li>
-		<li>Subtotal: %.2f GBP</li>
-		<li>Shipping fee: %.2f GBP</li>
-		<li><strong>Order total: %.2f GBP</strong></li>
+		<li>Subtotal: %s</li>
+		<li>Shipping fee: %s</li>
+		%s
+		<li><strong>Order total: %s</strong></li>
 	</ul>
+	%s
 	<p>We’ll notify you once your order is shipped.</p>
 	<p>Thank you for shopping with Beauty Shop ❤️</p>
-	`, name, orderID, deliveryType, subtotal, shippingFee, total)
+	`, name, order.ID.Hex(), order.DeliveryType, order.Subtotal, order.ShippingFee, discountHTML, order.TotalPrice, TaxBreakdownHTML(order))
 
 	QueueEmail(to, name, subject, html)
 }
 
+// TaxBreakdownHTML lists the tax charged at each rate, noting when it is
+// already included in the prices
+func TaxBreakdownHTML(order *models.Order) string {
+	if len(order.TaxLines) == 0 {
+		return ""
+	}
+
+	rows := ""
+	for _, line := range order.TaxLines {
+		note := "added"
+		if line.Inclusive {
+			note = "included"
+		}
+		rows += fmt.Sprintf("<li>%s on %s: %s (%s)</li>", line.Name, line.Taxable, line.Amount, note)
+	}
+	return fmt.Sprintf("<p><strong>Tax</strong></p><ul>%s</ul>", rows)
+}
+
 func SendFailedPaymentEmail(to, name, orderID string) {
 	subject := "Issue with your order payment"
 	html := fmt.Sprintf(`
@@ -172,14 +200,126 @@ func SendResetPasswordEmail(toEmail, name, resetLink string) {
 	QueueEmail(toEmail, name, subject, html)
 }
 
-func SendShipmentEmail(toEmail, toName, orderID, deliveryType string) {
+func SendShipmentEmail(toEmail, toName string, order *models.Order, shipment *models.Shipment) {
 	subject := "Your Order Has Been Shipped!"
-	html := fmt.Sprintf(`
+	if shipment == nil {
+		html := fmt.Sprintf(`
 	<h2>Hello %s,</h2>
 	<p>Your order <b>%s</b> has been shipped.</p>
 	<p>Delivery type: %s</p>
 	<p>Thank you for shopping with Beauty Shop ❤️</p>
-	`, toName, orderID, deliveryType)
+	`, toName, order.ID.Hex(), order.DeliveryType)
+
+		QueueEmail(toEmail, toName, subject, html)
+		return
+	}
+
+	names := map[string]string{}
+	for _, item := range order.Items {
+		names[item.Key()] = item.DisplayName()
+	}
+	itemsHTML := ""
+	for _, item := range shipment.Items {
+		itemsHTML += fmt.Sprintf("<li>%s × %d</li>", names[item.Key()], item.Quantity)
+	}
+
+	tracking := shipment.TrackingNumber
+	if shipment.TrackingURL != "" {
+		tracking = fmt.Sprintf(`<a href="%s">%s</a>`, shipment.TrackingURL, shipment.TrackingNumber)
+	}
+
+	rest := ""
+	if !order.FullyShipped() {
+		rest = "<p>The rest of your order will follow in a separate parcel.</p>"
+	}
+
+	html := fmt.Sprintf(`
+	<h2>Hello %s,</h2>
+	<p>A parcel from your order <b>%s</b> is on its way.</p>
+	<p><strong>Carrier:</strong> %s<br>
+	<strong>Tracking number:</strong> %s</p>
+	<p>This parcel contains:</p>
+	<ul>%s</ul>
+	%s
+	<p>Delivery type: %s</p>
+	<p>Thank you for shopping with Beauty Shop ❤️</p>
+	`, toName, order.ID.Hex(), shipment.Carrier, tracking, itemsHTML, rest, order.DeliveryType)
+
+	QueueEmail(toEmail, toName, subject, html)
+}
+
+func SendRefundEmail(toEmail, toName string, order *models.Order, refund *models.Refund) {
+	subject := "Your Refund Is On Its Way"
+
+	names := map[string]string{}
+	for _, item := range order.Items {
+		names[item.Key()] = item.DisplayName()
+	}
+	itemsHTML := ""
+	for _, item := range refund.Items {
+		itemsHTML += fmt.Sprintf("<li>%s × %d: %s</li>", names[item.Key()], item.Quantity, item.Amount.Format())
+	}
+	if refund.Shipping.IsPositive() {
+		itemsHTML += fmt.Sprintf("<li>Shipping: %s</li>", refund.Shipping.Format())
+	}
+
+	html := fmt.Sprintf(`
+	<h2>Hello %s,</h2>
+	<p>We have refunded <b>%s</b> for your order <b>%s</b>.</p>
+	<ul>%s</ul>
+	<p>It can take 5-10 working days to appear on your statement.</p>
+	<p>Thank you for shopping with Beauty Shop ❤️</p>
+	`, toName, refund.Amount.Format(), order.ID.Hex(), itemsHTML)
+
+	QueueEmail(toEmail, toName, subject, html)
+}
+
+// ReturnItemsHTML lists a return's items by their order line names
+func ReturnItemsHTML(order *models.Order, ret *models.Return) string {
+	names := map[string]string{}
+	for _, item := range order.Items {
+		names[item.Key()] = item.DisplayName()
+	}
+	html := ""
+	for _, item := range ret.Items {
+		html += fmt.Sprintf("<li>%s × %d</li>", names[item.Key()], item.Quantity)
+	}
+	return html
+}
+
+func SendReturnEmail(toEmail, toName string, order *models.Order, ret *models.Return, note string) {
+	var subject, message string
+	switch ret.Status {
+	case models.ReturnRequested:
+		subject = "We Have Received Your Return Request"
+		message = "We have received your return request and will review it shortly."
+	case models.ReturnApproved:
+		subject = "Your Return Has Been Approved"
+		message = "Your return has been approved. Please send the items back, quoting your return ID on the parcel."
+	case models.ReturnRejected:
+		subject = "Your Return Request"
+		message = "Unfortunately we are unable to accept this return."
+	case models.ReturnReceived:
+		subject = "We Have Received Your Returned Items"
+		message = "Your returned items have arrived and your refund is being processed."
+	default:
+		return
+	}
+
+	noteHTML := ""
+	if note != "" {
+		noteHTML = fmt.Sprintf("<p><strong>Note from the shop:</strong> %s</p>", html.EscapeString(note))
+	}
+
+	html := fmt.Sprintf(`
+	<h2>Hello %s,</h2>
+	<p>%s</p>
+	<p><strong>Order:</strong> %s<br>
+	<strong>Return ID:</strong> %s</p>
+	<ul>%s</ul>
+	%s
+	<p>Thank you for shopping with Beauty Shop ❤️</p>
+	`, toName, message, order.ID.Hex(), ret.ID.Hex(), ReturnItemsHTML(order, ret), noteHTML)
 
 	QueueEmail(toEmail, toName, subject, html)
 }
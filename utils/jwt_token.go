@@ -13,8 +13,13 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// GenerateToken creates a JWT token for a user
-func GenerateToken(userID primitive.ObjectID, email, role string) (string, error) {
+// AccessTokenTTL is how long an access token lasts; clients renew it with
+// their refresh token
+const AccessTokenTTL = 15 * time.Minute
+
+// GenerateToken creates a short-lived access token for a user. The token
+// version must match the user's for JWTMiddleware to accept it.
+func GenerateToken(userID primitive.ObjectID, email, role string, tokenVersion int) (string, error) {
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
 		secret = "defaultsecret"
@@ -24,7 +29,8 @@ func GenerateToken(userID primitive.ObjectID, email, role string) (string, error
 		"user_id": userID.Hex(),
 		"email":   email,
 		"role":    role,
-		"exp":     jwt.NewNumericDate(time.Now().Add(72 * time.Hour)),
+		"ver":     tokenVersion,
+		"exp":     jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
 		"iat":     jwt.NewNumericDate(time.Now()),
 	}
 